@@ -0,0 +1,244 @@
+// Package amf 实现 RTMP 命令消息和 FLV 脚本数据使用的 AMF0 编解码
+package amf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// AMF0 类型标记
+const (
+	markerNumber      = 0x00
+	markerBoolean     = 0x01
+	markerString      = 0x02
+	markerObject      = 0x03
+	markerNull        = 0x05
+	markerUndefined   = 0x06
+	markerECMAArray   = 0x08
+	markerObjectEnd   = 0x09
+	markerStrictArray = 0x0A
+	markerDate        = 0x0B
+	markerLongString  = 0x0C
+)
+
+// maxDepth 限制对象嵌套层数，避免畸形数据耗尽栈空间
+const maxDepth = 32
+
+// Object 是 AMF0 对象或 ECMA 数组
+type Object map[string]interface{}
+
+// Number 返回数值类型的属性，不存在或类型不符时 ok 为 false
+func (o Object) Number(key string) (float64, bool) {
+	v, ok := o[key].(float64)
+	return v, ok
+}
+
+// String 返回字符串类型的属性，不存在或类型不符时返回空字符串
+func (o Object) String(key string) string {
+	v, _ := o[key].(string)
+	return v
+}
+
+// Encode 将 float64、int、bool、string、Object 和 nil 依次编码为 AMF0
+func Encode(values ...interface{}) ([]byte, error) {
+	var b []byte
+	for _, v := range values {
+		var err error
+		if b, err = appendValue(b, v); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func appendValue(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, markerNull), nil
+	case float64:
+		b = append(b, markerNumber)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(v)), nil
+	case int:
+		return appendValue(b, float64(v))
+	case bool:
+		if v {
+			return append(b, markerBoolean, 1), nil
+		}
+		return append(b, markerBoolean, 0), nil
+	case string:
+		if len(v) > math.MaxUint16 {
+			b = append(b, markerLongString)
+			b = binary.BigEndian.AppendUint32(b, uint32(len(v)))
+			return append(b, v...), nil
+		}
+		b = append(b, markerString)
+		return appendString(b, v), nil
+	case Object:
+		b = append(b, markerObject)
+		// 按键排序，保证编码结果稳定
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b = appendString(b, k)
+			var err error
+			if b, err = appendValue(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, 0, 0, markerObjectEnd), nil
+	}
+	return nil, fmt.Errorf("amf0: unsupported type %T", v)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// Decode 解码数据中的所有 AMF0 值。对象和 ECMA 数组解码为 Object，
+// null 和 undefined 解码为 nil，严格数组解码为 []interface{}。
+// 遇到无法解码的数据时返回已解码的值和错误。
+func Decode(data []byte) ([]interface{}, error) {
+	d := decoder{data: data}
+	var values []interface{}
+	for d.pos < len(d.data) {
+		v, err := d.value(0)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+var errShort = fmt.Errorf("amf0: unexpected end of data")
+
+func (d *decoder) take(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, errShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) u16() (int, error) {
+	b, err := d.take(2)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint16(b)), nil
+}
+
+func (d *decoder) u32() (int, error) {
+	b, err := d.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint32(b)), nil
+}
+
+func (d *decoder) str(n int) (string, error) {
+	b, err := d.take(n)
+	return string(b), err
+}
+
+func (d *decoder) value(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("amf0: nesting too deep")
+	}
+	marker, err := d.take(1)
+	if err != nil {
+		return nil, err
+	}
+	switch marker[0] {
+	case markerNumber:
+		b, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case markerBoolean:
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case markerString:
+		n, err := d.u16()
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case markerLongString:
+		n, err := d.u32()
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case markerNull, markerUndefined:
+		return nil, nil
+	case markerObject:
+		return d.object(depth)
+	case markerECMAArray:
+		// 元素个数只是提示，实际以对象结束标记为准
+		if _, err := d.u32(); err != nil {
+			return nil, err
+		}
+		return d.object(depth)
+	case markerStrictArray:
+		n, err := d.u32()
+		if err != nil {
+			return nil, err
+		}
+		var list []interface{}
+		for i := 0; i < n; i++ {
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case markerDate:
+		b, err := d.take(10)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	}
+	return nil, fmt.Errorf("amf0: unsupported marker 0x%02X", marker[0])
+}
+
+func (d *decoder) object(depth int) (Object, error) {
+	obj := Object{}
+	for {
+		n, err := d.u16()
+		if err != nil {
+			return obj, err
+		}
+		key, err := d.str(n)
+		if err != nil {
+			return obj, err
+		}
+		if key == "" && d.pos < len(d.data) && d.data[d.pos] == markerObjectEnd {
+			d.pos++
+			return obj, nil
+		}
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return obj, err
+		}
+		obj[key] = v
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"m3u8_selector/core"
+	"m3u8_selector/parser"
+	"m3u8_selector/tester"
+)
+
+func runBatch(args []string) int {
+	fs := newFlagSet("batch", "[选项] <频道列表文件>",
+		"按频道列表批量搜索并测速，输出合并的播放列表，每个频道保留最好的若干个直播源。\n"+
+			"频道列表每行一个频道，格式为 \"频道名\" 或 \"频道名|别名1|别名2\"，别名同时作为搜索关键词；\n"+
+			"\"分组,#genre#\" 行开始一个新分组，# 开头的行为注释。")
+	var search searchOptions
+	var test testOptions
+	var out outputOptions
+	search.register(fs)
+	test.register(fs)
+	out.register(fs, "")
+	perChannel := fs.Int("per-channel", 3, "每个频道保留的最佳直播源数量，0 表示不限")
+	matchName := fs.Bool("match", true, "只保留搜索结果中频道名属于该频道的链接（与频道名或别名相同，或以其开头并带有 高清、体育 等后缀；结果没有频道名时保留）")
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	if fs.NArg() != 1 {
+		return usageError(fs, "需要且只能指定一个频道列表文件")
+	}
+	if *perChannel < 0 {
+		return usageError(fs, "-per-channel 不能为负数")
+	}
+	if err := test.validate(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if err := out.prepare(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	searchProviders, err := parser.ResolveProviders(search.provider)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(out.log, "读取频道列表失败: %v\n", err)
+		return exitFailure
+	}
+	channels, err := parser.ParseChannelList(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintln(out.log, err)
+		return exitFailure
+	}
+	if len(channels) == 0 {
+		fmt.Fprintln(out.log, "频道列表为空。")
+		return exitFailure
+	}
+
+	// 所有频道共享同一个搜索客户端（含限速器），测速时合并为一次批量测试，共享并发名额
+	client := newSearchClient(search)
+	channelURLs := make([][]string, len(channels))
+	candidates := []core.Candidate{}
+	for i, channel := range channels {
+		fmt.Fprintf(out.log, "\n##### [%d/%d] 频道: %s #####\n", i+1, len(channels), channel.Name)
+		seen := make(map[string]bool)
+		for _, term := range channel.Terms {
+			for _, c := range searchCandidates(out.log, searchProviders, term, search, client) {
+				if seen[c.URL] || (*matchName && c.Meta.Channel != "" && !channel.Matches(c.Meta.Channel)) {
+					continue
+				}
+				seen[c.URL] = true
+				c.Meta.Channel = channel.Name
+				c.Meta.Group = channel.Group
+				channelURLs[i] = append(channelURLs[i], c.URL)
+				candidates = append(candidates, c)
+			}
+		}
+		fmt.Fprintf(out.log, "频道 %s 共找到 %d 个候选链接\n", channel.Name, len(channelURLs[i]))
+	}
+
+	candidates = parser.RemoveDuplicateCandidates(candidates)
+	if len(candidates) == 0 {
+		fmt.Fprintln(out.log, "\n未找到任何流媒体链接。")
+		return exitFailure
+	}
+	core.SortCandidates(candidates)
+	results := tester.TestAllSources(candidates, test.testerOptions(out.log))
+
+	byURL := make(map[string]core.M3U8Source, len(results))
+	for _, result := range results {
+		byURL[result.URL] = result
+	}
+
+	combined := []core.M3U8Source{}
+	failed := []core.M3U8Source{}
+	missing := []string{}
+	fmt.Fprintf(out.log, "\n=== 批量测试结果 ===\n\n")
+	for i, channel := range channels {
+		channelResults := make([]core.M3U8Source, 0, len(channelURLs[i]))
+		for _, u := range channelURLs[i] {
+			result := byURL[u]
+			// 同一链接可能属于多个频道，输出时使用当前频道的信息
+			result.Meta.Channel = channel.Name
+			result.Meta.Group = channel.Group
+			channelResults = append(channelResults, result)
+		}
+		valid, channelFailed := splitResults(channelResults, test.rankBy())
+		failed = append(failed, channelFailed...)
+
+		best := limitSources(valid, *perChannel)
+		combined = append(combined, best...)
+		if len(best) == 0 {
+			missing = append(missing, channel.Name)
+			fmt.Fprintf(out.log, "%s: 没有可用的直播源 (候选 %d 个)\n", channel.Name, len(channelResults))
+			continue
+		}
+		fmt.Fprintf(out.log, "%s: %d 个可用, 排名第一 %.2f KB/s%s\n", channel.Name, len(valid), best[0].DownloadSpeed, describeMedia(best[0]))
+	}
+
+	if len(missing) > 0 {
+		fmt.Fprintf(out.log, "\n%d 个频道没有可用的直播源: %v\n", len(missing), missing)
+	}
+
+	if out.path != "" {
+		if err := writeResults(out, combined, failed); err != nil {
+			fmt.Fprintf(out.log, "导出测试结果失败: %v\n", err)
+			return exitFailure
+		}
+	}
+
+	if len(combined) == 0 {
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"m3u8_selector/exporter"
+)
+
+func runExport(args []string) int {
+	fs := newFlagSet("export", "[选项] <结果.json>", "读取使用 -format json 导出的测试结果，转换为其他格式。")
+	var out outputOptions
+	out.register(fs, "-")
+	out.registerLimit(fs)
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	if fs.NArg() != 1 {
+		return usageError(fs, "需要且只能指定一个 json 结果文件")
+	}
+	if err := out.prepare(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(out.log, "读取测试结果失败: %v\n", err)
+		return exitFailure
+	}
+	ranked, failed, err := exporter.ReadJSON(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintln(out.log, err)
+		return exitFailure
+	}
+
+	if err := writeResults(out, limitSources(ranked, out.limit), failed); err != nil {
+		fmt.Fprintf(out.log, "导出测试结果失败: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
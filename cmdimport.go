@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"m3u8_selector/core"
+	"m3u8_selector/parser"
+)
+
+func runImport(args []string) int {
+	fs := newFlagSet("import", "[选项] <文件或URL>...", "导入 M3U/M3U8 或 DIYP/TVBox txt 播放列表，测速后按排名输出。\n播放列表中的频道名、分组和 tvg 属性会随结果保留。")
+	var search searchOptions
+	var test testOptions
+	var out outputOptions
+	search.register(fs)
+	test.register(fs)
+	out.register(fs, "")
+	out.registerLimit(fs)
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	if fs.NArg() == 0 {
+		return usageError(fs, "需要指定至少一个播放列表")
+	}
+	if err := test.validate(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if err := out.prepare(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	candidates, err := importCandidates(out.log, fs.Args(), newSearchClient(search))
+	if err != nil {
+		fmt.Fprintln(out.log, err)
+		return exitFailure
+	}
+	return testAndReport(candidates, test, out)
+}
+
+// importCandidates 导入所有播放列表并按 URL 去重，任一播放列表导入失败时返回错误。导入的数量输出到 w
+func importCandidates(w io.Writer, locations []string, client *http.Client) ([]core.Candidate, error) {
+	candidates := []core.Candidate{}
+	for _, location := range locations {
+		imported, err := parser.ImportPlaylist(location, client)
+		if err != nil {
+			return nil, fmt.Errorf("导入播放列表 %s 失败: %v", location, err)
+		}
+		fmt.Fprintf(w, "从 %s 导入 %d 个直播源\n", location, len(imported))
+		candidates = append(candidates, imported...)
+	}
+	return parser.RemoveDuplicateCandidates(candidates), nil
+}
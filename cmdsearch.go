@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"m3u8_selector/core"
+	"m3u8_selector/parser"
+)
+
+func runSearch(args []string) int {
+	fs := newFlagSet("search", "[选项] <关键词>", "在搜索源中按关键词搜索直播源，测速后按排名输出。")
+	var search searchOptions
+	var test testOptions
+	var out outputOptions
+	search.register(fs)
+	test.register(fs)
+	out.register(fs, "")
+	out.registerLimit(fs)
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	if fs.NArg() != 1 {
+		return usageError(fs, "需要且只能指定一个搜索关键词")
+	}
+	if search.maxPages < 1 {
+		return usageError(fs, "-pages 必须大于 0")
+	}
+	if err := test.validate(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if err := out.prepare(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	searchProviders, err := parser.ResolveProviders(search.provider)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	client := newSearchClient(search)
+	candidates := searchCandidates(out.log, searchProviders, fs.Arg(0), search, client)
+	return testAndReport(candidates, test, out)
+}
+
+// searchCandidates 在所有搜索源中搜索关键词，返回去重后的候选源，搜索进度输出到 w
+func searchCandidates(w io.Writer, searchProviders []parser.SearchProvider, keyword string, search searchOptions, client *http.Client) []core.Candidate {
+	fmt.Fprintf(w, "搜索关键词: %s\n", keyword)
+	fmt.Fprintf(w, "最大搜索分页数: %d\n", search.maxPages)
+
+	candidates := []core.Candidate{}
+	for _, r := range parser.SearchAll(searchProviders, keyword, search.maxPages, client, search.workers, w) {
+		candidates = append(candidates, r.Candidate())
+	}
+	return candidates
+}
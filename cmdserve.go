@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/exporter"
+	"m3u8_selector/parser"
+	"m3u8_selector/tester"
+)
+
+func runServe(args []string) int {
+	fs := newFlagSet("serve", "[选项] [关键词]",
+		"定期按关键词搜索和/或导入播放列表并测速，通过 HTTP 提供最新结果:\n"+
+			"  /playlist.m3u  /playlist.txt  /results.json  /results.csv")
+	var search searchOptions
+	var test testOptions
+	search.register(fs)
+	test.register(fs)
+	addr := fs.String("addr", ":8080", "HTTP 监听地址")
+	interval := fs.Duration("interval", 30*time.Minute, "重新搜索和测速的间隔")
+	limit := fs.Int("limit", 0, "播放列表中可用直播源的数量上限，0 表示不限")
+	imports := []string{}
+	fs.Func("import", "导入的播放列表（文件路径或 URL），可重复指定", func(location string) error {
+		imports = append(imports, location)
+		return nil
+	})
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	if fs.NArg() > 1 {
+		return usageError(fs, "最多只能指定一个搜索关键词")
+	}
+	if fs.NArg() == 0 && len(imports) == 0 {
+		return usageError(fs, "需要指定搜索关键词或 -import 播放列表")
+	}
+	if err := test.validate(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if *interval <= 0 {
+		return usageError(fs, "-interval 必须大于 0")
+	}
+	if *limit < 0 {
+		return usageError(fs, "-limit 不能为负数")
+	}
+	searchProviders, err := parser.ResolveProviders(search.provider)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	client := newSearchClient(search)
+	server := &resultServer{limit: *limit}
+	refresh := func() {
+		candidates := []core.Candidate{}
+		// 某个播放列表导入失败时跳过它，其余播放列表照常使用
+		for _, location := range imports {
+			imported, err := importCandidates(os.Stdout, []string{location}, client)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			candidates = append(candidates, imported...)
+		}
+		if fs.NArg() == 1 {
+			candidates = append(candidates, searchCandidates(os.Stdout, searchProviders, fs.Arg(0), search, client)...)
+		}
+		server.refresh(parser.RemoveDuplicateCandidates(candidates), test)
+	}
+
+	go func() {
+		for {
+			refresh()
+			time.Sleep(*interval)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/playlist.m3u", server.handler("m3u", "audio/x-mpegurl; charset=utf-8"))
+	mux.HandleFunc("/playlist.txt", server.handler("txt", "text/plain; charset=utf-8"))
+	mux.HandleFunc("/results.json", server.handler("json", "application/json; charset=utf-8"))
+	mux.HandleFunc("/results.csv", server.handler("csv", "text/csv; charset=utf-8"))
+
+	fmt.Printf("HTTP 服务监听于 %s\n", *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		fmt.Printf("HTTP 服务启动失败: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// resultServer 保存最近一次测速的结果，供 HTTP 接口读取
+type resultServer struct {
+	limit int
+
+	mu        sync.RWMutex
+	ranked    []core.M3U8Source
+	failed    []core.M3U8Source
+	updatedAt time.Time
+}
+
+func (s *resultServer) refresh(candidates []core.Candidate, test testOptions) {
+	if len(candidates) == 0 {
+		fmt.Println("\n未找到任何流媒体链接，保留上一次的结果。")
+		return
+	}
+
+	core.SortCandidates(candidates)
+	ranked, failed := splitResults(tester.TestAllSources(candidates, test.testerOptions(os.Stdout)), test.rankBy())
+
+	s.mu.Lock()
+	s.ranked = limitSources(ranked, s.limit)
+	s.failed = failed
+	s.updatedAt = time.Now()
+	s.mu.Unlock()
+
+	fmt.Printf("结果已更新: %d 个可用直播源, %d 个不可用\n", len(ranked), len(failed))
+}
+
+func (s *resultServer) handler(format, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		ranked, failed, updatedAt := s.ranked, s.failed, s.updatedAt
+		s.mu.RUnlock()
+
+		if updatedAt.IsZero() {
+			http.Error(w, "首次测速尚未完成，请稍后再试", http.StatusServiceUnavailable)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := exporter.Write(&buf, format, ranked, failed); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+		w.Write(buf.Bytes())
+	}
+}
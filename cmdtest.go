@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"m3u8_selector/core"
+	"m3u8_selector/parser"
+)
+
+func runTest(args []string) int {
+	fs := newFlagSet("test", "[选项] [URL...]", "测试命令行或文件中给出的直播源地址，按排名输出。")
+	var test testOptions
+	var out outputOptions
+	listFile := fs.String("f", "", "从文件读取直播源地址，每行一个，# 开头的行为注释")
+	test.register(fs)
+	out.register(fs, "")
+	out.registerLimit(fs)
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	urls := fs.Args()
+	if *listFile != "" {
+		fileURLs, err := readURLList(*listFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		urls = append(urls, fileURLs...)
+	}
+	if len(urls) == 0 {
+		return usageError(fs, "需要指定至少一个直播源地址")
+	}
+	if err := test.validate(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if err := out.prepare(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	candidates := make([]core.Candidate, 0, len(urls))
+	for _, u := range urls {
+		candidates = append(candidates, core.Candidate{URL: u})
+	}
+	return testAndReport(parser.RemoveDuplicateCandidates(candidates), test, out)
+}
+
+// readURLList 读取每行一个地址的文件
+func readURLList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取地址列表失败: %v", err)
+	}
+	defer f.Close()
+
+	urls := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			urls = append(urls, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取地址列表失败: %v", err)
+	}
+	return urls, nil
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	resolutionPRegex  = regexp.MustCompile(`(?i)(\d{3,4})\s*[pi]\b`)
+	resolutionWHRegex = regexp.MustCompile(`(\d{3,4})\s*[x×*]\s*(\d{3,4})`)
+)
+
+// ResolutionHeight 将 "1080p"、"1920x1080"、"4K" 等标注转换为画面高度，无法识别时返回 0
+func ResolutionHeight(resolution string) int {
+	lower := strings.ToLower(resolution)
+	if m := resolutionWHRegex.FindStringSubmatch(lower); m != nil {
+		h, _ := strconv.Atoi(m[2])
+		return h
+	}
+	if m := resolutionPRegex.FindStringSubmatch(lower); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		return h
+	}
+	switch {
+	case strings.Contains(lower, "8k"):
+		return 4320
+	case strings.Contains(lower, "4k"), strings.Contains(lower, "uhd"):
+		return 2160
+	case strings.Contains(lower, "fhd"):
+		return 1080
+	case strings.Contains(lower, "hd"), strings.Contains(lower, "高清"):
+		return 720
+	}
+	return 0
+}
+
+// PreferredBefore 判断按来源元数据 a 是否应优先于 b：分辨率更高者优先，其次是更近检测过的
+func PreferredBefore(a, b StreamMeta) bool {
+	ha, hb := ResolutionHeight(a.Resolution), ResolutionHeight(b.Resolution)
+	if ha != hb {
+		return ha > hb
+	}
+	return a.CheckedAt.After(b.CheckedAt)
+}
+
+// SortCandidates 按来源元数据的优先级对候选源排序（稳定排序）
+func SortCandidates(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return PreferredBefore(candidates[i].Meta, candidates[j].Meta)
+	})
+}
+
+// SourceHeight 返回直播源的画面高度：优先使用从视频数据检测到的分辨率，其次是来源标注，未知时返回 0
+func SourceHeight(s M3U8Source) int {
+	if s.Height > 0 {
+		return s.Height
+	}
+	return ResolutionHeight(s.Meta.Resolution)
+}
+
+// RankBy 是直播源的排名方式
+type RankBy string
+
+const (
+	RankByPlayback   RankBy = "playback"   // 能否实时播放优先，其次是播放余量
+	RankBySpeed      RankBy = "speed"      // 下载速度优先，速度相同时参考分辨率
+	RankByResolution RankBy = "resolution" // 分辨率优先，分辨率相同时参考下载速度
+)
+
+// RankModes 是支持的排名方式
+var RankModes = []RankBy{RankByPlayback, RankBySpeed, RankByResolution}
+
+// MinHeadroom 是能够实时播放所需的最低播放余量
+const MinHeadroom = 1.0
+
+// playbackTier 将直播源按能否实时播放分为三档：0 能够实时播放，1 余量未知，2 下载跟不上播放
+func playbackTier(s M3U8Source) int {
+	switch {
+	case s.Headroom == 0:
+		return 1
+	case s.Headroom >= MinHeadroom:
+		return 0
+	}
+	return 2
+}
+
+// RankSources 按指定方式对可用直播源排序（稳定排序）
+func RankSources(sources []M3U8Source, by RankBy) {
+	sort.SliceStable(sources, func(i, j int) bool {
+		a, b := sources[i], sources[j]
+		// 未测速的直播源总是排在最后
+		if ua, ub := a.Measurement == Unmeasured, b.Measurement == Unmeasured; ua != ub {
+			return ub
+		}
+		ha, hb := SourceHeight(a), SourceHeight(b)
+		switch by {
+		case RankByPlayback:
+			if ta, tb := playbackTier(a), playbackTier(b); ta != tb {
+				return ta < tb
+			}
+			if a.Headroom != b.Headroom {
+				return a.Headroom > b.Headroom
+			}
+		case RankByResolution:
+			if ha != hb {
+				return ha > hb
+			}
+		}
+		if a.DownloadSpeed != b.DownloadSpeed {
+			return a.DownloadSpeed > b.DownloadSpeed
+		}
+		if ha != hb {
+			return ha > hb
+		}
+		return a.Meta.CheckedAt.After(b.Meta.CheckedAt)
+	})
+}
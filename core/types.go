@@ -4,6 +4,25 @@ import (
 	"time"
 )
 
+// StreamMeta 直播源的附加信息，来自搜索页面或导入的播放列表
+type StreamMeta struct {
+	Channel    string    // 频道名称
+	Group      string    // 分组，如 M3U 的 group-title
+	TvgID      string    // EPG 频道 ID (tvg-id)
+	TvgName    string    // EPG 频道名 (tvg-name)
+	TvgLogo    string    // 台标地址 (tvg-logo)
+	Resolution string    // 来源标注的分辨率，如 "1080p"
+	CheckedAt  time.Time // 来源最后一次检测该链接的时间，未知时为零值
+	Location   string    // 地区/运营商信息
+	Source     string    // 结果来源，如搜索源名称或播放列表路径
+}
+
+// Candidate 是一个待测试的直播源
+type Candidate struct {
+	URL  string
+	Meta StreamMeta
+}
+
 // M3U8Source represents a found M3U8 live stream source
 type M3U8Source struct {
 	URL           string
@@ -13,6 +32,77 @@ type M3U8Source struct {
 	Error         string
 	DataSize      int64 // bytes downloaded
 	DownloadTime  time.Duration
+	Measurement   Measurement // 下载速度是实测、估算还是没有测量
+	Meta          StreamMeta
+
+	Live      LiveState // 重新加载播放列表验证的直播状态
+	Container Container // 检测到的容器格式：通用 HTTP 流的数据开头，或 HLS/DASH 的媒体片段
+
+	// 从媒体片段中检测到的信息
+	Codecs           []string // 基本流编码，如 H.264、AAC
+	ContinuityErrors int      // MPEG-TS 连续计数器错误数，fMP4 片段为解码时间 (tfdt) 不连续的次数
+	Width            int      // 从视频 SPS 解析的分辨率，未检测到时为 0
+	Height           int
+	FrameRate        float64 // 帧率，未检测到时为 0
+
+	// 媒体码率和播放余量，由片段大小和 #EXTINF 时长计算，无法计算时为 0
+	Bitrate  float64 // 媒体码率 (kbit/s)
+	Headroom float64 // 下载吞吐量 / 媒体码率，大于 1 时能够实时播放
+
+	// UDP/RTP 组播的接收统计
+	Packets     int64         // 收到的 UDP 包数
+	LostPackets int64         // 根据 RTP 序列号计算的丢包数
+	Jitter      time.Duration // 到达间隔抖动 (RFC 3550)
+
+	ProxyClients int // udpxy 状态页中的客户端数量（含本次测试），未查询时为 0，查询失败时为 -1
+
+	// 主播放列表的变体测试结果，普通媒体播放列表为空
+	Variants        []VariantResult
+	SelectedVariant string // 排名所使用的变体地址
+}
+
+// Measurement 表示 M3U8Source.DownloadSpeed 的来源
+type Measurement string
+
+const (
+	Measured   Measurement = "measured"   // 由足够多的实际下载数据计算
+	Estimated  Measurement = "estimated"  // 由实际下载计算，但数据量太少，结果不可靠
+	Unmeasured Measurement = "unmeasured" // 没有下载到媒体数据，DownloadSpeed 为 0
+)
+
+// LiveState 是通过重新加载媒体播放列表验证的直播状态
+type LiveState string
+
+const (
+	LiveUnknown  LiveState = ""        // 未验证（非 HLS 直播源或重新加载失败）
+	LiveVerified LiveState = "live"    // 媒体序列号前进并出现了新片段
+	LiveFrozen   LiveState = "frozen"  // 重新加载后播放列表没有任何变化
+	LiveLooping  LiveState = "looping" // 片段地址重复使用，循环播放的静态内容
+	LiveStatic   LiveState = "static"  // 点播或已结束的播放列表 (VOD / #EXT-X-ENDLIST)
+)
+
+// Container 是按数据开头识别出的容器格式，来自通用 HTTP 流的数据开头或 HLS/DASH 的媒体片段
+type Container string
+
+const (
+	ContainerUnknown Container = ""       // 未检测或无法识别（组播、RTMP、RTSP、WebM 片段等）
+	ContainerMPEGTS  Container = "mpegts" // MPEG-TS
+	ContainerFLV     Container = "flv"    // HTTP-FLV
+	ContainerMP4     Container = "mp4"    // 普通 MP4，moov 之后没有分片
+	ContainerFMP4    Container = "fmp4"   // 分片 MP4 / CMAF (moof + mdat)
+	ContainerADTS    Container = "adts"   // AAC ADTS 音频流
+)
+
+// VariantResult 是 HLS 主播放列表中一个变体流的测试结果
+type VariantResult struct {
+	URL           string
+	Bandwidth     int64  // 声明的码率 (BANDWIDTH, bit/s)
+	Resolution    string // 声明的分辨率 (RESOLUTION)
+	Codecs        string // 声明的编码 (CODECS)
+	Valid         bool
+	Error         string
+	Latency       time.Duration
+	DownloadSpeed float64 // KB/s
 }
 
 func min(a, b int) int {
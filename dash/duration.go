@@ -0,0 +1,56 @@
+package dash
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// isoDurationRegex 匹配 xs:duration，如 PT2S、PT1H30M、P1DT0.5S
+var isoDurationRegex = regexp.MustCompile(`^(-)?P(?:(\d+(?:\.\d+)?)Y)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// isoDurationUnits 是各分量对应的时长，年和月按 365 天和 30 天近似
+var isoDurationUnits = []time.Duration{
+	365 * 24 * time.Hour, 30 * 24 * time.Hour, 24 * time.Hour,
+	time.Hour, time.Minute, time.Second,
+}
+
+// parseDuration 解析 ISO 8601 时长，空字符串或无法解析时返回 0
+func parseDuration(s string) time.Duration {
+	m := isoDurationRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	var d time.Duration
+	for i, unit := range isoDurationUnits {
+		if m[i+2] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(m[i+2], 64)
+		if err != nil {
+			return 0
+		}
+		d += time.Duration(v * float64(unit))
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d
+}
+
+// dateTimeLayouts 是 MPD 中常见的时间格式，不带时区的按 UTC 处理
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z0700",
+}
+
+// parseDateTime 解析 xs:dateTime，空字符串或无法解析时返回零值
+func parseDateTime(s string) time.Time {
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,55 @@
+package dash
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+	}{
+		{"PT2S", 2 * time.Second},
+		{"PT1.5S", 1500 * time.Millisecond},
+		{"PT0.04S", 40 * time.Millisecond},
+		{"PT1H30M", 90 * time.Minute},
+		{"PT1M", time.Minute},
+		{"P1DT2H", 26 * time.Hour},
+		{"P1DT0.5S", 24*time.Hour + 500*time.Millisecond},
+		{"P1Y", 365 * 24 * time.Hour},
+		{"P1M", 30 * 24 * time.Hour},
+		{"P2D", 48 * time.Hour},
+		{"-PT5S", -5 * time.Second},
+		{"", 0},
+		{"2S", 0},
+		{"P1S", 0},
+		{"PTxS", 0},
+		{"PT1S ", 0},
+	}
+	for _, tt := range tests {
+		if got := parseDuration(tt.s); got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2024-01-02T03:04:05.25Z", time.Date(2024, 1, 2, 3, 4, 5, 250000000, time.UTC)},
+		{"2024-01-02T11:04:05+08:00", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2024-01-02T03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2024-01-02T11:04:05+0800", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"", time.Time{}},
+		{"yesterday", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseDateTime(tt.s); !got.Equal(tt.want) {
+			t.Errorf("parseDateTime(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
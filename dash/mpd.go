@@ -0,0 +1,246 @@
+// Package dash 解析 MPEG-DASH 的 MPD 清单 (ISO/IEC 23009-1)，生成各表示的片段地址
+package dash
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Manifest 是解析后的 MPD
+type Manifest struct {
+	Dynamic               bool // type="dynamic" 表示直播，static 表示点播
+	AvailabilityStartTime time.Time
+	PublishTime           time.Time
+	MinimumUpdatePeriod   time.Duration
+	Duration              time.Duration // mediaPresentationDuration，直播时通常为 0
+	TimeShiftBufferDepth  time.Duration
+	MaxSegmentDuration    time.Duration
+	Periods               []Period
+}
+
+// Period 是 MPD 中的一个时段
+type Period struct {
+	ID             string
+	Start          time.Duration // 相对于 availabilityStartTime（直播）或节目开始（点播）
+	Duration       time.Duration // 未知时为 0
+	AdaptationSets []AdaptationSet
+}
+
+// AdaptationSet 是一组可以互相切换的表示
+type AdaptationSet struct {
+	ContentType     string // video、audio、text，未声明时由 mimeType 或编码推断
+	MimeType        string
+	Lang            string
+	Representations []Representation
+}
+
+// Representation 是一种编码参数的媒体流，属性已合并上层 AdaptationSet 的声明
+type Representation struct {
+	ID        string
+	Bandwidth int64 // bit/s
+	Width     int
+	Height    int
+	FrameRate float64
+	Codecs    string
+	MimeType  string
+	BaseURL   string // 解析后的绝对地址
+
+	Template *SegmentTemplate // 合并了 Period 和 AdaptationSet 上的 SegmentTemplate
+	List     *SegmentList
+
+	hasBaseURL bool // AdaptationSet 或 Representation 声明了 BaseURL，SegmentBase 的单文件表示依赖它
+}
+
+// SegmentTemplate 描述按模板生成的片段地址
+type SegmentTemplate struct {
+	Media                  string // 未展开的模板，相对于 Representation.BaseURL
+	Initialization         string
+	Timescale              uint64
+	Duration               uint64 // 以 Timescale 为单位的片段时长，使用 SegmentTimeline 时为 0
+	StartNumber            uint64
+	PresentationTimeOffset uint64
+	Timeline               []TimelineEntry
+}
+
+// TimelineEntry 是 SegmentTimeline 中的一个 S 元素
+type TimelineEntry struct {
+	T      uint64 // 开始时间，HasT 为 false 时接着上一个片段
+	HasT   bool
+	D      uint64
+	Repeat int64 // 额外重复的次数，-1 表示重复到下一个 S 元素或时段结束
+}
+
+// SegmentList 描述显式列出的片段地址
+type SegmentList struct {
+	Initialization string
+	Media          []string
+	Timescale      uint64
+	Duration       uint64
+}
+
+// Parse 解析 MPD 文档，baseURL 是清单自身的地址，用于解析相对的 BaseURL 和片段地址
+func Parse(data []byte, baseURL string) (*Manifest, error) {
+	var doc xmlMPD
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid MPD: %v", err)
+	}
+	if doc.XMLName.Local != "MPD" {
+		return nil, fmt.Errorf("root element is <%s>, not <MPD>", doc.XMLName.Local)
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest URL: %v", err)
+	}
+	base = resolveBase(base, doc.BaseURL)
+
+	m := &Manifest{
+		Dynamic:               doc.Type == "dynamic",
+		AvailabilityStartTime: parseDateTime(doc.AvailabilityStartTime),
+		PublishTime:           parseDateTime(doc.PublishTime),
+		MinimumUpdatePeriod:   parseDuration(doc.MinimumUpdatePeriod),
+		Duration:              parseDuration(doc.MediaPresentationDuration),
+		TimeShiftBufferDepth:  parseDuration(doc.TimeShiftBufferDepth),
+		MaxSegmentDuration:    parseDuration(doc.MaxSegmentDuration),
+	}
+
+	var next time.Duration // 未声明 start 时接着上一个时段
+	for _, xp := range doc.Periods {
+		p := Period{ID: xp.ID, Start: next, Duration: parseDuration(xp.Duration)}
+		if xp.Start != "" {
+			p.Start = parseDuration(xp.Start)
+		}
+		if p.Duration > 0 {
+			next = p.Start + p.Duration
+		}
+		periodBase := resolveBase(base, xp.BaseURL)
+		for _, xa := range xp.AdaptationSets {
+			p.AdaptationSets = append(p.AdaptationSets, newAdaptationSet(periodBase, xp.SegmentTemplate, xa))
+		}
+		m.Periods = append(m.Periods, p)
+	}
+	// 最后一个时段的时长可以由节目总时长推出
+	if n := len(m.Periods); n > 0 && m.Periods[n-1].Duration == 0 && m.Duration > m.Periods[n-1].Start {
+		m.Periods[n-1].Duration = m.Duration - m.Periods[n-1].Start
+	}
+	if len(m.Periods) == 0 {
+		return nil, fmt.Errorf("MPD has no periods")
+	}
+	return m, nil
+}
+
+func newAdaptationSet(base *url.URL, periodTemplate *xmlSegmentTemplate, xa xmlAdaptationSet) AdaptationSet {
+	as := AdaptationSet{ContentType: xa.ContentType, MimeType: xa.MimeType, Lang: xa.Lang}
+	asBase := resolveBase(base, xa.BaseURL)
+	asTemplate := periodTemplate.merge(xa.SegmentTemplate)
+	for _, xr := range xa.Representations {
+		r := Representation{
+			ID:        xr.ID,
+			Bandwidth: xr.Bandwidth,
+			Width:     firstNonZero(xr.Width, xa.Width),
+			Height:    firstNonZero(xr.Height, xa.Height),
+			FrameRate: parseFrameRate(firstNonEmpty(xr.FrameRate, xa.FrameRate)),
+			Codecs:    firstNonEmpty(xr.Codecs, xa.Codecs),
+			MimeType:  firstNonEmpty(xr.MimeType, xa.MimeType),
+		}
+		repBase := resolveBase(asBase, xr.BaseURL)
+		r.BaseURL = repBase.String()
+		r.hasBaseURL = len(xa.BaseURL) > 0 || len(xr.BaseURL) > 0
+		if t := asTemplate.merge(xr.SegmentTemplate); t != nil {
+			r.Template = t.build()
+		} else if list := firstList(xr.SegmentList, xa.SegmentList); list != nil {
+			r.List = list.build(repBase)
+		}
+		as.Representations = append(as.Representations, r)
+	}
+	if as.ContentType == "" {
+		as.ContentType = inferContentType(as)
+	}
+	return as
+}
+
+// inferContentType 根据 mimeType 或编码推断内容类型
+func inferContentType(as AdaptationSet) string {
+	mime, codecs := as.MimeType, ""
+	if len(as.Representations) > 0 {
+		mime = firstNonEmpty(mime, as.Representations[0].MimeType)
+		codecs = as.Representations[0].Codecs
+	}
+	if kind, _, ok := strings.Cut(mime, "/"); ok && (kind == "video" || kind == "audio" || kind == "text") {
+		return kind
+	}
+	switch {
+	case strings.HasPrefix(codecs, "avc"), strings.HasPrefix(codecs, "hvc"), strings.HasPrefix(codecs, "hev"),
+		strings.HasPrefix(codecs, "av01"), strings.HasPrefix(codecs, "vp09"):
+		return "video"
+	case strings.HasPrefix(codecs, "mp4a"), strings.HasPrefix(codecs, "ac-3"), strings.HasPrefix(codecs, "ec-3"),
+		strings.HasPrefix(codecs, "opus"):
+		return "audio"
+	}
+	return ""
+}
+
+// resolveBase 按 BaseURL 元素解析新的基地址，有多个 BaseURL 时使用第一个
+func resolveBase(base *url.URL, refs []string) *url.URL {
+	if len(refs) == 0 || strings.TrimSpace(refs[0]) == "" {
+		return base
+	}
+	ref, err := url.Parse(strings.TrimSpace(refs[0]))
+	if err != nil {
+		return base
+	}
+	return base.ResolveReference(ref)
+}
+
+// resolveURL 将相对地址按基地址解析为绝对地址
+func resolveURL(base *url.URL, ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// parseFrameRate 解析 "25" 或 "30000/1001" 形式的帧率
+func parseFrameRate(s string) float64 {
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0
+		}
+		return n / d
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstNonZero(values ...int) int {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+func firstList(lists ...*xmlSegmentList) *xmlSegmentList {
+	for _, l := range lists {
+		if l != nil {
+			return l
+		}
+	}
+	return nil
+}
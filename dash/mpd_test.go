@@ -0,0 +1,145 @@
+package dash
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const liveMPD = `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="dynamic" availabilityStartTime="2024-01-01T00:00:00Z"
+	publishTime="2024-01-01T00:10:00Z" minimumUpdatePeriod="PT2S" timeShiftBufferDepth="PT30S" maxSegmentDuration="PT2S">
+	<BaseURL>cdn/</BaseURL>
+	<Period id="p0" start="PT0S">
+		<AdaptationSet mimeType="video/mp4" codecs="avc1.64001f" frameRate="30000/1001">
+			<SegmentTemplate timescale="1000" duration="2000" startNumber="10"
+				media="$RepresentationID$/$Number%05d$.m4s" initialization="$RepresentationID$/init.mp4"/>
+			<Representation id="720p" bandwidth="3000000" width="1280" height="720"/>
+			<Representation id="360p" bandwidth="800000" width="640" height="360">
+				<SegmentTemplate startNumber="1"/>
+			</Representation>
+		</AdaptationSet>
+		<AdaptationSet lang="zh">
+			<Representation id="aac" bandwidth="128000" codecs="mp4a.40.2">
+				<BaseURL>audio/</BaseURL>
+				<SegmentList timescale="1" duration="2">
+					<Initialization sourceURL="init.mp4"/>
+					<SegmentURL media="1.m4s"/>
+					<SegmentURL media="2.m4s"/>
+				</SegmentList>
+			</Representation>
+		</AdaptationSet>
+	</Period>
+</MPD>`
+
+func TestParseLive(t *testing.T) {
+	m, err := Parse([]byte(liveMPD), "http://example.com/live/stream.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !m.Dynamic || !m.AvailabilityStartTime.Equal(ast) || !m.PublishTime.Equal(ast.Add(10*time.Minute)) {
+		t.Errorf("manifest = %+v", m)
+	}
+	if m.MinimumUpdatePeriod != 2*time.Second || m.TimeShiftBufferDepth != 30*time.Second || m.MaxSegmentDuration != 2*time.Second {
+		t.Errorf("durations = %v %v %v", m.MinimumUpdatePeriod, m.TimeShiftBufferDepth, m.MaxSegmentDuration)
+	}
+	if len(m.Periods) != 1 || len(m.Periods[0].AdaptationSets) != 2 {
+		t.Fatalf("periods = %+v", m.Periods)
+	}
+
+	p := &m.Periods[0]
+	videoSet, audioSet := p.AdaptationSets[0], p.AdaptationSets[1]
+	if videoSet.ContentType != "video" || audioSet.ContentType != "audio" || audioSet.Lang != "zh" {
+		t.Errorf("content types = %q, %q", videoSet.ContentType, audioSet.ContentType)
+	}
+
+	hd := &videoSet.Representations[0]
+	if hd.Codecs != "avc1.64001f" || hd.Width != 1280 || hd.FrameRate < 29.97 || hd.FrameRate > 29.98 {
+		t.Errorf("720p = %+v", hd)
+	}
+	if sd := videoSet.Representations[1].Template; sd.StartNumber != 1 || sd.Timescale != 1000 || sd.Duration != 2000 {
+		t.Errorf("360p template = %+v", sd)
+	}
+
+	now := ast.Add(100 * time.Second)
+	init, segments, err := m.Segments(p, hd, now, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if init != "http://example.com/live/cdn/720p/init.mp4" {
+		t.Errorf("init = %q", init)
+	}
+	var urls []string
+	for _, s := range segments {
+		urls = append(urls, s.URL)
+	}
+	want := []string{
+		"http://example.com/live/cdn/720p/00057.m4s",
+		"http://example.com/live/cdn/720p/00058.m4s",
+		"http://example.com/live/cdn/720p/00059.m4s",
+	}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("segment URLs = %v, want %v", urls, want)
+	}
+
+	init, segments, err = m.Segments(p, &audioSet.Representations[0], now, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSegments := []Segment{
+		{URL: "http://example.com/live/cdn/audio/1.m4s", Number: 1, Duration: 2 * time.Second},
+		{URL: "http://example.com/live/cdn/audio/2.m4s", Number: 2, Start: 2 * time.Second, Duration: 2 * time.Second},
+	}
+	if init != "http://example.com/live/cdn/audio/init.mp4" || !reflect.DeepEqual(segments, wantSegments) {
+		t.Errorf("SegmentList = %q %+v", init, segments)
+	}
+}
+
+func TestParsePeriods(t *testing.T) {
+	const mpd = `<MPD type="static" mediaPresentationDuration="PT20S">
+		<Period id="a" duration="PT8S"><AdaptationSet mimeType="video/mp4"><Representation id="v"><BaseURL>a.mp4</BaseURL></Representation></AdaptationSet></Period>
+		<Period id="b"><AdaptationSet mimeType="video/mp4"><Representation id="v"><BaseURL>b.mp4</BaseURL></Representation></AdaptationSet></Period>
+	</MPD>`
+	m, err := Parse([]byte(mpd), "http://example.com/vod/movie.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Dynamic || len(m.Periods) != 2 {
+		t.Fatalf("manifest = %+v", m)
+	}
+	if b := m.Periods[1]; b.Start != 8*time.Second || b.Duration != 12*time.Second {
+		t.Errorf("second period start %v duration %v, want 8s and 12s", b.Start, b.Duration)
+	}
+	if got := m.PeriodAt(time.Now()); got.ID != "a" {
+		t.Errorf("PeriodAt on a static MPD = %q, want a", got.ID)
+	}
+	_, segments, err := m.Segments(&m.Periods[1], &m.Periods[1].AdaptationSets[0].Representations[0], time.Now(), 3)
+	if err != nil || len(segments) != 1 || segments[0].URL != "http://example.com/vod/b.mp4" {
+		t.Errorf("single file segments = %+v, %v", segments, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		`not xml`,
+		`<html><body/></html>`,
+		`<MPD type="static"></MPD>`,
+	}
+	for _, doc := range tests {
+		if _, err := Parse([]byte(doc), "http://example.com/a.mpd"); err == nil {
+			t.Errorf("Parse(%q): expected error", doc)
+		}
+	}
+}
+
+func TestPeriodAt(t *testing.T) {
+	ast := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := &Manifest{Dynamic: true, AvailabilityStartTime: ast, Periods: []Period{{ID: "a"}, {ID: "b", Start: time.Hour}}}
+	if got := m.PeriodAt(ast.Add(30 * time.Minute)); got.ID != "a" {
+		t.Errorf("PeriodAt(30m) = %q, want a", got.ID)
+	}
+	if got := m.PeriodAt(ast.Add(2 * time.Hour)); got.ID != "b" {
+		t.Errorf("PeriodAt(2h) = %q, want b", got.ID)
+	}
+}
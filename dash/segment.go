@@ -0,0 +1,256 @@
+package dash
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Segment 是一个媒体片段
+type Segment struct {
+	URL      string
+	Number   uint64
+	Time     uint64        // 以 timescale 为单位的开始时间，用于 $Time$
+	Start    time.Duration // 相对于 Period 开始的呈现时间，已减去 presentationTimeOffset
+	Duration time.Duration // SegmentBase 的单文件表示为 0
+}
+
+// PeriodAt 返回 now 时刻正在播放的时段。点播或没有 availabilityStartTime 时返回第一个时段
+func (m *Manifest) PeriodAt(now time.Time) *Period {
+	current := &m.Periods[0]
+	if !m.Dynamic || m.AvailabilityStartTime.IsZero() {
+		return current
+	}
+	elapsed := now.Sub(m.AvailabilityStartTime)
+	for i := range m.Periods {
+		if m.Periods[i].Start <= elapsed {
+			current = &m.Periods[i]
+		}
+	}
+	return current
+}
+
+// Segments 返回表示的初始化片段地址（没有时为空）和媒体片段。
+// 直播时返回截至 now 已经完整可用的最后 limit 个片段，点播时返回最前面的 limit 个片段。
+func (m *Manifest) Segments(p *Period, r *Representation, now time.Time, limit int) (string, []Segment, error) {
+	if limit <= 0 {
+		limit = 1
+	}
+	base, err := url.Parse(r.BaseURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid BaseURL: %v", err)
+	}
+
+	switch {
+	case r.Template != nil:
+		st := r.Template
+		if st.Media == "" {
+			return "", nil, fmt.Errorf("SegmentTemplate has no media attribute")
+		}
+		var init string
+		if st.Initialization != "" {
+			init = resolveURL(base, expandTemplate(st.Initialization, r, 0, 0))
+		}
+		var segments []Segment
+		switch {
+		case len(st.Timeline) > 0:
+			segments = m.timelineSegments(p, st, now, limit)
+		case st.Duration > 0:
+			segments, err = m.numberSegments(p, st, now, limit)
+		default:
+			err = fmt.Errorf("SegmentTemplate has neither duration nor SegmentTimeline")
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		for i := range segments {
+			seg := &segments[i]
+			seg.URL = resolveURL(base, expandTemplate(st.Media, r, seg.Number, seg.Time))
+		}
+		if len(segments) == 0 {
+			return init, nil, fmt.Errorf("no segments available yet")
+		}
+		return init, segments, nil
+
+	case r.List != nil:
+		list := r.List
+		if len(list.Media) == 0 {
+			return "", nil, fmt.Errorf("SegmentList is empty")
+		}
+		dur := scale(list.Duration, list.Timescale)
+		first, last := 0, min(limit, len(list.Media))
+		if m.Dynamic {
+			first, last = max(0, len(list.Media)-limit), len(list.Media)
+		}
+		var segments []Segment
+		for i := first; i < last; i++ {
+			segments = append(segments, Segment{URL: list.Media[i], Number: uint64(i + 1), Start: time.Duration(i) * dur, Duration: dur})
+		}
+		return list.Initialization, segments, nil
+
+	case r.hasBaseURL:
+		// SegmentBase 或单文件表示，整个 BaseURL 就是一个片段
+		return "", []Segment{{URL: r.BaseURL, Number: 1}}, nil
+	}
+	return "", nil, fmt.Errorf("representation %q has no SegmentTemplate, SegmentList or BaseURL", r.ID)
+}
+
+// timelineSegments 展开 SegmentTimeline。直播时跳过 limit 之前的重复片段，避免从 availabilityStartTime 开始逐个生成
+func (m *Manifest) timelineSegments(p *Period, st *SegmentTemplate, now time.Time, limit int) []Segment {
+	var segments []Segment
+	var t uint64
+	number := st.StartNumber
+	for i, s := range st.Timeline {
+		if s.HasT {
+			t = s.T
+		}
+		if s.D == 0 {
+			break
+		}
+		count := uint64(s.Repeat + 1)
+		if s.Repeat < 0 {
+			count = m.openRepeatCount(p, st, i, t, s.D, now)
+		}
+		if m.Dynamic && count > uint64(limit) {
+			skip := count - uint64(limit)
+			t += skip * s.D
+			number += skip
+			count = uint64(limit)
+		}
+		for ; count > 0; count-- {
+			if !m.Dynamic && len(segments) >= limit {
+				return segments
+			}
+			segments = append(segments, Segment{
+				Number:   number,
+				Time:     t,
+				Start:    scale(t-min(t, st.PresentationTimeOffset), st.Timescale),
+				Duration: scale(s.D, st.Timescale),
+			})
+			t += s.D
+			number++
+		}
+		if m.Dynamic && len(segments) > limit {
+			segments = segments[len(segments)-limit:]
+		}
+	}
+	return segments
+}
+
+// openRepeatCount 计算 r="-1" 的 S 元素实际包含的片段数：重复到下一个 S 的开始时间、时段结束，
+// 或者直播时重复到 now 为止已经完整可用的片段
+func (m *Manifest) openRepeatCount(p *Period, st *SegmentTemplate, i int, t, d uint64, now time.Time) uint64 {
+	var end uint64
+	switch {
+	case i+1 < len(st.Timeline) && st.Timeline[i+1].HasT:
+		end = st.Timeline[i+1].T
+	case p.Duration > 0 && (!m.Dynamic || m.AvailabilityStartTime.IsZero()):
+		end = st.PresentationTimeOffset + scaleTo(p.Duration, st.Timescale)
+	case m.Dynamic && !m.AvailabilityStartTime.IsZero():
+		elapsed := now.Sub(m.AvailabilityStartTime) - p.Start
+		if p.Duration > 0 {
+			elapsed = min(elapsed, p.Duration)
+		}
+		if elapsed <= 0 {
+			return 0
+		}
+		end = st.PresentationTimeOffset + scaleTo(elapsed, st.Timescale)
+		if end <= t {
+			return 0
+		}
+		return (end - t) / d
+	default:
+		return 1
+	}
+	if end <= t {
+		return 1
+	}
+	return (end - t + d - 1) / d
+}
+
+// numberSegments 按 duration 和 startNumber 计算片段。直播时根据 availabilityStartTime 推算直播边缘，
+// 点播时根据时段时长计算片段总数
+func (m *Manifest) numberSegments(p *Period, st *SegmentTemplate, now time.Time, limit int) ([]Segment, error) {
+	dur := scale(st.Duration, st.Timescale)
+	if dur <= 0 {
+		return nil, fmt.Errorf("invalid segment duration")
+	}
+
+	var first, total uint64
+	if m.Dynamic {
+		if m.AvailabilityStartTime.IsZero() {
+			return nil, fmt.Errorf("dynamic MPD has no availabilityStartTime")
+		}
+		elapsed := now.Sub(m.AvailabilityStartTime) - p.Start
+		if p.Duration > 0 {
+			elapsed = min(elapsed, p.Duration)
+		}
+		if elapsed < dur {
+			return nil, nil
+		}
+		total = uint64(elapsed / dur)
+		window := uint64(limit)
+		if m.TimeShiftBufferDepth > 0 {
+			window = min(window, max(1, uint64(m.TimeShiftBufferDepth/dur)))
+		}
+		if total > window {
+			first = total - window
+		}
+	} else {
+		if p.Duration <= 0 {
+			return nil, fmt.Errorf("static MPD has no period or presentation duration")
+		}
+		total = min(uint64((p.Duration+dur-1)/dur), uint64(limit))
+	}
+
+	var segments []Segment
+	for i := first; i < total; i++ {
+		segments = append(segments, Segment{Number: st.StartNumber + i, Time: st.PresentationTimeOffset + i*st.Duration, Start: time.Duration(i) * dur, Duration: dur})
+	}
+	return segments, nil
+}
+
+// templateIdentifierRegex 匹配模板标识符，如 $RepresentationID$、$Number%05d$，以及转义的 $$
+var templateIdentifierRegex = regexp.MustCompile(`\$(RepresentationID|Number|Bandwidth|Time|)(?:%0(\d+)d)?\$`)
+
+// expandTemplate 展开 SegmentTemplate 中的标识符
+func expandTemplate(tmpl string, r *Representation, number, t uint64) string {
+	return templateIdentifierRegex.ReplaceAllStringFunc(tmpl, func(match string) string {
+		sub := templateIdentifierRegex.FindStringSubmatch(match)
+		var v uint64
+		switch sub[1] {
+		case "":
+			return "$"
+		case "RepresentationID":
+			return r.ID
+		case "Number":
+			v = number
+		case "Bandwidth":
+			v = uint64(r.Bandwidth)
+		case "Time":
+			v = t
+		}
+		if width, err := strconv.Atoi(sub[2]); err == nil {
+			return fmt.Sprintf("%0*d", width, v)
+		}
+		return strconv.FormatUint(v, 10)
+	})
+}
+
+// scale 将以 timescale 为单位的时间换算为时长
+func scale(v, timescale uint64) time.Duration {
+	if timescale == 0 {
+		timescale = 1
+	}
+	return time.Duration(v/timescale)*time.Second + time.Duration(v%timescale)*time.Second/time.Duration(timescale)
+}
+
+// scaleTo 将时长换算为以 timescale 为单位的时间
+func scaleTo(d time.Duration, timescale uint64) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	return uint64(d/time.Second)*timescale + uint64(d%time.Second)*timescale/uint64(time.Second)
+}
@@ -0,0 +1,242 @@
+package dash
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	r := &Representation{ID: "video_1", Bandwidth: 2000000}
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"$RepresentationID$/seg-$Number%05d$.m4s", "video_1/seg-00042.m4s"},
+		{"$RepresentationID$/$Time$.m4s", "video_1/900000.m4s"},
+		{"t-$Time%010d$.m4s", "t-0000900000.m4s"},
+		{"$Bandwidth$/$Number$.ts", "2000000/42.ts"},
+		{"a$$b-$Number$", "a$b-42"},
+		{"$Number%03d$", "042"},
+		{"$Number%01d$", "42"},
+		{"init.mp4", "init.mp4"},
+		{"$Foo$-$Number$", "$Foo$-42"},
+	}
+	for _, tt := range tests {
+		if got := expandTemplate(tt.tmpl, r, 42, 900000); got != tt.want {
+			t.Errorf("expandTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+// seg 构造以毫秒为 timescale 的片段
+func seg(number, t, start, d uint64) Segment {
+	return Segment{
+		Number:   number,
+		Time:     t,
+		Start:    time.Duration(start) * time.Millisecond,
+		Duration: time.Duration(d) * time.Millisecond,
+	}
+}
+
+func TestTimelineSegments(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		dynamic  bool
+		ast      time.Time
+		period   Period
+		pto      uint64
+		timeline []TimelineEntry
+		limit    int
+		want     []Segment
+	}{
+		{
+			name:     "static repeats",
+			timeline: []TimelineEntry{{T: 0, HasT: true, D: 2000, Repeat: 2}, {D: 1000}},
+			limit:    10,
+			want:     []Segment{seg(1, 0, 0, 2000), seg(2, 2000, 2000, 2000), seg(3, 4000, 4000, 2000), seg(4, 6000, 6000, 1000)},
+		},
+		{
+			name:     "static limit",
+			timeline: []TimelineEntry{{T: 0, HasT: true, D: 2000, Repeat: 2}, {D: 1000}},
+			limit:    2,
+			want:     []Segment{seg(1, 0, 0, 2000), seg(2, 2000, 2000, 2000)},
+		},
+		{
+			name:     "open repeat up to the next S",
+			timeline: []TimelineEntry{{T: 0, HasT: true, D: 2000, Repeat: -1}, {T: 9000, HasT: true, D: 1000}},
+			limit:    10,
+			want: []Segment{seg(1, 0, 0, 2000), seg(2, 2000, 2000, 2000), seg(3, 4000, 4000, 2000),
+				seg(4, 6000, 6000, 2000), seg(5, 8000, 8000, 2000), seg(6, 9000, 9000, 1000)},
+		},
+		{
+			name:     "open repeat at the end up to the period end",
+			period:   Period{Duration: 9 * time.Second},
+			timeline: []TimelineEntry{{T: 0, HasT: true, D: 2000, Repeat: -1}},
+			limit:    10,
+			want: []Segment{seg(1, 0, 0, 2000), seg(2, 2000, 2000, 2000), seg(3, 4000, 4000, 2000),
+				seg(4, 6000, 6000, 2000), seg(5, 8000, 8000, 2000)},
+		},
+		{
+			name:     "open repeat at the end without period duration",
+			timeline: []TimelineEntry{{T: 0, HasT: true, D: 2000, Repeat: -1}},
+			limit:    10,
+			want:     []Segment{seg(1, 0, 0, 2000)},
+		},
+		{
+			name:     "presentationTimeOffset",
+			pto:      5000,
+			timeline: []TimelineEntry{{T: 5000, HasT: true, D: 2000, Repeat: 1}},
+			limit:    10,
+			want:     []Segment{seg(1, 5000, 0, 2000), seg(2, 7000, 2000, 2000)},
+		},
+		{
+			name:     "dynamic keeps the last segments",
+			dynamic:  true,
+			ast:      now.Add(-time.Hour),
+			timeline: []TimelineEntry{{T: 0, HasT: true, D: 2000, Repeat: 4}, {D: 1000, Repeat: 1}},
+			limit:    3,
+			want:     []Segment{seg(5, 8000, 8000, 2000), seg(6, 10000, 10000, 1000), seg(7, 11000, 11000, 1000)},
+		},
+		{
+			name:     "dynamic open repeat at the end up to now",
+			dynamic:  true,
+			ast:      now.Add(-101 * time.Second),
+			timeline: []TimelineEntry{{T: 0, HasT: true, D: 2000, Repeat: -1}},
+			limit:    3,
+			want:     []Segment{seg(48, 94000, 94000, 2000), seg(49, 96000, 96000, 2000), seg(50, 98000, 98000, 2000)},
+		},
+		{
+			name:     "dynamic open repeat in a later period",
+			dynamic:  true,
+			ast:      now.Add(-100 * time.Second),
+			period:   Period{Start: 60 * time.Second},
+			timeline: []TimelineEntry{{T: 0, HasT: true, D: 2000, Repeat: -1}},
+			limit:    2,
+			want:     []Segment{seg(19, 36000, 36000, 2000), seg(20, 38000, 38000, 2000)},
+		},
+		{
+			name:     "dynamic open repeat before the first segment is complete",
+			dynamic:  true,
+			ast:      now.Add(-time.Second),
+			timeline: []TimelineEntry{{T: 0, HasT: true, D: 2000, Repeat: -1}},
+			limit:    3,
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		m := &Manifest{Dynamic: tt.dynamic, AvailabilityStartTime: tt.ast}
+		st := &SegmentTemplate{Timescale: 1000, StartNumber: 1, PresentationTimeOffset: tt.pto, Timeline: tt.timeline}
+		got := m.timelineSegments(&tt.period, st, now, tt.limit)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNumberSegments(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		dynamic bool
+		ast     time.Time
+		tsbd    time.Duration
+		period  Period
+		limit   int
+		want    []Segment
+		wantErr bool
+	}{
+		{
+			name:    "live edge",
+			dynamic: true,
+			ast:     now.Add(-101 * time.Second),
+			limit:   3,
+			want:    []Segment{seg(48, 94000, 94000, 2000), seg(49, 96000, 96000, 2000), seg(50, 98000, 98000, 2000)},
+		},
+		{
+			name:    "timeShiftBufferDepth narrows the window",
+			dynamic: true,
+			ast:     now.Add(-100 * time.Second),
+			tsbd:    4 * time.Second,
+			limit:   3,
+			want:    []Segment{seg(49, 96000, 96000, 2000), seg(50, 98000, 98000, 2000)},
+		},
+		{
+			name:    "timeShiftBufferDepth shorter than a segment",
+			dynamic: true,
+			ast:     now.Add(-100 * time.Second),
+			tsbd:    time.Second,
+			limit:   3,
+			want:    []Segment{seg(50, 98000, 98000, 2000)},
+		},
+		{
+			name:    "later period",
+			dynamic: true,
+			ast:     now.Add(-100 * time.Second),
+			period:  Period{Start: 60 * time.Second},
+			limit:   3,
+			want:    []Segment{seg(18, 34000, 34000, 2000), seg(19, 36000, 36000, 2000), seg(20, 38000, 38000, 2000)},
+		},
+		{
+			name:    "period ended",
+			dynamic: true,
+			ast:     now.Add(-100 * time.Second),
+			period:  Period{Duration: 30 * time.Second},
+			limit:   2,
+			want:    []Segment{seg(14, 26000, 26000, 2000), seg(15, 28000, 28000, 2000)},
+		},
+		{
+			name:    "first segment not complete",
+			dynamic: true,
+			ast:     now.Add(-time.Second),
+			limit:   3,
+			want:    nil,
+		},
+		{
+			name:    "dynamic without availabilityStartTime",
+			dynamic: true,
+			limit:   3,
+			wantErr: true,
+		},
+		{
+			name:   "static",
+			period: Period{Duration: 9 * time.Second},
+			limit:  10,
+			want: []Segment{seg(1, 0, 0, 2000), seg(2, 2000, 2000, 2000), seg(3, 4000, 4000, 2000),
+				seg(4, 6000, 6000, 2000), seg(5, 8000, 8000, 2000)},
+		},
+		{
+			name:    "static without duration",
+			limit:   10,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		m := &Manifest{Dynamic: tt.dynamic, AvailabilityStartTime: tt.ast, TimeShiftBufferDepth: tt.tsbd}
+		st := &SegmentTemplate{Timescale: 1000, Duration: 2000, StartNumber: 1}
+		got, err := m.numberSegments(&tt.period, st, now, tt.limit)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	if got := scale(90000*3+45000, 90000); got != 3500*time.Millisecond {
+		t.Errorf("scale = %v, want 3.5s", got)
+	}
+	if got := scale(7, 0); got != 7*time.Second {
+		t.Errorf("scale with timescale 0 = %v, want 7s", got)
+	}
+	if got := scaleTo(3500*time.Millisecond, 90000); got != 315000 {
+		t.Errorf("scaleTo = %d, want 315000", got)
+	}
+	if got := scaleTo(-time.Second, 90000); got != 0 {
+		t.Errorf("scaleTo negative = %d, want 0", got)
+	}
+}
@@ -0,0 +1,170 @@
+package dash
+
+import (
+	"encoding/xml"
+	"net/url"
+)
+
+// 以下是 MPD 文档的 XML 结构，只包含测试需要的元素和属性。
+// SegmentTemplate 的属性用指针表示，以便区分未声明和声明为 0，逐层合并
+
+type xmlMPD struct {
+	XMLName                   xml.Name
+	Type                      string      `xml:"type,attr"`
+	AvailabilityStartTime     string      `xml:"availabilityStartTime,attr"`
+	PublishTime               string      `xml:"publishTime,attr"`
+	MinimumUpdatePeriod       string      `xml:"minimumUpdatePeriod,attr"`
+	MediaPresentationDuration string      `xml:"mediaPresentationDuration,attr"`
+	TimeShiftBufferDepth      string      `xml:"timeShiftBufferDepth,attr"`
+	MaxSegmentDuration        string      `xml:"maxSegmentDuration,attr"`
+	BaseURL                   []string    `xml:"BaseURL"`
+	Periods                   []xmlPeriod `xml:"Period"`
+}
+
+type xmlPeriod struct {
+	ID              string              `xml:"id,attr"`
+	Start           string              `xml:"start,attr"`
+	Duration        string              `xml:"duration,attr"`
+	BaseURL         []string            `xml:"BaseURL"`
+	SegmentTemplate *xmlSegmentTemplate `xml:"SegmentTemplate"`
+	AdaptationSets  []xmlAdaptationSet  `xml:"AdaptationSet"`
+}
+
+type xmlAdaptationSet struct {
+	ContentType     string              `xml:"contentType,attr"`
+	MimeType        string              `xml:"mimeType,attr"`
+	Codecs          string              `xml:"codecs,attr"`
+	Lang            string              `xml:"lang,attr"`
+	Width           int                 `xml:"width,attr"`
+	Height          int                 `xml:"height,attr"`
+	FrameRate       string              `xml:"frameRate,attr"`
+	BaseURL         []string            `xml:"BaseURL"`
+	SegmentTemplate *xmlSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *xmlSegmentList     `xml:"SegmentList"`
+	Representations []xmlRepresentation `xml:"Representation"`
+}
+
+type xmlRepresentation struct {
+	ID              string              `xml:"id,attr"`
+	Bandwidth       int64               `xml:"bandwidth,attr"`
+	Width           int                 `xml:"width,attr"`
+	Height          int                 `xml:"height,attr"`
+	FrameRate       string              `xml:"frameRate,attr"`
+	Codecs          string              `xml:"codecs,attr"`
+	MimeType        string              `xml:"mimeType,attr"`
+	BaseURL         []string            `xml:"BaseURL"`
+	SegmentTemplate *xmlSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *xmlSegmentList     `xml:"SegmentList"`
+}
+
+type xmlSegmentTemplate struct {
+	Media                  *string      `xml:"media,attr"`
+	Initialization         *string      `xml:"initialization,attr"`
+	Timescale              *uint64      `xml:"timescale,attr"`
+	Duration               *uint64      `xml:"duration,attr"`
+	StartNumber            *uint64      `xml:"startNumber,attr"`
+	PresentationTimeOffset *uint64      `xml:"presentationTimeOffset,attr"`
+	Timeline               *xmlTimeline `xml:"SegmentTimeline"`
+}
+
+type xmlTimeline struct {
+	S []struct {
+		T *uint64 `xml:"t,attr"`
+		D uint64  `xml:"d,attr"`
+		R int64   `xml:"r,attr"`
+	} `xml:"S"`
+}
+
+type xmlSegmentList struct {
+	Timescale      uint64 `xml:"timescale,attr"`
+	Duration       uint64 `xml:"duration,attr"`
+	Initialization *struct {
+		SourceURL string `xml:"sourceURL,attr"`
+	} `xml:"Initialization"`
+	SegmentURLs []struct {
+		Media string `xml:"media,attr"`
+	} `xml:"SegmentURL"`
+}
+
+// merge 用下层的 SegmentTemplate 覆盖上层已声明的属性，两者都为空时返回 nil
+func (t *xmlSegmentTemplate) merge(child *xmlSegmentTemplate) *xmlSegmentTemplate {
+	if t == nil {
+		return child
+	}
+	if child == nil {
+		return t
+	}
+	merged := *t
+	if child.Media != nil {
+		merged.Media = child.Media
+	}
+	if child.Initialization != nil {
+		merged.Initialization = child.Initialization
+	}
+	if child.Timescale != nil {
+		merged.Timescale = child.Timescale
+	}
+	if child.Duration != nil {
+		merged.Duration = child.Duration
+	}
+	if child.StartNumber != nil {
+		merged.StartNumber = child.StartNumber
+	}
+	if child.PresentationTimeOffset != nil {
+		merged.PresentationTimeOffset = child.PresentationTimeOffset
+	}
+	if child.Timeline != nil {
+		merged.Timeline = child.Timeline
+	}
+	return &merged
+}
+
+// build 填入默认值生成 SegmentTemplate。模板中的 $Number%05d$ 等格式不是合法的 URL 转义，
+// 地址保持原样，展开变量后再按 BaseURL 解析
+func (t *xmlSegmentTemplate) build() *SegmentTemplate {
+	st := &SegmentTemplate{Timescale: 1, StartNumber: 1}
+	if t.Media != nil {
+		st.Media = *t.Media
+	}
+	if t.Initialization != nil {
+		st.Initialization = *t.Initialization
+	}
+	if t.Timescale != nil && *t.Timescale > 0 {
+		st.Timescale = *t.Timescale
+	}
+	if t.Duration != nil {
+		st.Duration = *t.Duration
+	}
+	if t.StartNumber != nil {
+		st.StartNumber = *t.StartNumber
+	}
+	if t.PresentationTimeOffset != nil {
+		st.PresentationTimeOffset = *t.PresentationTimeOffset
+	}
+	if t.Timeline != nil {
+		for _, s := range t.Timeline.S {
+			entry := TimelineEntry{D: s.D, Repeat: s.R}
+			if s.T != nil {
+				entry.T, entry.HasT = *s.T, true
+			}
+			st.Timeline = append(st.Timeline, entry)
+		}
+	}
+	return st
+}
+
+// build 生成 SegmentList，片段地址解析为绝对地址
+func (l *xmlSegmentList) build(base *url.URL) *SegmentList {
+	list := &SegmentList{Timescale: l.Timescale, Duration: l.Duration}
+	if list.Timescale == 0 {
+		list.Timescale = 1
+	}
+	if l.Initialization != nil && l.Initialization.SourceURL != "" {
+		list.Initialization = resolveURL(base, l.Initialization.SourceURL)
+	}
+	for _, s := range l.SegmentURLs {
+		// 没有 media 属性的 SegmentURL 指向 BaseURL 本身
+		list.Media = append(list.Media, resolveURL(base, s.Media))
+	}
+	return list
+}
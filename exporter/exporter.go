@@ -0,0 +1,39 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"m3u8_selector/core"
+)
+
+// Formats 是支持的导出格式
+var Formats = []string{"m3u", "txt", "json", "csv"}
+
+// Write 按指定格式导出测试结果。ranked 是按排名排序的可用直播源，failed 是不可用的直播源；
+// m3u 和 txt 只包含可用直播源，json 和 csv 同时包含失败的直播源及原因。
+func Write(w io.Writer, format string, ranked, failed []core.M3U8Source) error {
+	switch strings.ToLower(format) {
+	case "m3u", "m3u8":
+		return WriteM3U(w, ranked)
+	case "txt":
+		return WriteTXT(w, ranked)
+	case "json":
+		return WriteJSON(w, ranked, failed)
+	case "csv":
+		return WriteCSV(w, ranked, failed)
+	}
+	return fmt.Errorf("不支持的导出格式: %s (可用: %s)", format, strings.Join(Formats, ", "))
+}
+
+// ValidFormat 判断导出格式是否受支持
+func ValidFormat(format string) bool {
+	format = strings.ToLower(format)
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return format == "m3u8"
+}
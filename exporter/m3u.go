@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"m3u8_selector/core"
+)
+
+// WriteM3U 按给定顺序将直播源写成扩展 M3U 播放列表。
+// 每个条目带有 tvg-id、tvg-name、tvg-logo、group-title 属性，以及实测的
+// speed（下载速度，KB/s）和 latency（延迟，毫秒）属性。
+func WriteM3U(w io.Writer, sources []core.M3U8Source) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "#EXTM3U")
+	for i, source := range sources {
+		attrs := []string{}
+		addAttr := func(key, value string) {
+			if value != "" {
+				attrs = append(attrs, fmt.Sprintf(`%s="%s"`, key, escapeAttr(value)))
+			}
+		}
+		addAttr("tvg-id", source.Meta.TvgID)
+		addAttr("tvg-name", source.Meta.TvgName)
+		addAttr("tvg-logo", source.Meta.TvgLogo)
+		addAttr("group-title", source.Meta.Group)
+		addAttr("speed", fmt.Sprintf("%.2f", source.DownloadSpeed))
+		addAttr("latency", fmt.Sprintf("%d", source.Latency.Milliseconds()))
+
+		fmt.Fprintf(bw, "#EXTINF:-1 %s,%s\n", strings.Join(attrs, " "), channelName(source, i))
+		fmt.Fprintln(bw, source.URL)
+	}
+
+	return bw.Flush()
+}
+
+// channelName 返回用于播放列表显示的频道名，没有频道信息时按排名编号
+func channelName(source core.M3U8Source, index int) string {
+	name := source.Meta.Channel
+	if name == "" {
+		name = source.Meta.TvgName
+	}
+	if name == "" {
+		name = fmt.Sprintf("Source %d", index+1)
+	}
+	// 频道名位于行尾，换行会破坏播放列表结构
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(name)
+}
+
+// escapeAttr 去掉属性值中会破坏 M3U 语法的字符
+func escapeAttr(value string) string {
+	return strings.NewReplacer(`"`, "'", "\r", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,261 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"m3u8_selector/core"
+)
+
+// Record 是 core.M3U8Source 的扁平化表示，用于 JSON/CSV 导出
+type Record struct {
+	Rank           int     `json:"rank"` // 可用直播源的排名，不可用时为 0
+	URL            string  `json:"url"`
+	Valid          bool    `json:"valid"`
+	Error          string  `json:"error"`
+	LatencyMs      int64   `json:"latency_ms"`
+	DownloadSpeed  float64 `json:"download_speed_kBps"` // KB/s，与 bitrate_kbps (kbit/s) 的单位不同
+	DataSize       int64   `json:"data_size"`
+	DownloadTimeMs int64   `json:"download_time_ms"`
+	Measurement    string  `json:"measurement,omitempty"` // measured、estimated 或 unmeasured
+	Channel        string  `json:"channel,omitempty"`
+	Group          string  `json:"group,omitempty"`
+	TvgID          string  `json:"tvg_id,omitempty"`
+	TvgName        string  `json:"tvg_name,omitempty"`
+	TvgLogo        string  `json:"tvg_logo,omitempty"`
+	Resolution     string  `json:"resolution,omitempty"`
+	CheckedAt      string  `json:"checked_at,omitempty"` // RFC 3339
+	Location       string  `json:"location,omitempty"`
+	Source         string  `json:"source,omitempty"`
+	Live           string  `json:"live,omitempty"`      // 直播状态: live、frozen、looping、static
+	Container      string  `json:"container,omitempty"` // 通用 HTTP 流或 HLS/DASH 媒体片段的容器格式: mpegts、flv、mp4、fmp4、adts
+
+	Codecs           []string `json:"codecs,omitempty"` // 从媒体数据检测到的编码
+	ContinuityErrors int      `json:"cc_errors,omitempty"`
+	Width            int      `json:"width,omitempty"` // 从视频 SPS 检测到的分辨率
+	Height           int      `json:"height,omitempty"`
+	FrameRate        float64  `json:"frame_rate,omitempty"`
+	Bitrate          float64  `json:"bitrate_kbps,omitempty"` // 由片段大小和 #EXTINF 时长计算的媒体码率
+	Headroom         float64  `json:"headroom,omitempty"`     // 下载吞吐量 / 媒体码率
+	Packets          int64    `json:"packets,omitempty"`      // UDP/RTP 组播的接收统计
+	LostPackets      int64    `json:"lost_packets,omitempty"`
+	JitterMs         float64  `json:"jitter_ms,omitempty"`
+	ProxyClients     int      `json:"proxy_clients,omitempty"` // udpxy 状态页中的客户端数量，查询失败时为 -1
+
+	SelectedVariant string          `json:"selected_variant,omitempty"`
+	Variants        []VariantRecord `json:"variants,omitempty"`
+}
+
+// VariantRecord 是 core.VariantResult 的导出表示
+type VariantRecord struct {
+	URL           string  `json:"url"`
+	Bandwidth     int64   `json:"bandwidth"`
+	Resolution    string  `json:"resolution,omitempty"`
+	Codecs        string  `json:"codecs,omitempty"`
+	Valid         bool    `json:"valid"`
+	Error         string  `json:"error"`
+	LatencyMs     int64   `json:"latency_ms"`
+	DownloadSpeed float64 `json:"download_speed_kBps"` // KB/s
+}
+
+// csvHeader 与 Record.csvRow 的列顺序一致
+var csvHeader = []string{
+	"rank", "url", "valid", "error", "latency_ms", "download_speed_kBps", "data_size", "download_time_ms", "measurement",
+	"channel", "group", "tvg_id", "tvg_name", "tvg_logo", "resolution", "checked_at", "location", "source",
+	"live", "container", "codecs", "cc_errors", "width", "height", "frame_rate", "bitrate_kbps", "headroom",
+	"packets", "lost_packets", "jitter_ms", "proxy_clients", "selected_variant", "variant_count",
+}
+
+// NewRecord 将测试结果转换为导出记录
+func NewRecord(source core.M3U8Source, rank int) Record {
+	r := Record{
+		Rank:           rank,
+		URL:            source.URL,
+		Valid:          source.Valid,
+		Error:          source.Error,
+		LatencyMs:      source.Latency.Milliseconds(),
+		DownloadSpeed:  source.DownloadSpeed,
+		DataSize:       source.DataSize,
+		DownloadTimeMs: source.DownloadTime.Milliseconds(),
+		Measurement:    string(source.Measurement),
+		Channel:        source.Meta.Channel,
+		Group:          source.Meta.Group,
+		TvgID:          source.Meta.TvgID,
+		TvgName:        source.Meta.TvgName,
+		TvgLogo:        source.Meta.TvgLogo,
+		Resolution:     source.Meta.Resolution,
+		Location:       source.Meta.Location,
+		Source:         source.Meta.Source,
+		Live:           string(source.Live),
+		Container:      string(source.Container),
+
+		Codecs:           source.Codecs,
+		ContinuityErrors: source.ContinuityErrors,
+		Width:            source.Width,
+		Height:           source.Height,
+		FrameRate:        source.FrameRate,
+		Bitrate:          source.Bitrate,
+		Headroom:         source.Headroom,
+		Packets:          source.Packets,
+		LostPackets:      source.LostPackets,
+		JitterMs:         float64(source.Jitter.Microseconds()) / 1000,
+		ProxyClients:     source.ProxyClients,
+
+		SelectedVariant: source.SelectedVariant,
+	}
+	if !source.Meta.CheckedAt.IsZero() {
+		r.CheckedAt = source.Meta.CheckedAt.Format(time.RFC3339)
+	}
+	for _, v := range source.Variants {
+		r.Variants = append(r.Variants, VariantRecord{
+			URL:           v.URL,
+			Bandwidth:     v.Bandwidth,
+			Resolution:    v.Resolution,
+			Codecs:        v.Codecs,
+			Valid:         v.Valid,
+			Error:         v.Error,
+			LatencyMs:     v.Latency.Milliseconds(),
+			DownloadSpeed: v.DownloadSpeed,
+		})
+	}
+	return r
+}
+
+// ToSource 将导出记录还原为测试结果
+func (r Record) ToSource() core.M3U8Source {
+	source := core.M3U8Source{
+		URL:           r.URL,
+		Latency:       time.Duration(r.LatencyMs) * time.Millisecond,
+		DownloadSpeed: r.DownloadSpeed,
+		Valid:         r.Valid,
+		Error:         r.Error,
+		DataSize:      r.DataSize,
+		DownloadTime:  time.Duration(r.DownloadTimeMs) * time.Millisecond,
+		Measurement:   core.Measurement(r.Measurement),
+		Meta: core.StreamMeta{
+			Channel:    r.Channel,
+			Group:      r.Group,
+			TvgID:      r.TvgID,
+			TvgName:    r.TvgName,
+			TvgLogo:    r.TvgLogo,
+			Resolution: r.Resolution,
+			Location:   r.Location,
+			Source:     r.Source,
+		},
+		Live:             core.LiveState(r.Live),
+		Container:        core.Container(r.Container),
+		Codecs:           r.Codecs,
+		ContinuityErrors: r.ContinuityErrors,
+		Width:            r.Width,
+		Height:           r.Height,
+		FrameRate:        r.FrameRate,
+		Bitrate:          r.Bitrate,
+		Headroom:         r.Headroom,
+		Packets:          r.Packets,
+		LostPackets:      r.LostPackets,
+		Jitter:           time.Duration(r.JitterMs * float64(time.Millisecond)),
+		ProxyClients:     r.ProxyClients,
+		SelectedVariant:  r.SelectedVariant,
+	}
+	if r.CheckedAt != "" {
+		source.Meta.CheckedAt, _ = time.Parse(time.RFC3339, r.CheckedAt)
+	}
+	for _, v := range r.Variants {
+		source.Variants = append(source.Variants, core.VariantResult{
+			URL:           v.URL,
+			Bandwidth:     v.Bandwidth,
+			Resolution:    v.Resolution,
+			Codecs:        v.Codecs,
+			Valid:         v.Valid,
+			Error:         v.Error,
+			Latency:       time.Duration(v.LatencyMs) * time.Millisecond,
+			DownloadSpeed: v.DownloadSpeed,
+		})
+	}
+	return source
+}
+
+func (r Record) csvRow() []string {
+	return []string{
+		strconv.Itoa(r.Rank), r.URL, strconv.FormatBool(r.Valid), r.Error,
+		strconv.FormatInt(r.LatencyMs, 10), strconv.FormatFloat(r.DownloadSpeed, 'f', 2, 64),
+		strconv.FormatInt(r.DataSize, 10), strconv.FormatInt(r.DownloadTimeMs, 10), r.Measurement,
+		r.Channel, r.Group, r.TvgID, r.TvgName, r.TvgLogo, r.Resolution, r.CheckedAt, r.Location, r.Source,
+		r.Live, r.Container, strings.Join(r.Codecs, "/"), strconv.Itoa(r.ContinuityErrors),
+		strconv.Itoa(r.Width), strconv.Itoa(r.Height), strconv.FormatFloat(r.FrameRate, 'f', 2, 64),
+		strconv.FormatFloat(r.Bitrate, 'f', 0, 64), strconv.FormatFloat(r.Headroom, 'f', 2, 64),
+		strconv.FormatInt(r.Packets, 10), strconv.FormatInt(r.LostPackets, 10), strconv.FormatFloat(r.JitterMs, 'f', 3, 64),
+		strconv.Itoa(r.ProxyClients),
+		r.SelectedVariant, strconv.Itoa(len(r.Variants)),
+	}
+}
+
+// buildRecords 先按排名输出可用直播源，再附上不可用的直播源
+func buildRecords(ranked, failed []core.M3U8Source) []Record {
+	records := make([]Record, 0, len(ranked)+len(failed))
+	for i, source := range ranked {
+		records = append(records, NewRecord(source, i+1))
+	}
+	for _, source := range failed {
+		records = append(records, NewRecord(source, 0))
+	}
+	return records
+}
+
+// WriteJSON 将所有测试结果写成 JSON 数组，不可用的直播源附带失败原因
+func WriteJSON(w io.Writer, ranked, failed []core.M3U8Source) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(buildRecords(ranked, failed))
+}
+
+// ReadJSON 读取 WriteJSON 导出的结果，按记录中的排名还原可用直播源的顺序，
+// 并返回不可用的直播源
+func ReadJSON(r io.Reader) (ranked, failed []core.M3U8Source, err error) {
+	var records []Record
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, nil, fmt.Errorf("解析 JSON 结果失败: %v", err)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		// 排名为 0 的记录（失败的直播源）排在最后
+		ri, rj := records[i].Rank, records[j].Rank
+		if ri == 0 || rj == 0 {
+			return ri != 0 && rj == 0
+		}
+		return ri < rj
+	})
+	for _, record := range records {
+		if record.Valid {
+			ranked = append(ranked, record.ToSource())
+		} else {
+			failed = append(failed, record.ToSource())
+		}
+	}
+	return ranked, failed, nil
+}
+
+// WriteCSV 将所有测试结果写成带表头的 CSV，不可用的直播源附带失败原因
+func WriteCSV(w io.Writer, ranked, failed []core.M3U8Source) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range buildRecords(ranked, failed) {
+		if err := cw.Write(r.csvRow()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("写入 CSV 失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"m3u8_selector/core"
+)
+
+// defaultGroup 是没有分组信息的直播源在文本列表中使用的分组名
+const defaultGroup = "未分组"
+
+// WriteTXT 将直播源写成 DIYP/TVBox 使用的 "频道名,URL" 文本列表。
+// 分组按首次出现的顺序输出为 "分组,#genre#"，组内保持给定的排名顺序。
+func WriteTXT(w io.Writer, sources []core.M3U8Source) error {
+	groups := []string{}
+	byGroup := make(map[string][]int)
+	for i, source := range sources {
+		group := source.Meta.Group
+		if group == "" {
+			group = defaultGroup
+		}
+		if _, ok := byGroup[group]; !ok {
+			groups = append(groups, group)
+		}
+		byGroup[group] = append(byGroup[group], i)
+	}
+
+	bw := bufio.NewWriter(w)
+	for gi, group := range groups {
+		if gi > 0 {
+			fmt.Fprintln(bw)
+		}
+		fmt.Fprintf(bw, "%s,#genre#\n", txtField(group))
+		for _, i := range byGroup[group] {
+			fmt.Fprintf(bw, "%s,%s\n", txtField(channelName(sources[i], i)), sources[i].URL)
+		}
+	}
+	return bw.Flush()
+}
+
+// txtField 去掉会破坏 "名称,URL" 结构的逗号和换行
+func txtField(s string) string {
+	return strings.NewReplacer(",", " ", "，", " ", "\r", " ", "\n", " ").Replace(s)
+}
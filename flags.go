@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/exporter"
+	"m3u8_selector/parser"
+	"m3u8_selector/tester"
+)
+
+// newFlagSet 创建子命令的选项集合，usage 为参数说明，如 "[选项] <关键词>"
+func newFlagSet(name, usage, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s %s %s\n\n%s\n\n选项:\n", os.Args[0], name, usage, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags 解析子命令选项。返回的退出码为 -1 时表示继续执行
+func parseFlags(fs *flag.FlagSet, args []string) int {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	return -1
+}
+
+// usageError 打印参数错误和子命令用法
+func usageError(fs *flag.FlagSet, format string, a ...interface{}) int {
+	fmt.Fprintf(fs.Output(), format+"\n\n", a...)
+	fs.Usage()
+	return exitUsage
+}
+
+// searchOptions 是关键词搜索相关的选项
+type searchOptions struct {
+	provider string
+	maxPages int
+	workers  int
+	rate     float64
+	burst    int
+}
+
+func (o *searchOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.provider, "provider", "tonkiang", "搜索源，多个用逗号分隔，all 表示全部 (可用: "+strings.Join(parser.ProviderNames(), ", ")+")")
+	fs.IntVar(&o.maxPages, "pages", 20, "每个搜索源的最大分页数，实际页数由分页控件决定")
+	fs.IntVar(&o.workers, "workers", 4, "并发抓取搜索页面的 worker 数量")
+	fs.Float64Var(&o.rate, "rate", 1, "每个搜索站点每秒允许的请求数")
+	fs.IntVar(&o.burst, "burst", 2, "每个搜索站点允许的突发请求数")
+}
+
+// newSearchClient 创建搜索和下载播放列表用的 HTTP 客户端
+func newSearchClient(o searchOptions) *http.Client {
+	return parser.NewRateLimitedClient(30*time.Second, o.rate, o.burst)
+}
+
+// testOptions 是直播源测速相关的选项
+type testOptions struct {
+	timeout      time.Duration
+	concurrency  int
+	variants     string
+	verifyLive   bool
+	rank         string
+	minRes       string
+	unmeasured   bool
+	iface        string
+	sampleWindow time.Duration
+	udpxyStatus  bool
+	rtspPlay     bool
+}
+
+func (o *testOptions) register(fs *flag.FlagSet) {
+	defaults := tester.DefaultOptions()
+	fs.DurationVar(&o.timeout, "timeout", defaults.Timeout, "单个请求的超时时间")
+	fs.IntVar(&o.concurrency, "concurrency", defaults.Concurrency, "同时测试的直播源数量")
+	fs.StringVar(&o.variants, "variants", defaults.VariantPolicy, "HLS 主播放列表的变体和 DASH 表示的测试策略: "+strings.Join(tester.VariantPolicies, ", "))
+	fs.StringVar(&o.rank, "rank", string(core.RankByPlayback), "可用直播源的排名方式: playback（能否实时播放优先）、speed（下载速度优先）或 resolution（分辨率优先）")
+	fs.StringVar(&o.minRes, "min-resolution", "", "最低分辨率，如 720p、1920x1080、4K；分辨率未知的直播源不受限制")
+	fs.BoolVar(&o.unmeasured, "include-unmeasured", defaults.IncludeUnmeasured, "保留无法测速的直播源（如片段下载失败、UDP 无数据），排在已测速的直播源之后")
+	fs.StringVar(&o.iface, "iface", "", "加入 udp:// 和 rtp:// 组播组使用的网卡名，如 eth1，默认由系统选择")
+	fs.DurationVar(&o.sampleWindow, "sample-window", defaults.SampleWindow, "组播、udpxy 代理流、HTTP 直播流、RTSP 和 RTMP 播放的采样时长，从收到第一个包开始计时")
+	fs.DurationVar(&o.sampleWindow, "udp-window", defaults.SampleWindow, "已弃用，同 -sample-window")
+	fs.BoolVar(&o.udpxyStatus, "udpxy-status", defaults.QueryProxyStatus, "测试 udpxy 代理地址时查询代理的 /status 页面，输出客户端数量")
+	fs.BoolVar(&o.rtspPlay, "rtsp-play", defaults.RTSPPlay, "对 RTSP 直播源执行 SETUP/PLAY 测量吞吐量，关闭时只做 OPTIONS/DESCRIBE（结果为未测速）")
+	fs.BoolVar(&o.verifyLive, "verify-live", defaults.VerifyLive, "约一个目标时长后重新加载 HLS 播放列表、检查 DASH 清单的时间信息，排除停止更新或循环播放的直播源")
+}
+
+// testerOptions 返回测速参数，测速进度输出到 progress
+func (o testOptions) testerOptions(progress io.Writer) tester.Options {
+	opts := tester.DefaultOptions()
+	opts.Timeout = o.timeout
+	opts.Concurrency = o.concurrency
+	opts.VariantPolicy = o.variants
+	opts.VerifyLive = o.verifyLive
+	opts.MinHeight = core.ResolutionHeight(o.minRes)
+	opts.IncludeUnmeasured = o.unmeasured
+	opts.MulticastInterface = o.iface
+	opts.SampleWindow = o.sampleWindow
+	opts.QueryProxyStatus = o.udpxyStatus
+	opts.RTSPPlay = o.rtspPlay
+	opts.Progress = progress
+	return opts
+}
+
+func (o testOptions) rankBy() core.RankBy {
+	return core.RankBy(o.rank)
+}
+
+// validate 校验测速选项
+func (o testOptions) validate() error {
+	if o.timeout <= 0 {
+		return fmt.Errorf("-timeout 必须大于 0")
+	}
+	if o.concurrency < 1 {
+		return fmt.Errorf("-concurrency 必须大于 0")
+	}
+	if o.sampleWindow <= 0 {
+		return fmt.Errorf("-sample-window 必须大于 0")
+	}
+	if o.minRes != "" && core.ResolutionHeight(o.minRes) == 0 {
+		return fmt.Errorf("无法识别的分辨率: %s", o.minRes)
+	}
+	if !containsRankBy(core.RankModes, o.rankBy()) {
+		return fmt.Errorf("不支持的排名方式: %s", o.rank)
+	}
+	for _, policy := range tester.VariantPolicies {
+		if o.variants == policy {
+			return nil
+		}
+	}
+	return fmt.Errorf("不支持的变体测试策略: %s", o.variants)
+}
+
+func containsRankBy(modes []core.RankBy, by core.RankBy) bool {
+	for _, mode := range modes {
+		if mode == by {
+			return true
+		}
+	}
+	return false
+}
+
+// outputOptions 是结果输出相关的选项
+type outputOptions struct {
+	path   string
+	format string
+	limit  int
+
+	log io.Writer // 进度、排名等信息的输出位置，由 prepare 设置
+}
+
+func (o *outputOptions) register(fs *flag.FlagSet, defaultPath string) {
+	fs.StringVar(&o.path, "o", defaultPath, "导出测试结果的文件路径，- 表示标准输出")
+	fs.StringVar(&o.format, "format", "m3u", "导出格式: "+strings.Join(exporter.Formats, ", ")+" (json/csv 包含失败的直播源)")
+}
+
+func (o *outputOptions) registerLimit(fs *flag.FlagSet) {
+	fs.IntVar(&o.limit, "limit", 10, "显示和导出的可用直播源数量上限，0 表示不限")
+}
+
+// prepare 校验输出选项。结果写到标准输出时，进度信息改为输出到标准错误，避免混入结果
+func (o *outputOptions) prepare() error {
+	if !exporter.ValidFormat(o.format) {
+		return fmt.Errorf("不支持的导出格式: %s", o.format)
+	}
+	if o.limit < 0 {
+		return fmt.Errorf("-limit 不能为负数")
+	}
+	o.log = os.Stdout
+	if o.path == "-" {
+		o.log = os.Stderr
+	}
+	return nil
+}
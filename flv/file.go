@@ -0,0 +1,48 @@
+package flv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// fileHeaderSize 是 FLV 文件头的最小长度
+const fileHeaderSize = 9
+
+// IsFLV 判断数据是否以 FLV 文件头开头
+func IsFLV(data []byte) bool {
+	return len(data) >= 4 && bytes.HasPrefix(data, []byte("FLV")) && data[3] == 1
+}
+
+// Inspect 解析 FLV 文件头和其后的标签。HTTP-FLV 直播流可能在任意位置截断，
+// 最后一个不完整的标签会被忽略。没有任何音视频标签时返回错误。
+func Inspect(data []byte) (Info, error) {
+	if !IsFLV(data) || len(data) < fileHeaderSize {
+		return Info{}, fmt.Errorf("missing FLV header")
+	}
+	offset := int(binary.BigEndian.Uint32(data[5:9]))
+	if offset < fileHeaderSize || offset > len(data) {
+		return Info{}, fmt.Errorf("invalid FLV header size %d", offset)
+	}
+	data = data[min(offset+prevTagSizeSize, len(data)):]
+
+	var p Prober
+	for len(data) >= TagHeaderSize {
+		header, _ := ParseTagHeader(data)
+		if header.Type != TagAudio && header.Type != TagVideo && header.Type != TagScript {
+			return Info{}, fmt.Errorf("invalid FLV tag type %d", header.Type)
+		}
+		end := TagHeaderSize + header.DataSize
+		if end > len(data) {
+			break
+		}
+		p.Add(header.Type, header.Timestamp, data[TagHeaderSize:end])
+		data = data[min(end+prevTagSizeSize, len(data)):]
+	}
+
+	info := p.Info()
+	if info.VideoTags == 0 && info.AudioTags == 0 {
+		return info, fmt.Errorf("no audio or video tags")
+	}
+	return info, nil
+}
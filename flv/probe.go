@@ -0,0 +1,183 @@
+package flv
+
+import (
+	"time"
+
+	"m3u8_selector/amf"
+	"m3u8_selector/video"
+)
+
+// Info 是从 FLV 标签中检测到的媒体信息
+type Info struct {
+	VideoCodec string
+	AudioCodec string
+	Width      int
+	Height     int
+	FrameRate  float64
+	VideoTags  int
+	AudioTags  int
+	Duration   time.Duration // 音视频标签时间戳的跨度
+}
+
+// Codecs 返回检测到的编码，视频在前
+func (info Info) Codecs() []string {
+	var codecs []string
+	if info.VideoCodec != "" {
+		codecs = append(codecs, info.VideoCodec)
+	}
+	if info.AudioCodec != "" {
+		codecs = append(codecs, info.AudioCodec)
+	}
+	return codecs
+}
+
+// Prober 逐个接收标签，汇总编码、分辨率和帧率。
+// 分辨率和帧率优先取自序列头中的 SPS，其次是 onMetaData，帧率最后按视频标签的时间戳估算。
+type Prober struct {
+	info    Info
+	picture video.Info // 从序列头 SPS 解析
+	meta    metadata
+
+	started         bool
+	firstTS, lastTS uint32
+	videoFrames     int
+	firstVideoTS    uint32
+	lastVideoTS     uint32
+}
+
+// metadata 是 onMetaData 中声明的媒体参数
+type metadata struct {
+	videoCodec string
+	audioCodec string
+	width      int
+	height     int
+	frameRate  float64
+}
+
+// Add 处理一个标签。data 是标签负载，不含标签头
+func (p *Prober) Add(tagType byte, timestamp uint32, data []byte) {
+	switch tagType {
+	case TagAudio:
+		p.info.AudioTags++
+		if codec := audioCodec(data); codec != "" {
+			p.info.AudioCodec = codec
+		}
+		p.track(timestamp)
+	case TagVideo:
+		tag, ok := parseVideoTag(data)
+		if !ok {
+			return
+		}
+		p.info.VideoTags++
+		p.info.VideoCodec = tag.codec
+		p.track(timestamp)
+		if tag.sequenceHeader {
+			p.parseConfig(tag)
+			return
+		}
+		if p.videoFrames == 0 {
+			p.firstVideoTS = timestamp
+		}
+		p.videoFrames++
+		p.lastVideoTS = timestamp
+	case TagScript:
+		p.parseScript(data)
+	}
+}
+
+// track 记录音视频标签的时间戳范围
+func (p *Prober) track(timestamp uint32) {
+	if !p.started {
+		p.firstTS = timestamp
+		p.started = true
+	}
+	p.lastTS = timestamp
+}
+
+// parseConfig 从序列头的解码器配置记录中解析 SPS
+func (p *Prober) parseConfig(tag videoTag) {
+	var picture video.Info
+	var err error
+	switch tag.codec {
+	case "H.264":
+		picture, err = video.ParseAVCConfig(tag.config)
+	case "H.265":
+		picture, err = video.ParseHEVCConfig(tag.config)
+	default:
+		return
+	}
+	if err == nil {
+		p.picture = picture
+	}
+}
+
+// parseScript 解析 onMetaData 脚本数据，兼容推流端常见的 @setDataFrame 前缀
+func (p *Prober) parseScript(data []byte) {
+	values, _ := amf.Decode(data)
+	if len(values) > 0 && values[0] == "@setDataFrame" {
+		values = values[1:]
+	}
+	if len(values) < 2 || values[0] != "onMetaData" {
+		return
+	}
+	obj, ok := values[1].(amf.Object)
+	if !ok {
+		return
+	}
+
+	if v, ok := obj.Number("width"); ok {
+		p.meta.width = int(v)
+	}
+	if v, ok := obj.Number("height"); ok {
+		p.meta.height = int(v)
+	}
+	if v, ok := obj.Number("framerate"); ok {
+		p.meta.frameRate = v
+	} else if v, ok := obj.Number("videoframerate"); ok {
+		p.meta.frameRate = v
+	}
+	p.meta.videoCodec = metadataCodec(obj["videocodecid"], videoCodecs)
+	p.meta.audioCodec = metadataCodec(obj["audiocodecid"], audioFormats)
+}
+
+// metadataCodec 将 onMetaData 中的编码 ID 转换为编码名称，ID 可以是数字或 FourCC 字符串
+func metadataCodec(id interface{}, names map[byte]string) string {
+	switch id := id.(type) {
+	case float64:
+		if id >= 0 && id < 256 {
+			return codecName(names, byte(id))
+		}
+	case string:
+		if id != "" {
+			return codecName(fourCCs, id)
+		}
+	}
+	return ""
+}
+
+// Info 返回目前为止检测到的媒体信息
+func (p *Prober) Info() Info {
+	info := p.info
+	if info.VideoCodec == "" {
+		info.VideoCodec = p.meta.videoCodec
+	}
+	if info.AudioCodec == "" {
+		info.AudioCodec = p.meta.audioCodec
+	}
+	if p.started {
+		info.Duration = time.Duration(p.lastTS-p.firstTS) * time.Millisecond
+	}
+
+	info.Width, info.Height = p.picture.Width, p.picture.Height
+	if info.Width == 0 {
+		info.Width, info.Height = p.meta.width, p.meta.height
+	}
+	info.FrameRate = p.picture.FrameRate
+	if info.FrameRate == 0 {
+		info.FrameRate = p.meta.frameRate
+	}
+	if info.FrameRate == 0 && p.videoFrames > 1 && p.lastVideoTS > p.firstVideoTS {
+		info.FrameRate = float64(p.videoFrames-1) * 1000 / float64(p.lastVideoTS-p.firstVideoTS)
+	}
+	return info
+}
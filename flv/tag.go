@@ -0,0 +1,169 @@
+// Package flv 解析 FLV 标签，识别音视频编码、分辨率和帧率。
+// RTMP 的音视频和数据消息与 FLV 文件中的标签使用相同的负载格式。
+package flv
+
+import (
+	"fmt"
+)
+
+// 标签类型，与 RTMP 消息类型相同
+const (
+	TagAudio  = 8
+	TagVideo  = 9
+	TagScript = 18
+)
+
+// TagHeaderSize 是 FLV 标签头的长度
+const TagHeaderSize = 11
+
+// TagHeader 是 FLV 标签头
+type TagHeader struct {
+	Type      byte
+	DataSize  int
+	Timestamp uint32 // 毫秒，包含扩展的高 8 位
+	StreamID  uint32
+}
+
+// Tag 是一个完整的 FLV 标签
+type Tag struct {
+	TagHeader
+	Data []byte
+}
+
+// ParseTagHeader 解析 11 字节的标签头
+func ParseTagHeader(b []byte) (TagHeader, error) {
+	if len(b) < TagHeaderSize {
+		return TagHeader{}, fmt.Errorf("FLV tag header truncated")
+	}
+	return TagHeader{
+		Type:      b[0] & 0x1F, // 高位是加密标志
+		DataSize:  int(b[1])<<16 | int(b[2])<<8 | int(b[3]),
+		Timestamp: uint32(b[7])<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6]),
+		StreamID:  uint32(b[8])<<16 | uint32(b[9])<<8 | uint32(b[10]),
+	}, nil
+}
+
+// audioFormats 是音频标签 SoundFormat 对应的编码
+var audioFormats = map[byte]string{
+	0:  "PCM",
+	1:  "ADPCM",
+	2:  "MP3",
+	3:  "PCM",
+	4:  "Nellymoser",
+	5:  "Nellymoser",
+	6:  "Nellymoser",
+	7:  "G.711 A-law",
+	8:  "G.711 μ-law",
+	10: "AAC",
+	11: "Speex",
+	14: "MP3",
+}
+
+// videoCodecs 是视频标签 CodecID 对应的编码。12 不在 FLV 规范中，是国内 CDN 普遍使用的 HEVC 扩展
+var videoCodecs = map[byte]string{
+	2:  "Sorenson H.263",
+	3:  "Screen Video",
+	4:  "VP6",
+	5:  "VP6",
+	6:  "Screen Video 2",
+	7:  "H.264",
+	12: "H.265",
+}
+
+// fourCCs 是 Enhanced RTMP 扩展头中 FourCC 对应的编码
+var fourCCs = map[string]string{
+	"avc1": "H.264",
+	"hvc1": "H.265",
+	"av01": "AV1",
+	"vp09": "VP9",
+	"mp4a": "AAC",
+	"Opus": "Opus",
+	"ac-3": "AC-3",
+	"ec-3": "E-AC-3",
+	"fLaC": "FLAC",
+	".mp3": "MP3",
+}
+
+// audioFormatExHeader 表示音频标签使用 Enhanced RTMP 扩展头
+const audioFormatExHeader = 9
+
+// videoExHeader 是视频标签第一个字节中表示 Enhanced RTMP 扩展头的标志位
+const videoExHeader = 0x80
+
+// 视频包类型：AVC/HEVC 的 AVCPacketType 和 Enhanced RTMP 的 PacketType 中，0 都表示序列头
+const packetSequenceStart = 0
+
+// audioCodec 返回音频标签的编码名称
+func audioCodec(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	format := data[0] >> 4
+	if format == audioFormatExHeader && len(data) >= 5 {
+		return codecName(fourCCs, string(data[1:5]))
+	}
+	return codecName(audioFormats, format)
+}
+
+// videoTag 是解析后的视频标签头
+type videoTag struct {
+	codec          string
+	sequenceHeader bool
+	config         []byte // 序列头中的解码器配置记录
+}
+
+// parseVideoTag 解析视频标签的编码和序列头
+func parseVideoTag(data []byte) (videoTag, bool) {
+	if len(data) == 0 {
+		return videoTag{}, false
+	}
+	if data[0]&videoExHeader != 0 {
+		if len(data) < 5 {
+			return videoTag{}, false
+		}
+		tag := videoTag{codec: codecName(fourCCs, string(data[1:5]))}
+		if data[0]&0x0F == packetSequenceStart {
+			tag.sequenceHeader = true
+			tag.config = data[5:]
+		}
+		return tag, true
+	}
+
+	codecID := data[0] & 0x0F
+	tag := videoTag{codec: codecName(videoCodecs, codecID)}
+	if (codecID == 7 || codecID == 12) && len(data) >= 5 && data[1] == packetSequenceStart {
+		tag.sequenceHeader = true
+		tag.config = data[5:] // 跳过 AVCPacketType 和 3 字节的 CompositionTime
+	}
+	return tag, true
+}
+
+// codecName 查找编码名称，未知的编码用原始值表示，如 "unknown(13)"
+func codecName[K comparable](names map[K]string, key K) string {
+	if name, ok := names[key]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%v)", key)
+}
+
+// prevTagSizeSize 是 FLV 文件和 RTMP 聚合消息中每个标签后的 PreviousTagSize 字段长度
+const prevTagSizeSize = 4
+
+// SplitTags 拆分连续的 FLV 标签（每个标签后跟 4 字节的 PreviousTagSize），
+// 用于 RTMP 聚合消息。返回的 Data 引用 data 中的内容。
+func SplitTags(data []byte) ([]Tag, error) {
+	var tags []Tag
+	for len(data) > 0 {
+		header, err := ParseTagHeader(data)
+		if err != nil {
+			return tags, err
+		}
+		end := TagHeaderSize + header.DataSize
+		if end > len(data) {
+			return tags, fmt.Errorf("FLV tag data truncated")
+		}
+		tags = append(tags, Tag{TagHeader: header, Data: data[TagHeaderSize:end]})
+		data = data[min(end+prevTagSizeSize, len(data)):]
+	}
+	return tags, nil
+}
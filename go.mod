@@ -1,3 +1,8 @@
 module m3u8_selector
 
 go 1.23.1
+
+require (
+	golang.org/x/net v0.40.0
+	golang.org/x/text v0.25.0
+)
@@ -0,0 +1,348 @@
+package hls
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse 解析 HLS 播放列表，根据内容返回 *MasterPlaylist 或 *MediaPlaylist。
+// baseURL 是播放列表自身的地址，用于解析片段和变体的相对地址。
+func Parse(content, baseURL string) (Playlist, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid playlist URL: %v", err)
+	}
+
+	p := &parser{base: base, media: &MediaPlaylist{}, master: &MasterPlaylist{}}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lineNo := 0
+	sawHeader := false
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if !sawHeader {
+			line = strings.TrimPrefix(line, "\uFEFF")
+			if line == "" {
+				continue
+			}
+			if line != "#EXTM3U" {
+				return nil, fmt.Errorf("line %d: missing #EXTM3U header", lineNo)
+			}
+			sawHeader = true
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if err := p.parseLine(line); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !sawHeader {
+		return nil, fmt.Errorf("empty playlist")
+	}
+
+	if len(p.master.Variants) > 0 {
+		if len(p.media.Segments) > 0 {
+			return nil, fmt.Errorf("playlist mixes #EXT-X-STREAM-INF variants with media segments")
+		}
+		p.master.Version = p.media.Version
+		p.master.IndependentSegments = p.media.IndependentSegments
+		return p.master, nil
+	}
+	return p.media, nil
+}
+
+// parser 保存逐行解析时的状态
+type parser struct {
+	base   *url.URL
+	media  *MediaPlaylist
+	master *MasterPlaylist
+
+	// 作用于下一个片段的标签
+	segment          Segment
+	nextSeq          uint64
+	seqStarted       bool
+	lastRange        map[string]int64 // 每个地址上一个字节范围的结束位置，用于省略偏移的 BYTERANGE
+	key              *Key
+	mapSection       *Map
+	pendingStreamInf *Variant
+}
+
+func (p *parser) parseLine(line string) error {
+	if !strings.HasPrefix(line, "#") {
+		return p.parseURI(line)
+	}
+	if !strings.HasPrefix(line, "#EXT") {
+		return nil // 注释
+	}
+
+	tag, value := line, ""
+	if colon := strings.Index(line, ":"); colon >= 0 {
+		tag, value = line[:colon], line[colon+1:]
+	}
+
+	var err error
+	switch tag {
+	case "#EXT-X-VERSION":
+		p.media.Version, err = strconv.Atoi(value)
+	case "#EXT-X-TARGETDURATION":
+		var seconds float64
+		seconds, err = strconv.ParseFloat(value, 64)
+		p.media.TargetDuration = secondsToDuration(seconds)
+	case "#EXT-X-MEDIA-SEQUENCE":
+		p.media.MediaSequence, err = strconv.ParseUint(value, 10, 64)
+	case "#EXT-X-DISCONTINUITY-SEQUENCE":
+		p.media.DiscontinuitySequence, err = strconv.ParseUint(value, 10, 64)
+	case "#EXT-X-PLAYLIST-TYPE":
+		p.media.PlaylistType = strings.ToUpper(value)
+	case "#EXT-X-ENDLIST":
+		p.media.EndList = true
+	case "#EXT-X-I-FRAMES-ONLY":
+		p.media.IFramesOnly = true
+	case "#EXT-X-INDEPENDENT-SEGMENTS":
+		p.media.IndependentSegments = true
+	case "#EXTINF":
+		err = p.parseExtinf(value)
+	case "#EXT-X-BYTERANGE":
+		p.segment.ByteRange, err = parseByteRange(value)
+	case "#EXT-X-DISCONTINUITY":
+		p.segment.Discontinuity = true
+	case "#EXT-X-PROGRAM-DATE-TIME":
+		p.segment.ProgramDateTime = parseDateTime(value)
+	case "#EXT-X-KEY":
+		err = p.parseKey(value)
+	case "#EXT-X-MAP":
+		err = p.parseMap(value)
+	case "#EXT-X-STREAM-INF":
+		p.pendingStreamInf, err = p.parseStreamInf(value)
+	case "#EXT-X-MEDIA":
+		p.parseMedia(value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", tag, err)
+	}
+	return nil
+}
+
+func (p *parser) parseExtinf(value string) error {
+	durationPart, title := value, ""
+	if comma := strings.Index(value, ","); comma >= 0 {
+		durationPart, title = value[:comma], strings.TrimSpace(value[comma+1:])
+	}
+	// 有些播放列表在时长后带有属性（如 "-1 tvg-id=..."），只取第一个字段
+	if fields := strings.Fields(durationPart); len(fields) > 0 {
+		durationPart = fields[0]
+	}
+	seconds, err := strconv.ParseFloat(durationPart, 64)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", durationPart)
+	}
+	p.segment.Duration = secondsToDuration(seconds)
+	p.segment.Title = title
+	return nil
+}
+
+func (p *parser) parseKey(value string) error {
+	attrs := parseAttributeList(value)
+	method := attrs["METHOD"]
+	if method == "" {
+		return fmt.Errorf("missing METHOD")
+	}
+	if method == "NONE" {
+		p.key = nil
+		return nil
+	}
+	p.key = &Key{
+		Method:            method,
+		URI:               p.resolve(attrs["URI"]),
+		IV:                attrs["IV"],
+		KeyFormat:         attrs["KEYFORMAT"],
+		KeyFormatVersions: attrs["KEYFORMATVERSIONS"],
+	}
+	return nil
+}
+
+func (p *parser) parseMap(value string) error {
+	attrs := parseAttributeList(value)
+	if attrs["URI"] == "" {
+		return fmt.Errorf("missing URI")
+	}
+	m := &Map{URI: p.resolve(attrs["URI"])}
+	if r, ok := attrs["BYTERANGE"]; ok {
+		br, err := parseByteRange(r)
+		if err != nil {
+			return err
+		}
+		if br.Offset < 0 {
+			br.Offset = 0
+		}
+		m.ByteRange = br
+	}
+	p.mapSection = m
+	return nil
+}
+
+func (p *parser) parseStreamInf(value string) (*Variant, error) {
+	attrs := parseAttributeList(value)
+	bandwidth, err := strconv.ParseInt(attrs["BANDWIDTH"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BANDWIDTH %q", attrs["BANDWIDTH"])
+	}
+	v := &Variant{
+		Bandwidth:  bandwidth,
+		Codecs:     attrs["CODECS"],
+		Resolution: attrs["RESOLUTION"],
+		Audio:      attrs["AUDIO"],
+		Video:      attrs["VIDEO"],
+		Subtitles:  attrs["SUBTITLES"],
+	}
+	if avg, ok := attrs["AVERAGE-BANDWIDTH"]; ok {
+		v.AverageBandwidth, _ = strconv.ParseInt(avg, 10, 64)
+	}
+	if fr, ok := attrs["FRAME-RATE"]; ok {
+		v.FrameRate, _ = strconv.ParseFloat(fr, 64)
+	}
+	if v.Resolution != "" {
+		if x := strings.IndexAny(v.Resolution, "xX"); x > 0 {
+			v.Width, _ = strconv.Atoi(v.Resolution[:x])
+			v.Height, _ = strconv.Atoi(v.Resolution[x+1:])
+		}
+	}
+	return v, nil
+}
+
+func (p *parser) parseMedia(value string) {
+	attrs := parseAttributeList(value)
+	p.master.Renditions = append(p.master.Renditions, Rendition{
+		Type:       attrs["TYPE"],
+		GroupID:    attrs["GROUP-ID"],
+		Name:       attrs["NAME"],
+		Language:   attrs["LANGUAGE"],
+		URI:        p.resolve(attrs["URI"]),
+		Default:    attrs["DEFAULT"] == "YES",
+		AutoSelect: attrs["AUTOSELECT"] == "YES",
+	})
+}
+
+func (p *parser) parseURI(line string) error {
+	uri := p.resolve(line)
+
+	if p.pendingStreamInf != nil {
+		p.pendingStreamInf.URI = uri
+		p.master.Variants = append(p.master.Variants, *p.pendingStreamInf)
+		p.pendingStreamInf = nil
+		return nil
+	}
+
+	if !p.seqStarted {
+		p.nextSeq = p.media.MediaSequence
+		p.seqStarted = true
+	}
+
+	seg := p.segment
+	seg.URI = uri
+	seg.Sequence = p.nextSeq
+	seg.Key = p.key
+	seg.Map = p.mapSection
+	if seg.ByteRange != nil {
+		if p.lastRange == nil {
+			p.lastRange = make(map[string]int64)
+		}
+		if seg.ByteRange.Offset < 0 {
+			seg.ByteRange.Offset = p.lastRange[uri]
+		}
+		p.lastRange[uri] = seg.ByteRange.Offset + seg.ByteRange.Length
+	}
+	p.media.Segments = append(p.media.Segments, seg)
+
+	p.nextSeq++
+	p.segment = Segment{}
+	return nil
+}
+
+// resolve 将相对地址按播放列表地址解析为绝对地址
+func (p *parser) resolve(ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return p.base.ResolveReference(u).String()
+}
+
+// parseByteRange 解析 "长度[@偏移]"，省略偏移时 Offset 为 -1
+func parseByteRange(value string) (*ByteRange, error) {
+	value = strings.Trim(value, `"`)
+	lengthPart, offsetPart := value, ""
+	if at := strings.Index(value, "@"); at >= 0 {
+		lengthPart, offsetPart = value[:at], value[at+1:]
+	}
+	length, err := strconv.ParseInt(lengthPart, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid byte range %q", value)
+	}
+	br := &ByteRange{Length: length, Offset: -1}
+	if offsetPart != "" {
+		if br.Offset, err = strconv.ParseInt(offsetPart, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid byte range %q", value)
+		}
+	}
+	return br, nil
+}
+
+// parseAttributeList 解析 KEY=VALUE,KEY="VALUE" 形式的属性列表，引号内可以包含逗号
+func parseAttributeList(s string) map[string]string {
+	attrs := make(map[string]string)
+	for len(s) > 0 {
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToUpper(strings.TrimSpace(s[:eq]))
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else if comma := strings.Index(s, ","); comma >= 0 {
+			value, s = s[:comma], s[comma:]
+		} else {
+			value, s = s, ""
+		}
+		attrs[key] = strings.TrimSpace(value)
+
+		s = strings.TrimLeft(s, ", ")
+	}
+	return attrs
+}
+
+// parseDateTime 解析 #EXT-X-PROGRAM-DATE-TIME，格式不规范时返回零值而不是报错
+func parseDateTime(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,203 @@
+package hls
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBaseURL = "http://example.com/live/stream/index.m3u8?token=abc"
+
+func parseMedia(t *testing.T, content string) *MediaPlaylist {
+	t.Helper()
+	pl, err := Parse(content, testBaseURL)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	media, ok := pl.(*MediaPlaylist)
+	if !ok {
+		t.Fatalf("Parse returned %T, want *MediaPlaylist", pl)
+	}
+	return media
+}
+
+func TestParseMaster(t *testing.T) {
+	content := `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="English",LANGUAGE="en",DEFAULT=YES,AUTOSELECT=YES,URI="audio/en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=2500000,AVERAGE-BANDWIDTH=2000000,CODECS="avc1.64001f,mp4a.40.2",RESOLUTION=1280x720,FRAME-RATE=29.970,AUDIO="aac"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360
+/other/360p.m3u8
+`
+	pl, err := Parse(content, testBaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, ok := pl.(*MasterPlaylist)
+	if !ok {
+		t.Fatalf("Parse returned %T, want *MasterPlaylist", pl)
+	}
+	if master.Version != 6 || !master.IndependentSegments {
+		t.Errorf("Version, IndependentSegments = %d, %v", master.Version, master.IndependentSegments)
+	}
+	want := []Variant{
+		{
+			URI: "http://example.com/live/stream/720p.m3u8", Bandwidth: 2500000, AverageBandwidth: 2000000,
+			Codecs: "avc1.64001f,mp4a.40.2", Resolution: "1280x720", Width: 1280, Height: 720, FrameRate: 29.97, Audio: "aac",
+		},
+		{URI: "http://example.com/other/360p.m3u8", Bandwidth: 800000, Resolution: "640x360", Width: 640, Height: 360},
+	}
+	if !reflect.DeepEqual(master.Variants, want) {
+		t.Errorf("Variants = %+v, want %+v", master.Variants, want)
+	}
+	if len(master.Renditions) != 1 || master.Renditions[0].URI != "http://example.com/live/stream/audio/en.m3u8" || !master.Renditions[0].Default {
+		t.Errorf("Renditions = %+v", master.Renditions)
+	}
+}
+
+func TestParseMediaPlaylist(t *testing.T) {
+	media := parseMedia(t, `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:100
+#EXTINF:6.006,
+seg100.ts
+#EXTINF:5.5,title
+seg101.ts
+#EXT-X-ENDLIST
+`)
+	if media.TargetDuration != 6*time.Second || media.MediaSequence != 100 || !media.EndList {
+		t.Errorf("TargetDuration, MediaSequence, EndList = %v, %d, %v", media.TargetDuration, media.MediaSequence, media.EndList)
+	}
+	if len(media.Segments) != 2 {
+		t.Fatalf("got %d segments, want 2", len(media.Segments))
+	}
+	if s := media.Segments[1]; s.Sequence != 101 || s.Duration != 5500*time.Millisecond || s.Title != "title" {
+		t.Errorf("second segment = %+v", s)
+	}
+	if media.IsLive() {
+		t.Error("playlist with #EXT-X-ENDLIST reported as live")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"missing header", "#EXTINF:1,\na.ts\n", "missing #EXTM3U"},
+		{"empty", "\n\n", "empty playlist"},
+		{"bad duration", "#EXTM3U\n#EXTINF:abc,\na.ts\n", "invalid duration"},
+		{"mixed variants and segments", "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000\nv.m3u8\n#EXTINF:1,\na.ts\n", "mixes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.content, testBaseURL)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseByteRange(t *testing.T) {
+	media := parseMedia(t, `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXTINF:4,
+#EXT-X-BYTERANGE:1000@500
+main.ts
+#EXTINF:4,
+#EXT-X-BYTERANGE:2000
+main.ts
+#EXTINF:4,
+#EXT-X-BYTERANGE:300
+other.ts
+#EXTINF:4,
+#EXT-X-BYTERANGE:400
+main.ts
+`)
+	// 省略偏移时从同一地址上一个字节范围的结束位置开始，不同地址分别计算
+	want := []ByteRange{{1000, 500}, {2000, 1500}, {300, 0}, {400, 3500}}
+	for i, w := range want {
+		if r := media.Segments[i].ByteRange; r == nil || *r != w {
+			t.Errorf("segment %d ByteRange = %v, want %+v", i, r, w)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	media := parseMedia(t, `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-KEY:METHOD=AES-128,URI="keys/k1.bin",IV=0x0123456789ABCDEF0123456789ABCDEF
+#EXTINF:4,
+a.ts
+#EXTINF:4,
+b.ts
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:4,
+c.ts
+`)
+	want := &Key{Method: "AES-128", URI: "http://example.com/live/stream/keys/k1.bin", IV: "0x0123456789ABCDEF0123456789ABCDEF"}
+	for i := 0; i < 2; i++ {
+		if !reflect.DeepEqual(media.Segments[i].Key, want) {
+			t.Errorf("segment %d Key = %+v, want %+v", i, media.Segments[i].Key, want)
+		}
+	}
+	if key := media.Segments[2].Key; key != nil {
+		t.Errorf("Key after METHOD=NONE = %+v, want nil", key)
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	media := parseMedia(t, `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-MAP:URI="init.mp4",BYTERANGE="720"
+#EXTINF:4,
+a.m4s
+#EXTINF:4,
+b.m4s
+#EXT-X-MAP:URI="init2.mp4"
+#EXTINF:4,
+c.m4s
+`)
+	first := &Map{URI: "http://example.com/live/stream/init.mp4", ByteRange: &ByteRange{Length: 720, Offset: 0}}
+	for i := 0; i < 2; i++ {
+		if !reflect.DeepEqual(media.Segments[i].Map, first) {
+			t.Errorf("segment %d Map = %+v, want %+v", i, media.Segments[i].Map, first)
+		}
+	}
+	if m := media.Segments[2].Map; m == nil || m.URI != "http://example.com/live/stream/init2.mp4" || m.ByteRange != nil {
+		t.Errorf("segment 2 Map = %+v", m)
+	}
+}
+
+func TestParseResolvesURIs(t *testing.T) {
+	media := parseMedia(t, `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXTINF:4,
+seg1.ts
+#EXTINF:4,
+../seg2.ts?x=1
+#EXTINF:4,
+/abs/seg3.ts
+#EXTINF:4,
+//cdn.example.net/seg4.ts
+#EXTINF:4,
+https://other.example.org/seg5.ts
+`)
+	want := []string{
+		"http://example.com/live/stream/seg1.ts",
+		"http://example.com/live/seg2.ts?x=1",
+		"http://example.com/abs/seg3.ts",
+		"http://cdn.example.net/seg4.ts",
+		"https://other.example.org/seg5.ts",
+	}
+	for i, w := range want {
+		if got := media.Segments[i].URI; got != w {
+			t.Errorf("segment %d URI = %q, want %q", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package hls 解析 HLS (RFC 8216) 主播放列表和媒体播放列表
+package hls
+
+import (
+	"time"
+)
+
+// Playlist 是 *MasterPlaylist 或 *MediaPlaylist
+type Playlist interface {
+	isPlaylist()
+}
+
+// MediaPlaylist 是包含媒体片段的播放列表
+type MediaPlaylist struct {
+	Version               int
+	TargetDuration        time.Duration
+	MediaSequence         uint64
+	DiscontinuitySequence uint64
+	PlaylistType          string // "EVENT"、"VOD"，未声明时为空
+	EndList               bool
+	IFramesOnly           bool
+	IndependentSegments   bool
+	Segments              []Segment
+}
+
+// Segment 是媒体播放列表中的一个片段
+type Segment struct {
+	URI             string // 已按播放列表地址解析的绝对地址
+	Duration        time.Duration
+	Title           string
+	Sequence        uint64 // 媒体序列号
+	Discontinuity   bool   // 片段前有 #EXT-X-DISCONTINUITY
+	ByteRange       *ByteRange
+	Key             *Key // 当前生效的 #EXT-X-KEY，未加密时为 nil
+	Map             *Map // 当前生效的 #EXT-X-MAP，没有初始化片段时为 nil
+	ProgramDateTime time.Time
+}
+
+// ByteRange 表示 #EXT-X-BYTERANGE 指定的子范围
+type ByteRange struct {
+	Length int64
+	Offset int64
+}
+
+// Key 表示 #EXT-X-KEY 加密信息
+type Key struct {
+	Method            string // NONE、AES-128、SAMPLE-AES
+	URI               string
+	IV                string
+	KeyFormat         string
+	KeyFormatVersions string
+}
+
+// Map 表示 #EXT-X-MAP 初始化片段
+type Map struct {
+	URI       string
+	ByteRange *ByteRange
+}
+
+// MasterPlaylist 是列出多个码率变体的主播放列表
+type MasterPlaylist struct {
+	Version             int
+	IndependentSegments bool
+	Variants            []Variant
+	Renditions          []Rendition
+}
+
+// Variant 是 #EXT-X-STREAM-INF 描述的一个变体流
+type Variant struct {
+	URI              string // 已解析的变体媒体播放列表地址
+	Bandwidth        int64  // bit/s
+	AverageBandwidth int64  // bit/s，未声明时为 0
+	Codecs           string
+	Resolution       string // 原始的 "宽x高" 字符串
+	Width            int
+	Height           int
+	FrameRate        float64
+	Audio            string
+	Video            string
+	Subtitles        string
+}
+
+// Rendition 是 #EXT-X-MEDIA 描述的备选媒体（音轨、字幕等）
+type Rendition struct {
+	Type       string
+	GroupID    string
+	Name       string
+	Language   string
+	URI        string
+	Default    bool
+	AutoSelect bool
+}
+
+func (*MediaPlaylist) isPlaylist()  {}
+func (*MasterPlaylist) isPlaylist() {}
+
+// Duration 返回所有片段时长之和
+func (p *MediaPlaylist) Duration() time.Duration {
+	var total time.Duration
+	for _, s := range p.Segments {
+		total += s.Duration
+	}
+	return total
+}
+
+// IsLive 判断播放列表是否为直播：没有 #EXT-X-ENDLIST 且不是 VOD
+func (p *MediaPlaylist) IsLive() bool {
+	return !p.EndList && p.PlaylistType != "VOD"
+}
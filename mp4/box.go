@@ -0,0 +1,99 @@
+// Package mp4 解析 ISO BMFF (MP4/fMP4/CMAF) 的盒子结构
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrTruncated 表示最后一个盒子在数据结束前没有读完。直播流在任意位置截断时是正常情况
+var ErrTruncated = errors.New("box truncated")
+
+// Box 是一个盒子，Data 是去掉盒子头之后的内容
+type Box struct {
+	Type string
+	Size int64 // 包含盒子头的声明大小
+	Data []byte
+}
+
+// ReadBox 读取 data 开头的一个盒子，返回盒子和盒子之后的剩余数据。
+// 盒子的声明大小超过 data 时返回 ErrTruncated，此时 Box 的 Data 是已有的部分。
+func ReadBox(data []byte) (Box, []byte, error) {
+	if len(data) < 8 {
+		return Box{}, nil, ErrTruncated
+	}
+	size := int64(binary.BigEndian.Uint32(data))
+	box := Box{Type: string(data[4:8])}
+	header := int64(8)
+	switch size {
+	case 0: // 盒子延续到数据末尾
+		size = int64(len(data))
+	case 1: // 64 位 largesize
+		if len(data) < 16 {
+			return Box{}, nil, ErrTruncated
+		}
+		size = int64(binary.BigEndian.Uint64(data[8:]))
+		header = 16
+	}
+	if size < header {
+		return Box{}, nil, fmt.Errorf("invalid size %d for box %q", size, box.Type)
+	}
+	box.Size = size
+	if size > int64(len(data)) {
+		box.Data = data[header:]
+		return box, nil, ErrTruncated
+	}
+	box.Data = data[header:size]
+	return box, data[size:], nil
+}
+
+// ReadBoxes 读取 data 中顺序排列的所有完整盒子。最后一个盒子不完整时返回已读取的盒子和 ErrTruncated
+func ReadBoxes(data []byte) ([]Box, error) {
+	var boxes []Box
+	for len(data) > 0 {
+		box, rest, err := ReadBox(data)
+		if err != nil {
+			return boxes, err
+		}
+		boxes = append(boxes, box)
+		data = rest
+	}
+	return boxes, nil
+}
+
+// FindBox 按路径查找第一个匹配的子盒子，如 FindBox(moov.Data, "trak", "mdia", "hdlr")
+func FindBox(data []byte, path ...string) (Box, bool) {
+	for i, typ := range path {
+		boxes, _ := ReadBoxes(data)
+		found := false
+		for _, box := range boxes {
+			if box.Type == typ {
+				if i == len(path)-1 {
+					return box, true
+				}
+				data, found = box.Data, true
+				break
+			}
+		}
+		if !found {
+			return Box{}, false
+		}
+	}
+	return Box{}, false
+}
+
+// topLevelTypes 是可能出现在 MP4/fMP4 文件或片段开头的顶层盒子
+var topLevelTypes = map[string]bool{
+	"ftyp": true, "styp": true, "moov": true, "moof": true, "mdat": true,
+	"sidx": true, "emsg": true, "prft": true, "free": true, "skip": true, "uuid": true,
+}
+
+// IsTopLevelBox 判断 data 是否以常见的顶层盒子开头，用于识别容器格式
+func IsTopLevelBox(data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+	size := binary.BigEndian.Uint32(data)
+	return (size == 1 || size >= 8) && topLevelTypes[string(data[4:8])]
+}
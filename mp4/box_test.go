@@ -0,0 +1,125 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// box 构造一个 32 位大小的盒子
+func box(typ string, parts ...[]byte) []byte {
+	var data []byte
+	for _, p := range parts {
+		data = append(data, p...)
+	}
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	return append(append(b, typ...), data...)
+}
+
+// fullBox 构造带 version 和 flags 的盒子
+func fullBox(typ string, version byte, flags uint32, parts ...[]byte) []byte {
+	header := []byte{version, byte(flags >> 16), byte(flags >> 8), byte(flags)}
+	return box(typ, append([][]byte{header}, parts...)...)
+}
+
+func u32(values ...uint32) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+func TestReadBox(t *testing.T) {
+	payload := []byte("payload")
+	large := append(u32(1), "mdat"...)
+	large = binary.BigEndian.AppendUint64(large, uint64(16+len(payload)))
+	large = append(large, payload...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		typ      string
+		size     int64
+		body     string
+		rest     int
+		wantErr  error
+		errMatch string
+	}{
+		{name: "32-bit size", data: append(box("free", payload), 1, 2), typ: "free", size: 15, body: "payload", rest: 2},
+		{name: "64-bit largesize", data: append(large, 9), typ: "mdat", size: 23, body: "payload", rest: 1},
+		{name: "size 0 extends to end", data: append(append(u32(0), "mdat"...), payload...), typ: "mdat", size: 15, body: "payload"},
+		{name: "truncated body", data: box("moof", payload)[:12], typ: "moof", size: 15, body: "payl", wantErr: ErrTruncated},
+		{name: "truncated largesize", data: large[:12], wantErr: ErrTruncated},
+		{name: "truncated header", data: []byte{0, 0, 0}, wantErr: ErrTruncated},
+		{name: "size smaller than header", data: append(u32(4), "free"...), errMatch: "invalid size 4"},
+		{name: "largesize smaller than header", data: append(append(u32(1), "free"...), 0, 0, 0, 0, 0, 0, 0, 8), errMatch: "invalid size 8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, rest, err := ReadBox(tt.data)
+			if tt.errMatch != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMatch) {
+					t.Fatalf("err = %v, want %q", err, tt.errMatch)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if b.Type != tt.typ || b.Size != tt.size || string(b.Data) != tt.body || len(rest) != tt.rest {
+				t.Errorf("got %q size %d data %q rest %d, want %q size %d data %q rest %d",
+					b.Type, b.Size, b.Data, len(rest), tt.typ, tt.size, tt.body, tt.rest)
+			}
+		})
+	}
+}
+
+func TestFindBox(t *testing.T) {
+	data := box("moov", box("mvhd", u32(0)), box("trak", box("tkhd", u32(1)), box("mdia", box("hdlr", u32(2)))))
+	moov, _, _ := ReadBox(data)
+	if b, ok := FindBox(moov.Data, "trak", "mdia", "hdlr"); !ok || binary.BigEndian.Uint32(b.Data) != 2 {
+		t.Errorf("FindBox(trak/mdia/hdlr) = %v, %v", b, ok)
+	}
+	if _, ok := FindBox(moov.Data, "trak", "minf"); ok {
+		t.Error("FindBox found a box that does not exist")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	styp := box("styp", []byte("msdh"), u32(0))
+	moof := box("moof", box("mfhd", u32(0, 1)), box("traf", fullBox("tfhd", 0, 0, u32(1))))
+	mdat := box("mdat", make([]byte, 100))
+
+	info, err := Inspect(concat(styp, moof, mdat, moof, mdat[:50]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MajorBrand != "msdh" || info.Fragments != 2 || !info.Fragmented || info.MediaData != 142 || len(info.TrackFragments) != 2 {
+		t.Errorf("got %+v", info)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"moof without mdat", concat(styp, moof, moof, mdat), "moof 1 has no mdat"},
+		{"no moov or moof", concat(styp, mdat), "no moov or moof"},
+		{"not MP4", []byte("<html><body></body></html>"), "does not start with an MP4 box"},
+	}
+	for _, tt := range tests {
+		if _, err := Inspect(tt.data); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
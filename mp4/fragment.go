@@ -0,0 +1,170 @@
+package mp4
+
+import "encoding/binary"
+
+// TrackFragment 是 moof 中一个 traf 的概要
+type TrackFragment struct {
+	TrackID       uint32
+	DecodeTime    uint64 // tfdt 的 baseMediaDecodeTime，以轨道时间刻度为单位
+	HasDecodeTime bool
+	Samples       int
+	// Duration 是样本时长之和。trun 和 tfhd 都没有给出样本时长时为 0，需要使用初始化片段 trex 中的默认值
+	Duration uint64
+}
+
+// tfhd 的 tf_flags
+const (
+	tfhdBaseDataOffset         = 0x01
+	tfhdSampleDescriptionIndex = 0x02
+	tfhdDefaultSampleDuration  = 0x08
+)
+
+// trun 的 tr_flags
+const (
+	trunDataOffset       = 0x001
+	trunFirstSampleFlags = 0x004
+	trunSampleDuration   = 0x100
+	trunSampleSize       = 0x200
+	trunSampleFlags      = 0x400
+	trunCompositionTime  = 0x800
+)
+
+// parseMoof 解析 moof 盒子内容中的各个 traf
+func parseMoof(data []byte) []TrackFragment {
+	boxes, _ := ReadBoxes(data)
+	var frags []TrackFragment
+	for _, box := range boxes {
+		if box.Type == "traf" {
+			frags = append(frags, parseTraf(box.Data))
+		}
+	}
+	return frags
+}
+
+// parseTraf 解析 traf 中的 tfhd、tfdt 和 trun
+func parseTraf(data []byte) TrackFragment {
+	var f TrackFragment
+	var defaultDuration uint32
+	boxes, _ := ReadBoxes(data)
+	for _, box := range boxes {
+		b := box.Data
+		if len(b) < 8 {
+			continue
+		}
+		flags := binary.BigEndian.Uint32(b) & 0xFFFFFF
+		switch box.Type {
+		case "tfhd":
+			f.TrackID = binary.BigEndian.Uint32(b[4:])
+			pos := 8
+			if flags&tfhdBaseDataOffset != 0 {
+				pos += 8
+			}
+			if flags&tfhdSampleDescriptionIndex != 0 {
+				pos += 4
+			}
+			if flags&tfhdDefaultSampleDuration != 0 && pos+4 <= len(b) {
+				defaultDuration = binary.BigEndian.Uint32(b[pos:])
+			}
+		case "tfdt":
+			f.HasDecodeTime = true
+			if b[0] == 1 {
+				if len(b) >= 12 {
+					f.DecodeTime = binary.BigEndian.Uint64(b[4:])
+				}
+			} else {
+				f.DecodeTime = uint64(binary.BigEndian.Uint32(b[4:]))
+			}
+		case "trun":
+			count := int(binary.BigEndian.Uint32(b[4:]))
+			f.Samples += count
+			pos := 8
+			if flags&trunDataOffset != 0 {
+				pos += 4
+			}
+			if flags&trunFirstSampleFlags != 0 {
+				pos += 4
+			}
+			if flags&trunSampleDuration == 0 {
+				f.Duration += uint64(count) * uint64(defaultDuration)
+				continue
+			}
+			// 每个样本依次是时长、大小、标志和合成时间偏移，只读取时长
+			stride := 0
+			for _, flag := range []uint32{trunSampleDuration, trunSampleSize, trunSampleFlags, trunCompositionTime} {
+				if flags&flag != 0 {
+					stride += 4
+				}
+			}
+			for i := 0; i < count && pos+4 <= len(b); i++ {
+				f.Duration += uint64(binary.BigEndian.Uint32(b[pos:]))
+				pos += stride
+			}
+		}
+	}
+	return f
+}
+
+// SampleDuration 返回轨道片段的总时长，片段中没有样本时长时使用初始化片段 trex 中的默认值
+func (init *Init) SampleDuration(f TrackFragment) uint64 {
+	if f.Duration > 0 {
+		return f.Duration
+	}
+	if t := init.track(f.TrackID); t != nil {
+		return uint64(f.Samples) * uint64(t.DefaultSampleDuration)
+	}
+	return 0
+}
+
+// FrameRate 按视频轨道片段的样本数和时长估算帧率，用于 SPS 中没有时序信息的情况。无法估算时返回 0
+func (init *Init) FrameRate(frags []TrackFragment) float64 {
+	t := init.VideoTrack()
+	if t == nil || t.Timescale == 0 {
+		return 0
+	}
+	var samples int
+	var duration uint64
+	for _, f := range frags {
+		if f.TrackID == t.ID {
+			samples += f.Samples
+			duration += init.SampleDuration(f)
+		}
+	}
+	if samples == 0 || duration == 0 {
+		return 0
+	}
+	return float64(samples) * float64(t.Timescale) / float64(duration)
+}
+
+// Timeline 检查相邻媒体片段中各轨道的 baseMediaDecodeTime 是否连续：
+// 每个轨道片段的解码时间应等于同一轨道上一个片段的解码时间加上其样本时长之和
+type Timeline struct {
+	init *Init
+	next map[uint32]uint64
+}
+
+// NewTimeline 创建按 init 中轨道默认样本时长计算的时间线
+func NewTimeline(init *Init) *Timeline {
+	return &Timeline{init: init, next: make(map[uint32]uint64)}
+}
+
+// Add 依次加入一个媒体片段中的轨道片段，返回解码时间不连续的次数
+func (t *Timeline) Add(frags []TrackFragment) int {
+	gaps := 0
+	for _, f := range frags {
+		duration := t.init.SampleDuration(f)
+		if !f.HasDecodeTime || duration == 0 {
+			delete(t.next, f.TrackID)
+			continue
+		}
+		if want, ok := t.next[f.TrackID]; ok && want != f.DecodeTime {
+			gaps++
+		}
+		t.next[f.TrackID] = f.DecodeTime + duration
+	}
+	return gaps
+}
+
+// Reset 清除已记录的解码时间，用于片段不相邻（下载失败或 #EXT-X-DISCONTINUITY）的情况
+func (t *Timeline) Reset() {
+	clear(t.next)
+}
@@ -0,0 +1,152 @@
+package mp4
+
+import (
+	"reflect"
+	"testing"
+)
+
+// traf 构造轨道片段盒子并返回其内容
+func traf(boxes ...[]byte) []byte {
+	b, _, _ := ReadBox(box("traf", boxes...))
+	return b.Data
+}
+
+func TestParseTraf(t *testing.T) {
+	tfdt0 := fullBox("tfdt", 0, 0, u32(90000))
+	tfdt1 := fullBox("tfdt", 1, 0, u32(1, 0)) // 1<<32
+	tests := []struct {
+		name string
+		traf []byte
+		want TrackFragment
+	}{
+		{
+			"default duration after base offset and description index",
+			traf(fullBox("tfhd", 0, tfhdBaseDataOffset|tfhdSampleDescriptionIndex|tfhdDefaultSampleDuration, u32(1, 0, 0x1000, 1, 3000)),
+				tfdt0,
+				fullBox("trun", 0, trunDataOffset|trunSampleSize, u32(3, 100), u32(10, 20, 30))),
+			TrackFragment{TrackID: 1, DecodeTime: 90000, HasDecodeTime: true, Samples: 3, Duration: 9000},
+		},
+		{
+			"per-sample durations with all fields",
+			traf(fullBox("tfhd", 0, tfhdDefaultSampleDuration, u32(2, 5000)),
+				tfdt1,
+				fullBox("trun", 0, trunDataOffset|trunFirstSampleFlags|trunSampleDuration|trunSampleSize|trunSampleFlags|trunCompositionTime,
+					u32(3, 100, 0x02000000),
+					u32(1000, 10, 0, 0), u32(2000, 20, 0, 0), u32(3000, 30, 0, 0))),
+			TrackFragment{TrackID: 2, DecodeTime: 1 << 32, HasDecodeTime: true, Samples: 3, Duration: 6000},
+		},
+		{
+			"durations and sizes without data offset",
+			traf(fullBox("tfhd", 0, 0, u32(3)),
+				fullBox("trun", 0, trunSampleDuration|trunSampleSize, u32(2), u32(1024, 300, 1024, 310))),
+			TrackFragment{TrackID: 3, Samples: 2, Duration: 2048},
+		},
+		{
+			"two truns",
+			traf(fullBox("tfhd", 0, 0, u32(4)),
+				fullBox("trun", 0, trunSampleDuration, u32(2), u32(40, 40)),
+				fullBox("trun", 0, trunSampleDuration|trunCompositionTime, u32(1), u32(20, 0))),
+			TrackFragment{TrackID: 4, Samples: 3, Duration: 100},
+		},
+		{
+			"no sample durations",
+			traf(fullBox("tfhd", 0, 0, u32(5)), fullBox("trun", 0, trunSampleSize, u32(2), u32(10, 20))),
+			TrackFragment{TrackID: 5, Samples: 2},
+		},
+		{
+			"trun shorter than sample count",
+			traf(fullBox("tfhd", 0, 0, u32(6)), fullBox("trun", 0, trunSampleDuration, u32(100), u32(40, 40))),
+			TrackFragment{TrackID: 6, Samples: 100, Duration: 80},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTraf(tt.traf); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// timelineInit 是一个视频轨道 (90kHz，trex 默认样本时长 3000) 和一个音频轨道 (48kHz) 的初始化片段
+func timelineInit() *Init {
+	return &Init{Tracks: []Track{
+		{ID: 1, Handler: "vide", Timescale: 90000, DefaultSampleDuration: 3000},
+		{ID: 2, Handler: "soun", Timescale: 48000},
+	}}
+}
+
+func TestTimeline(t *testing.T) {
+	init := timelineInit()
+	// 视频片段没有样本时长，按 trex 的默认值计算：10 个样本共 30000
+	video := func(decode uint64) TrackFragment {
+		return TrackFragment{TrackID: 1, DecodeTime: decode, HasDecodeTime: true, Samples: 10}
+	}
+	audio := func(decode uint64) TrackFragment {
+		return TrackFragment{TrackID: 2, DecodeTime: decode, HasDecodeTime: true, Samples: 15, Duration: 15 * 1024}
+	}
+
+	tl := NewTimeline(init)
+	steps := []struct {
+		name  string
+		frags []TrackFragment
+		gaps  int
+	}{
+		{"first segment", []TrackFragment{video(0), audio(0)}, 0},
+		{"contiguous", []TrackFragment{video(30000), audio(15360)}, 0},
+		{"video tfdt jumps", []TrackFragment{video(70000), audio(30720)}, 1},
+		{"continues from the jump", []TrackFragment{video(100000), audio(46080)}, 0},
+		{"both tracks jump", []TrackFragment{video(0), audio(0)}, 2},
+		{"fragment without tfdt", []TrackFragment{{TrackID: 1, Samples: 10}}, 0},
+		{"video restarts after missing tfdt", []TrackFragment{video(500000), audio(15360)}, 0},
+	}
+	for _, step := range steps {
+		if gaps := tl.Add(step.frags); gaps != step.gaps {
+			t.Errorf("%s: gaps = %d, want %d", step.name, gaps, step.gaps)
+		}
+	}
+
+	tl.Reset()
+	if gaps := tl.Add([]TrackFragment{video(900000), audio(0)}); gaps != 0 {
+		t.Errorf("after Reset: gaps = %d, want 0", gaps)
+	}
+}
+
+func TestSampleDurationAndFrameRate(t *testing.T) {
+	init := timelineInit()
+	tests := []struct {
+		name string
+		frag TrackFragment
+		want uint64
+	}{
+		{"from trun", TrackFragment{TrackID: 1, Samples: 10, Duration: 36036}, 36036},
+		{"from trex", TrackFragment{TrackID: 1, Samples: 10}, 30000},
+		{"no default", TrackFragment{TrackID: 2, Samples: 10}, 0},
+		{"unknown track", TrackFragment{TrackID: 9, Samples: 10}, 0},
+	}
+	for _, tt := range tests {
+		if got := init.SampleDuration(tt.frag); got != tt.want {
+			t.Errorf("%s: SampleDuration = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	frags := []TrackFragment{{TrackID: 1, Samples: 10}, {TrackID: 2, Samples: 15, Duration: 15360}, {TrackID: 1, Samples: 5}}
+	if got := init.FrameRate(frags); got != 30 {
+		t.Errorf("FrameRate = %v, want 30", got)
+	}
+	audioOnly := &Init{Tracks: init.Tracks[1:]}
+	if got := audioOnly.FrameRate(frags); got != 0 {
+		t.Errorf("FrameRate without video track = %v, want 0", got)
+	}
+}
+
+func TestParseMoof(t *testing.T) {
+	moof := box("moof", box("mfhd", u32(0, 7)),
+		box("traf", fullBox("tfhd", 0, 0, u32(1)), fullBox("trun", 0, 0, u32(4))),
+		box("traf", fullBox("tfhd", 0, 0, u32(2)), fullBox("trun", 0, 0, u32(6))))
+	b, _, _ := ReadBox(moof)
+	want := []TrackFragment{{TrackID: 1, Samples: 4}, {TrackID: 2, Samples: 6}}
+	if got := parseMoof(b.Data); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,263 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"m3u8_selector/video"
+)
+
+// Track 是初始化片段中的一个轨道
+type Track struct {
+	ID          uint32
+	Handler     string // hdlr 的 handler_type，如 vide、soun
+	SampleEntry string // stsd 中第一个样本描述的类型，加密轨道为 frma 中的原始格式，如 avc1、mp4a
+	Codec       string // 如 H.264、H.265、AAC
+	Encrypted   bool   // 样本描述为 encv/enca
+	Timescale   uint32 // mdhd 的时间刻度
+
+	// DefaultSampleDuration 是 mvex/trex 中的默认样本时长，trun 和 tfhd 都没有样本时长时使用
+	DefaultSampleDuration uint32
+
+	// 视频轨道的画面参数，优先来自 avcC/hvcC 中的 SPS，其次是样本描述中的宽高
+	Width     int
+	Height    int
+	FrameRate float64
+}
+
+// Init 是初始化片段 (ftyp + moov) 的解析结果
+type Init struct {
+	Tracks []Track
+}
+
+// sampleEntryCodecs 是样本描述类型对应的编码
+var sampleEntryCodecs = map[string]string{
+	"avc1": "H.264",
+	"avc3": "H.264",
+	"hvc1": "H.265",
+	"hev1": "H.265",
+	"av01": "AV1",
+	"vp09": "VP9",
+	"mp4a": "AAC",
+	"ac-3": "AC-3",
+	"ec-3": "E-AC-3",
+	"Opus": "Opus",
+	"fLaC": "FLAC",
+	".mp3": "MP3",
+	"wvtt": "WebVTT",
+	"stpp": "TTML",
+}
+
+// 样本描述中子盒子之前的固定字段长度 (ISO/IEC 14496-12 12.1.3, 12.2.3)
+const (
+	visualSampleEntrySize = 78
+	audioSampleEntrySize  = 28
+)
+
+// ParseInit 解析初始化片段中 moov 下的各个轨道
+func ParseInit(data []byte) (*Init, error) {
+	boxes, err := ReadBoxes(data)
+	if err != nil {
+		return nil, fmt.Errorf("init segment: %v", err)
+	}
+	var moov *Box
+	for i := range boxes {
+		if boxes[i].Type == "moov" {
+			moov = &boxes[i]
+			break
+		}
+	}
+	if moov == nil {
+		return nil, fmt.Errorf("init segment has no moov box")
+	}
+
+	children, _ := ReadBoxes(moov.Data)
+	defaults := make(map[uint32]uint32)
+	if mvex, ok := FindBox(moov.Data, "mvex"); ok {
+		trexes, _ := ReadBoxes(mvex.Data)
+		for _, trex := range trexes {
+			// version/flags, track_ID, default_sample_description_index, default_sample_duration
+			if trex.Type == "trex" && len(trex.Data) >= 16 {
+				defaults[binary.BigEndian.Uint32(trex.Data[4:])] = binary.BigEndian.Uint32(trex.Data[12:])
+			}
+		}
+	}
+
+	init := &Init{}
+	for _, box := range children {
+		if box.Type != "trak" {
+			continue
+		}
+		track := parseTrak(box.Data)
+		track.DefaultSampleDuration = defaults[track.ID]
+		init.Tracks = append(init.Tracks, track)
+	}
+	if len(init.Tracks) == 0 {
+		return nil, fmt.Errorf("init segment has no tracks")
+	}
+	return init, nil
+}
+
+// Codecs 返回各轨道的编码名称，按轨道顺序去重
+func (init *Init) Codecs() []string {
+	seen := make(map[string]bool)
+	codecs := []string{}
+	for _, t := range init.Tracks {
+		if t.Codec == "" || seen[t.Codec] {
+			continue
+		}
+		seen[t.Codec] = true
+		codecs = append(codecs, t.Codec)
+	}
+	return codecs
+}
+
+// VideoTrack 返回第一个视频轨道，没有时返回 nil
+func (init *Init) VideoTrack() *Track {
+	for i := range init.Tracks {
+		if init.Tracks[i].Handler == "vide" {
+			return &init.Tracks[i]
+		}
+	}
+	return nil
+}
+
+// track 按 ID 查找轨道，没有时返回 nil
+func (init *Init) track(id uint32) *Track {
+	for i := range init.Tracks {
+		if init.Tracks[i].ID == id {
+			return &init.Tracks[i]
+		}
+	}
+	return nil
+}
+
+// parseTrak 解析 trak 盒子中的轨道 ID、类型、时间刻度和第一个样本描述
+func parseTrak(data []byte) Track {
+	var t Track
+	if tkhd, ok := FindBox(data, "tkhd"); ok && len(tkhd.Data) >= 24 {
+		// version 1 的创建和修改时间是 64 位
+		offset := 12
+		if tkhd.Data[0] == 1 {
+			offset = 20
+		}
+		t.ID = binary.BigEndian.Uint32(tkhd.Data[offset:])
+	}
+	if mdhd, ok := FindBox(data, "mdia", "mdhd"); ok && len(mdhd.Data) >= 24 {
+		offset := 12
+		if mdhd.Data[0] == 1 {
+			offset = 20
+		}
+		t.Timescale = binary.BigEndian.Uint32(mdhd.Data[offset:])
+	}
+	if hdlr, ok := FindBox(data, "mdia", "hdlr"); ok && len(hdlr.Data) >= 12 {
+		t.Handler = string(hdlr.Data[8:12])
+	}
+	if stsd, ok := FindBox(data, "mdia", "minf", "stbl", "stsd"); ok && len(stsd.Data) >= 8 {
+		// version/flags 和 entry_count 之后是样本描述盒子
+		if entry, _, err := ReadBox(stsd.Data[8:]); err == nil {
+			parseSampleEntry(&t, entry)
+		}
+	}
+	return t
+}
+
+// parseSampleEntry 从样本描述中识别编码，视频轨道还解析画面参数
+func parseSampleEntry(t *Track, entry Box) {
+	t.SampleEntry = entry.Type
+	var children []byte
+	switch t.Handler {
+	case "vide":
+		if len(entry.Data) < visualSampleEntrySize {
+			return
+		}
+		t.Width = int(binary.BigEndian.Uint16(entry.Data[24:]))
+		t.Height = int(binary.BigEndian.Uint16(entry.Data[26:]))
+		children = entry.Data[visualSampleEntrySize:]
+	case "soun":
+		if len(entry.Data) < audioSampleEntrySize {
+			return
+		}
+		children = entry.Data[audioSampleEntrySize:]
+	}
+
+	if entry.Type == "encv" || entry.Type == "enca" {
+		t.Encrypted = true
+		if frma, ok := FindBox(children, "sinf", "frma"); ok && len(frma.Data) >= 4 {
+			t.SampleEntry = string(frma.Data[:4])
+		}
+	}
+	t.Codec = sampleEntryCodecs[t.SampleEntry]
+	if t.Codec == "" {
+		t.Codec = fmt.Sprintf("unknown(%s)", t.SampleEntry)
+	}
+
+	var picture video.Info
+	var err error
+	switch t.Codec {
+	case "H.264":
+		if avcC, ok := FindBox(children, "avcC"); ok {
+			picture, err = video.ParseAVCConfig(avcC.Data)
+		}
+	case "H.265":
+		if hvcC, ok := FindBox(children, "hvcC"); ok {
+			picture, err = video.ParseHEVCConfig(hvcC.Data)
+		}
+	case "AAC":
+		if esds, ok := FindBox(children, "esds"); ok && isMP3Descriptor(esds.Data) {
+			t.Codec = "MP3"
+		}
+	}
+	if err == nil && picture.Width > 0 {
+		t.Width, t.Height, t.FrameRate = picture.Width, picture.Height, picture.FrameRate
+	}
+}
+
+// isMP3Descriptor 判断 esds 的 objectTypeIndication 是否为 MPEG 音频 (0x69、0x6B)。mp4a 样本描述也用于 MP3
+func isMP3Descriptor(esds []byte) bool {
+	// version/flags 之后是 ES_Descriptor (tag 0x03)
+	tag, body, ok := readDescriptor(esds[min(4, len(esds)):])
+	if !ok || tag != 0x03 || len(body) < 3 {
+		return false
+	}
+	flags := body[2]
+	pos := 3
+	if flags&0x80 != 0 { // streamDependenceFlag
+		pos += 2
+	}
+	if flags&0x40 != 0 && pos < len(body) { // URL_Flag
+		pos += 1 + int(body[pos])
+	}
+	if flags&0x20 != 0 { // OCRstreamFlag
+		pos += 2
+	}
+	if pos >= len(body) {
+		return false
+	}
+	tag, config, ok := readDescriptor(body[pos:])
+	if !ok || tag != 0x04 || len(config) < 1 {
+		return false
+	}
+	return config[0] == 0x69 || config[0] == 0x6B
+}
+
+// readDescriptor 读取一个 MPEG-4 描述符，长度最多用 4 个字节表示，每字节的最高位表示后面还有长度字节
+func readDescriptor(data []byte) (tag byte, body []byte, ok bool) {
+	if len(data) < 2 {
+		return 0, nil, false
+	}
+	tag = data[0]
+	size, pos := 0, 1
+	for i := 0; i < 4 && pos < len(data); i++ {
+		b := data[pos]
+		pos++
+		size = size<<7 | int(b&0x7F)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if pos+size > len(data) {
+		return tag, data[pos:], true
+	}
+	return tag, data[pos : pos+size], true
+}
@@ -0,0 +1,140 @@
+package mp4
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+// x264SPS 是 1920x1080、30fps 的 H.264 High profile SPS
+const x264SPS = "6764002AACD940780227E5C044000003000400000300F03C60C658"
+
+// trak 构造一个轨道。version 为 1 时 tkhd 和 mdhd 使用 64 位的时间字段
+func trak(version byte, id, timescale uint32, handler string, entry []byte) []byte {
+	times := make([]byte, 8) // creation_time、modification_time
+	if version == 1 {
+		times = make([]byte, 16)
+	}
+	tkhd := fullBox("tkhd", version, 7, times, u32(id, 0), make([]byte, 60))
+	mdhd := fullBox("mdhd", version, 0, times, u32(timescale), make([]byte, 8))
+	hdlr := fullBox("hdlr", 0, 0, u32(0), []byte(handler), make([]byte, 12), []byte("name\x00"))
+	stsd := fullBox("stsd", 0, 0, u32(1), entry)
+	return box("trak", tkhd, box("mdia", mdhd, hdlr, box("minf", box("stbl", stsd))))
+}
+
+// visualEntry 构造视频样本描述，宽高写在固定字段中
+func visualEntry(typ string, width, height uint16, children ...[]byte) []byte {
+	fixed := make([]byte, visualSampleEntrySize)
+	fixed[24], fixed[25] = byte(width>>8), byte(width)
+	fixed[26], fixed[27] = byte(height>>8), byte(height)
+	return box(typ, append([][]byte{fixed}, children...)...)
+}
+
+// avcC 构造只含一个 SPS 的 AVCDecoderConfigurationRecord
+func avcC(sps []byte) []byte {
+	record := []byte{1, sps[1], sps[2], sps[3], 0xFF, 0xE1, byte(len(sps) >> 8), byte(len(sps))}
+	return box("avcC", append(append(record, sps...), 0))
+}
+
+// esds 构造 ES_Descriptor，objectTypeIndication 为 oti
+func esds(oti byte) []byte {
+	config := []byte{0x04, 13, oti, 0x15, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	es := append([]byte{0x03, 0x80, 0x80, 0x80, byte(3 + len(config)), 0, 1, 0}, config...)
+	return fullBox("esds", 0, 0, es)
+}
+
+func TestParseInit(t *testing.T) {
+	sps, _ := hex.DecodeString(x264SPS)
+	video := trak(0, 1, 90000, "vide", visualEntry("avc1", 1280, 720, avcC(sps)))
+	audio := trak(1, 2, 48000, "soun", box("mp4a", make([]byte, audioSampleEntrySize), esds(0x6B)))
+	mvex := box("mvex", fullBox("trex", 0, 0, u32(1, 1, 3000, 0, 0)), fullBox("trex", 0, 0, u32(2, 1, 1024, 0, 0)))
+	data := concat(box("ftyp", []byte("iso6"), u32(0)), box("moov", fullBox("mvhd", 0, 0, make([]byte, 96)), video, audio, mvex))
+
+	init, err := ParseInit(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Track{
+		{ID: 1, Handler: "vide", SampleEntry: "avc1", Codec: "H.264", Timescale: 90000, DefaultSampleDuration: 3000,
+			Width: 1920, Height: 1080, FrameRate: 30},
+		{ID: 2, Handler: "soun", SampleEntry: "mp4a", Codec: "MP3", Timescale: 48000, DefaultSampleDuration: 1024},
+	}
+	if !reflect.DeepEqual(init.Tracks, want) {
+		t.Errorf("Tracks = %+v\nwant %+v", init.Tracks, want)
+	}
+	if got := init.Codecs(); !reflect.DeepEqual(got, []string{"H.264", "MP3"}) {
+		t.Errorf("Codecs = %v", got)
+	}
+	if v := init.VideoTrack(); v == nil || v.ID != 1 {
+		t.Errorf("VideoTrack = %+v", v)
+	}
+
+	if _, err := ParseInit(box("ftyp", []byte("iso6"))); err == nil {
+		t.Error("init segment without moov: expected error")
+	}
+	if _, err := ParseInit(box("moov", fullBox("mvhd", 0, 0, make([]byte, 96)))); err == nil {
+		t.Error("moov without tracks: expected error")
+	}
+}
+
+func TestParseTrak(t *testing.T) {
+	hev1 := visualEntry("hev1", 3840, 2160)
+	encrypted := visualEntry("encv", 640, 360, box("sinf", box("frma", []byte("avc1")), box("schm", u32(0))))
+	tests := []struct {
+		name string
+		trak []byte
+		want Track
+	}{
+		{"version 0 headers", trak(0, 7, 12800, "vide", hev1),
+			Track{ID: 7, Handler: "vide", SampleEntry: "hev1", Codec: "H.265", Timescale: 12800, Width: 3840, Height: 2160}},
+		{"version 1 headers", trak(1, 0x01020304, 44100, "soun", box("mp4a", make([]byte, audioSampleEntrySize), esds(0x40))),
+			Track{ID: 0x01020304, Handler: "soun", SampleEntry: "mp4a", Codec: "AAC", Timescale: 44100}},
+		{"encrypted sample entry", trak(0, 1, 90000, "vide", encrypted),
+			Track{ID: 1, Handler: "vide", SampleEntry: "avc1", Codec: "H.264", Encrypted: true, Timescale: 90000, Width: 640, Height: 360}},
+		{"unknown sample entry", trak(0, 3, 1000, "subt", box("xyz1", u32(0))),
+			Track{ID: 3, Handler: "subt", SampleEntry: "xyz1", Codec: "unknown(xyz1)", Timescale: 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, err := ReadBox(tt.trak)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := parseTrak(b.Data); got != tt.want {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMP3Descriptor(t *testing.T) {
+	versionFlags := []byte{0, 0, 0, 0}
+	decoderConfig := func(oti byte) []byte { return []byte{0x04, 2, oti, 0x15} }
+	es := func(flags byte, extra []byte, oti byte) []byte {
+		body := append(append([]byte{0, 1, flags}, extra...), decoderConfig(oti)...)
+		return append(append(versionFlags, 0x03, byte(len(body))), body...)
+	}
+	tests := []struct {
+		name string
+		esds []byte
+		want bool
+	}{
+		{"AAC", es(0, nil, 0x40), false},
+		{"MPEG-1 audio", es(0, nil, 0x6B), true},
+		{"MPEG-2 audio", es(0, nil, 0x69), true},
+		{"stream dependence", es(0x80, []byte{0, 2}, 0x6B), true},
+		{"URL", es(0x40, []byte{3, 'a', 'b', 'c'}, 0x6B), true},
+		{"OCR stream", es(0x20, []byte{0, 3}, 0x6B), true},
+		{"all optional fields", es(0xE0, []byte{0, 2, 1, 'x', 0, 3}, 0x69), true},
+		{"multi-byte length", append(versionFlags, 0x03, 0x80, 0x80, 0x80, 7, 0, 1, 0, 0x04, 0x80, 1, 0x6B), true},
+		{"not an ES_Descriptor", append(versionFlags, 0x05, 2, 0x6B, 0), false},
+		{"missing DecoderConfigDescriptor", append(versionFlags, 0x03, 3, 0, 1, 0), false},
+		{"truncated", es(0, nil, 0x6B)[:9], false},
+		{"empty", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isMP3Descriptor(tt.esds); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
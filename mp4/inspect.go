@@ -0,0 +1,67 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Info 是 MP4/fMP4 数据的顶层结构概要
+type Info struct {
+	MajorBrand string // ftyp 或 styp 的主品牌，如 isom、iso6、cmfc
+	HasMoov    bool
+	Fragmented bool  // moov 中有 mvex 或出现了 moof
+	Fragments  int   // moof 的数量
+	MediaData  int64 // mdat 中已读取的字节数
+
+	TrackFragments []TrackFragment // 各 moof 中的 traf，按出现顺序排列
+}
+
+// Inspect 检查数据的顶层盒子结构。数据必须以常见的顶层盒子开头，并且包含 moov 或 moof，
+// 每个 moof 之后必须有 mdat。最后一个盒子不完整不算错误。
+func Inspect(data []byte) (*Info, error) {
+	if !IsTopLevelBox(data) {
+		return nil, fmt.Errorf("data does not start with an MP4 box")
+	}
+
+	info := &Info{}
+	pendingMoof := false // 上一个 moof 之后还没有出现 mdat
+	for len(data) > 0 {
+		box, rest, err := ReadBox(data)
+		if err != nil && !errors.Is(err, ErrTruncated) {
+			return nil, err
+		}
+		switch box.Type {
+		case "ftyp", "styp":
+			if len(box.Data) >= 4 {
+				info.MajorBrand = string(box.Data[:4])
+			}
+		case "moov":
+			info.HasMoov = true
+			if _, ok := FindBox(box.Data, "mvex"); ok {
+				info.Fragmented = true
+			}
+		case "moof":
+			if pendingMoof {
+				return nil, fmt.Errorf("moof %d has no mdat", info.Fragments)
+			}
+			info.Fragments++
+			info.Fragmented = true
+			pendingMoof = true
+			if err == nil {
+				info.TrackFragments = append(info.TrackFragments, parseMoof(box.Data)...)
+			}
+		case "mdat":
+			info.MediaData += int64(len(box.Data))
+			pendingMoof = false
+		}
+		if err != nil {
+			break
+		}
+		data = rest
+	}
+
+	if !info.HasMoov && info.Fragments == 0 {
+		return nil, fmt.Errorf("no moov or moof box found")
+	}
+	return info, nil
+}
@@ -0,0 +1,156 @@
+package mpegts
+
+import (
+	"errors"
+)
+
+// Info 是对一段 MPEG-TS 数据的检查结果
+type Info struct {
+	Packets          int // 成功解析的 TS 包数量
+	SyncErrors       int // 丢失同步后重新同步的次数
+	TransportErrors  int // 设置了 transport_error_indicator 的包数量
+	ContinuityErrors int // 连续计数器不连续的次数
+	Streams          []Stream
+
+	// 第一个视频流的数据，用于解析 SPS 和估算帧率
+	VideoData []byte  // 开头几个 PES 包的基本流数据
+	VideoPTS  []int64 // 各 PES 包的 PTS (90kHz)
+}
+
+// VideoCodec 返回第一个视频流的编码，没有视频流时返回空字符串
+func (info *Info) VideoCodec() string {
+	for _, s := range info.Streams {
+		if s.Kind == KindVideo {
+			return s.Codec
+		}
+	}
+	return ""
+}
+
+// Codecs 返回所有基本流的编码名称，按 PMT 中的顺序去重
+func (info *Info) Codecs() []string {
+	seen := make(map[string]bool)
+	codecs := []string{}
+	for _, s := range info.Streams {
+		if s.Kind == KindOther || seen[s.Codec] {
+			continue
+		}
+		seen[s.Codec] = true
+		codecs = append(codecs, s.Codec)
+	}
+	return codecs
+}
+
+// HasVideo 判断是否包含视频流
+func (info *Info) HasVideo() bool {
+	for _, s := range info.Streams {
+		if s.Kind == KindVideo {
+			return true
+		}
+	}
+	return false
+}
+
+// Inspect 检查 data 是否为有效的 MPEG-TS：按 188 字节包同步，解析 PAT/PMT 列出基本流，
+// 并统计连续计数器错误。找不到同步字节或没有 PAT/PMT 时返回错误。
+func Inspect(data []byte) (*Info, error) {
+	start := findSync(data)
+	if start < 0 {
+		return nil, ErrNoSync
+	}
+
+	info := &Info{}
+	pmtPIDs := make(map[uint16]bool)
+	streamPIDs := make(map[uint16]bool)
+	sections := make(map[uint16]*sectionAssembler)
+	lastCC := make(map[uint16]uint8)
+	sawPAT := false
+	var video *videoCollector
+
+	for pos := start; pos+PacketSize <= len(data); pos += PacketSize {
+		if data[pos] != SyncByte {
+			info.SyncErrors++
+			next := findSync(data[pos:])
+			if next < 0 {
+				break
+			}
+			pos += next
+		}
+
+		p, err := parsePacket(data[pos : pos+PacketSize])
+		if err != nil {
+			info.TransportErrors++
+			continue
+		}
+		info.Packets++
+		if p.transportError {
+			info.TransportErrors++
+			continue
+		}
+		if p.pid == nullPID {
+			continue
+		}
+
+		// 只有带负载的包才递增连续计数器，重复包可以有相同的计数值
+		if p.hasPayload {
+			if last, ok := lastCC[p.pid]; ok && !p.discontinuity && p.cc != last && p.cc != (last+1)&0x0F {
+				info.ContinuityErrors++
+			}
+			lastCC[p.pid] = p.cc
+		}
+
+		if video != nil && p.pid == video.pid && p.hasPayload {
+			video.push(p)
+			continue
+		}
+		if p.pid != patPID && !pmtPIDs[p.pid] {
+			continue
+		}
+		assembler := sections[p.pid]
+		if assembler == nil {
+			assembler = &sectionAssembler{}
+			sections[p.pid] = assembler
+		}
+		section := assembler.push(p)
+		if section == nil {
+			continue
+		}
+
+		if p.pid == patPID {
+			pids, err := parsePAT(section)
+			if err != nil {
+				continue
+			}
+			sawPAT = true
+			for _, pid := range pids {
+				pmtPIDs[pid] = true
+			}
+			continue
+		}
+		streams, err := parsePMT(section)
+		if err != nil {
+			continue
+		}
+		for _, s := range streams {
+			if !streamPIDs[s.PID] {
+				streamPIDs[s.PID] = true
+				info.Streams = append(info.Streams, s)
+			}
+			if video == nil && s.Kind == KindVideo {
+				video = &videoCollector{pid: s.PID}
+			}
+		}
+	}
+	if video != nil {
+		info.VideoData = video.data
+		info.VideoPTS = video.pts
+	}
+
+	if !sawPAT {
+		return info, errors.New("no valid PAT found")
+	}
+	if len(info.Streams) == 0 {
+		return info, errors.New("no valid PMT found")
+	}
+	return info, nil
+}
@@ -0,0 +1,124 @@
+package mpegts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tsPacket 构造一个只有负载的 TS 包，剩余部分用 0xFF 填充
+func tsPacket(pid uint16, start bool, cc uint8, payload []byte) []byte {
+	p := bytes.Repeat([]byte{0xFF}, PacketSize)
+	p[0] = SyncByte
+	p[1] = byte(pid>>8) & 0x1F
+	if start {
+		p[1] |= 0x40
+	}
+	p[2] = byte(pid)
+	p[3] = 0x10 | cc&0x0F
+	copy(p[4:], payload)
+	return p
+}
+
+// psiPacket 将 PSI 段（不含 CRC）加上 CRC32 和 pointer_field 放进一个 TS 包
+func psiPacket(pid uint16, section []byte) []byte {
+	section = binary.BigEndian.AppendUint32(section, crc32MPEG(section))
+	return tsPacket(pid, true, 0, append([]byte{0}, section...))
+}
+
+var (
+	// testPAT 声明节目 1，PMT 位于 PID 0x100
+	testPAT = []byte{0x00, 0xB0, 13, 0, 1, 0xC1, 0, 0, 0, 1, 0xE1, 0x00}
+	// testPMT 声明 PID 0x101 上的 H.264 和 PID 0x102 上的 AAC
+	testPMT = []byte{0x02, 0xB0, 23, 0, 1, 0xC1, 0, 0, 0xE1, 0x01, 0xF0, 0,
+		0x1B, 0xE1, 0x01, 0xF0, 0,
+		0x0F, 0xE1, 0x02, 0xF0, 0}
+)
+
+// buildStream 构造 PAT、PMT 和 n 个视频包，ccs 指定各视频包的连续计数器，为空时依次递增
+func buildStream(n int, ccs ...uint8) []byte {
+	data := append(psiPacket(patPID, testPAT), psiPacket(0x100, testPMT)...)
+	for i := 0; i < n; i++ {
+		cc := uint8(i)
+		if i < len(ccs) {
+			cc = ccs[i]
+		}
+		data = append(data, tsPacket(0x101, false, cc, nil)...)
+	}
+	return data
+}
+
+func TestCRC32MPEG(t *testing.T) {
+	// CRC-32/MPEG-2 的标准校验值
+	if got := crc32MPEG([]byte("123456789")); got != 0x0376E6E7 {
+		t.Errorf("crc32MPEG = %#08x, want 0x0376e6e7", got)
+	}
+	section := binary.BigEndian.AppendUint32(append([]byte{}, testPAT...), crc32MPEG(testPAT))
+	if got := crc32MPEG(section); got != 0 {
+		t.Errorf("crc32MPEG over section with CRC = %#08x, want 0", got)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	corrupt := func(data []byte, pos int) []byte {
+		data = append([]byte{}, data...)
+		data[pos] ^= 0xFF
+		return data
+	}
+	garbage := bytes.Repeat([]byte("garbage!"), 20)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantErr    string
+		packets    int
+		syncErrors int
+		ccErrors   int
+	}{
+		{name: "valid PAT and PMT", data: buildStream(4), packets: 6},
+		{name: "duplicate packet", data: buildStream(4, 0, 1, 1, 2), packets: 6},
+		{name: "continuity gap", data: buildStream(4, 0, 1, 3, 4), packets: 6, ccErrors: 1},
+		{name: "counter wraps", data: buildStream(18), packets: 20},
+		{name: "leading garbage", data: append(garbage, buildStream(4)...), packets: 6},
+		{name: "garbage between packets", data: func() []byte {
+			data := buildStream(6)
+			return append(append(append([]byte{}, data[:3*PacketSize]...), garbage[:50]...), data[3*PacketSize:]...)
+		}(), packets: 8, syncErrors: 1},
+		{name: "bad PAT CRC", data: corrupt(buildStream(2), 5+len(testPAT)), wantErr: "no valid PAT found"},
+		{name: "bad PMT CRC", data: corrupt(buildStream(2), PacketSize+10), wantErr: "no valid PMT found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Inspect(tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Packets != tt.packets || info.SyncErrors != tt.syncErrors || info.ContinuityErrors != tt.ccErrors {
+				t.Errorf("Packets, SyncErrors, ContinuityErrors = %d, %d, %d, want %d, %d, %d",
+					info.Packets, info.SyncErrors, info.ContinuityErrors, tt.packets, tt.syncErrors, tt.ccErrors)
+			}
+			if want := []string{"H.264", "AAC"}; !reflect.DeepEqual(info.Codecs(), want) {
+				t.Errorf("Codecs = %v, want %v", info.Codecs(), want)
+			}
+		})
+	}
+}
+
+func TestInspectNotTS(t *testing.T) {
+	html := []byte(strings.Repeat("<html><body><p>404 Not Found</p></body></html>\n", 20))
+	if _, err := Inspect(html); !errors.Is(err, ErrNoSync) {
+		t.Errorf("err = %v, want ErrNoSync", err)
+	}
+	if _, err := Inspect(nil); !errors.Is(err, ErrNoSync) {
+		t.Errorf("empty input: err = %v, want ErrNoSync", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Package mpegts 检查 MPEG-TS 数据：包同步、PAT/PMT 节目表、基本流类型和连续计数器
+package mpegts
+
+import (
+	"errors"
+)
+
+// MPEG-TS 包的固定参数
+const (
+	PacketSize = 188
+	SyncByte   = 0x47
+
+	patPID  = 0x0000
+	nullPID = 0x1FFF
+)
+
+// ErrNoSync 表示数据中找不到连续的 0x47 同步字节，不是 MPEG-TS
+var ErrNoSync = errors.New("no MPEG-TS sync byte found")
+
+// packet 是解析后的一个 TS 包
+type packet struct {
+	pid            uint16
+	payloadStart   bool // payload_unit_start_indicator
+	transportError bool
+	cc             uint8 // continuity_counter
+	hasPayload     bool
+	discontinuity  bool // 自适应字段中的 discontinuity_indicator
+	payload        []byte
+}
+
+// parsePacket 解析一个 188 字节的 TS 包
+func parsePacket(b []byte) (packet, error) {
+	p := packet{
+		pid:            uint16(b[1]&0x1F)<<8 | uint16(b[2]),
+		payloadStart:   b[1]&0x40 != 0,
+		transportError: b[1]&0x80 != 0,
+		cc:             b[3] & 0x0F,
+	}
+
+	control := (b[3] >> 4) & 0x03
+	offset := 4
+	if control&0x02 != 0 {
+		length := int(b[4])
+		if length > 0 {
+			p.discontinuity = b[5]&0x80 != 0
+		}
+		offset = 5 + length
+		if offset > PacketSize {
+			return p, errors.New("adaptation field exceeds packet")
+		}
+	}
+	if control&0x01 != 0 {
+		p.hasPayload = true
+		p.payload = b[offset:PacketSize]
+	}
+	return p, nil
+}
+
+// findSync 返回第一个后续包也以同步字节开头的位置，找不到时返回 -1。
+// 检查后续两个包可以避免把负载中偶然出现的 0x47 当成包头。
+func findSync(data []byte) int {
+	for off := 0; off < PacketSize && off < len(data); off++ {
+		if data[off] != SyncByte {
+			continue
+		}
+		ok := true
+		for k := 1; k <= 2; k++ {
+			next := off + k*PacketSize
+			if next < len(data) && data[next] != SyncByte {
+				ok = false
+				break
+			}
+		}
+		if ok && off+PacketSize <= len(data) {
+			return off
+		}
+	}
+	return -1
+}
@@ -0,0 +1,81 @@
+package mpegts
+
+import (
+	"sort"
+)
+
+// 收集视频基本流数据的上限，SPS 位于第一个关键帧之前，只需要开头的几个 PES 包
+const (
+	maxVideoPES  = 3
+	maxVideoData = 1024 * 1024
+)
+
+// pesHeader 解析 PES 包头，返回负载在 payload 中的起始位置和 PTS（没有 PTS 时为 -1）
+func pesHeader(payload []byte) (dataStart int, pts int64, ok bool) {
+	if len(payload) < 9 || payload[0] != 0 || payload[1] != 0 || payload[2] != 1 {
+		return 0, -1, false
+	}
+	dataStart = 9 + int(payload[8])
+	if dataStart > len(payload) {
+		return 0, -1, false
+	}
+	pts = -1
+	if payload[7]&0x80 != 0 && len(payload) >= 14 {
+		b := payload[9:14]
+		pts = int64(b[0]>>1&0x07)<<30 | int64(b[1])<<22 | int64(b[2]>>1)<<15 | int64(b[3])<<7 | int64(b[4]>>1)
+	}
+	return dataStart, pts, true
+}
+
+// videoCollector 收集第一个视频流的 PTS 和开头几个 PES 包的数据
+type videoCollector struct {
+	pid     uint16
+	pesSeen int
+	data    []byte
+	pts     []int64
+}
+
+func (c *videoCollector) push(p packet) {
+	if p.payloadStart {
+		start, pts, ok := pesHeader(p.payload)
+		if !ok {
+			return
+		}
+		c.pesSeen++
+		if pts >= 0 {
+			c.pts = append(c.pts, pts)
+		}
+		if c.pesSeen <= maxVideoPES {
+			c.appendData(p.payload[start:])
+		}
+		return
+	}
+	if c.pesSeen > 0 && c.pesSeen <= maxVideoPES {
+		c.appendData(p.payload)
+	}
+}
+
+func (c *videoCollector) appendData(b []byte) {
+	if room := maxVideoData - len(c.data); room > 0 {
+		if len(b) > room {
+			b = b[:room]
+		}
+		c.data = append(c.data, b...)
+	}
+}
+
+// FrameRateFromPTS 根据视频 PES 的 PTS 间隔估算帧率，假定每个 PES 包含一帧。
+// PTS 少于两个时返回 0。
+func (info *Info) FrameRateFromPTS() float64 {
+	pts := append([]int64(nil), info.VideoPTS...)
+	if len(pts) < 2 {
+		return 0
+	}
+	// 有 B 帧时 PTS 不是单调递增的，先排序
+	sort.Slice(pts, func(i, j int) bool { return pts[i] < pts[j] })
+	span := pts[len(pts)-1] - pts[0]
+	if span <= 0 {
+		return 0
+	}
+	return float64(len(pts)-1) * 90000 / float64(span)
+}
@@ -0,0 +1,117 @@
+package mpegts
+
+import (
+	"errors"
+)
+
+// PSI 表的 table_id
+const (
+	tablePAT = 0x00
+	tablePMT = 0x02
+)
+
+// sectionAssembler 将跨越多个 TS 包的 PSI 段拼接完整
+type sectionAssembler struct {
+	buf    []byte
+	active bool
+}
+
+// push 加入一个包的负载，拼出完整的段时返回该段
+func (a *sectionAssembler) push(p packet) []byte {
+	if p.payloadStart {
+		if len(p.payload) == 0 {
+			return nil
+		}
+		pointer := int(p.payload[0])
+		if 1+pointer > len(p.payload) {
+			a.active = false
+			return nil
+		}
+		a.buf = append(a.buf[:0], p.payload[1+pointer:]...)
+		a.active = true
+	} else if a.active {
+		a.buf = append(a.buf, p.payload...)
+	} else {
+		return nil
+	}
+
+	if len(a.buf) < 3 {
+		return nil
+	}
+	total := 3 + (int(a.buf[1]&0x0F)<<8 | int(a.buf[2]))
+	if len(a.buf) < total {
+		return nil
+	}
+	a.active = false
+	return a.buf[:total]
+}
+
+// checkSection 校验段的 table_id、长度和 CRC32
+func checkSection(section []byte, tableID byte, minLength int) error {
+	if section[0] != tableID {
+		return errors.New("unexpected table id")
+	}
+	if len(section) < minLength {
+		return errors.New("section too short")
+	}
+	if crc32MPEG(section) != 0 {
+		return errors.New("CRC mismatch")
+	}
+	return nil
+}
+
+// parsePAT 返回节目关联表中各节目的 PMT PID
+func parsePAT(section []byte) ([]uint16, error) {
+	if err := checkSection(section, tablePAT, 12); err != nil {
+		return nil, err
+	}
+	var pids []uint16
+	for i := 8; i+4 <= len(section)-4; i += 4 {
+		program := uint16(section[i])<<8 | uint16(section[i+1])
+		pid := uint16(section[i+2]&0x1F)<<8 | uint16(section[i+3])
+		if program != 0 { // 节目号 0 指向网络信息表
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// parsePMT 返回节目映射表中的基本流
+func parsePMT(section []byte) ([]Stream, error) {
+	if err := checkSection(section, tablePMT, 16); err != nil {
+		return nil, err
+	}
+	programInfoLength := int(section[10]&0x0F)<<8 | int(section[11])
+	var streams []Stream
+	end := len(section) - 4
+	for i := 12 + programInfoLength; i+5 <= end; {
+		streamType := section[i]
+		pid := uint16(section[i+1]&0x1F)<<8 | uint16(section[i+2])
+		infoLength := int(section[i+3]&0x0F)<<8 | int(section[i+4])
+		descStart := i + 5
+		descEnd := descStart + infoLength
+		if descEnd > end {
+			return nil, errors.New("elementary stream info exceeds section")
+		}
+		streams = append(streams, newStream(pid, streamType, section[descStart:descEnd]))
+		i = descEnd
+	}
+	return streams, nil
+}
+
+// crc32MPEG 计算 MPEG-2 使用的 CRC32（多项式 0x04C11DB7，不反转）。
+// 对包含 CRC 字段的完整段计算结果为 0 时校验通过。
+func crc32MPEG(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,86 @@
+package mpegts
+
+import (
+	"fmt"
+)
+
+// StreamKind 是基本流的大类
+type StreamKind string
+
+const (
+	KindVideo StreamKind = "video"
+	KindAudio StreamKind = "audio"
+	KindOther StreamKind = "other"
+)
+
+// Stream 是 PMT 中声明的一个基本流
+type Stream struct {
+	PID   uint16
+	Type  byte   // PMT 中的 stream_type
+	Codec string // 如 H.264、H.265、AAC、MP2
+	Kind  StreamKind
+}
+
+// streamTypes 是常见 stream_type 对应的编码和类别
+var streamTypes = map[byte]struct {
+	codec string
+	kind  StreamKind
+}{
+	0x01: {"MPEG-1 Video", KindVideo},
+	0x02: {"MPEG-2 Video", KindVideo},
+	0x03: {"MP2", KindAudio}, // MPEG-1 音频，直播源中通常是 Layer II
+	0x04: {"MP2", KindAudio},
+	0x0F: {"AAC", KindAudio},
+	0x10: {"MPEG-4 Visual", KindVideo},
+	0x11: {"AAC-LATM", KindAudio},
+	0x15: {"ID3", KindOther},
+	0x1B: {"H.264", KindVideo},
+	0x24: {"H.265", KindVideo},
+	0x42: {"AVS", KindVideo},
+	0x81: {"AC-3", KindAudio},
+	0x87: {"E-AC-3", KindAudio},
+}
+
+// 私有数据流 (stream_type 0x06) 通过描述符识别的编码
+const (
+	descriptorRegistration = 0x05
+	descriptorAC3          = 0x6A
+	descriptorEAC3         = 0x7A
+)
+
+// newStream 根据 stream_type 和描述符识别编码
+func newStream(pid uint16, streamType byte, descriptors []byte) Stream {
+	s := Stream{PID: pid, Type: streamType, Codec: fmt.Sprintf("0x%02X", streamType), Kind: KindOther}
+	if t, ok := streamTypes[streamType]; ok {
+		s.Codec, s.Kind = t.codec, t.kind
+		return s
+	}
+	if streamType != 0x06 {
+		return s
+	}
+
+	for len(descriptors) >= 2 {
+		tag, length := descriptors[0], int(descriptors[1])
+		if 2+length > len(descriptors) {
+			break
+		}
+		body := descriptors[2 : 2+length]
+		switch {
+		case tag == descriptorAC3:
+			s.Codec, s.Kind = "AC-3", KindAudio
+		case tag == descriptorEAC3:
+			s.Codec, s.Kind = "E-AC-3", KindAudio
+		case tag == descriptorRegistration && length >= 4:
+			switch string(body[:4]) {
+			case "AC-3":
+				s.Codec, s.Kind = "AC-3", KindAudio
+			case "EAC3":
+				s.Codec, s.Kind = "E-AC-3", KindAudio
+			case "HEVC":
+				s.Codec, s.Kind = "H.265", KindVideo
+			}
+		}
+		descriptors = descriptors[2+length:]
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ChannelSpec 是批量测试中的一个频道
+type ChannelSpec struct {
+	Name  string   // 输出时使用的频道名
+	Group string   // 所属分组，来自 "分组,#genre#" 行
+	Terms []string // 搜索关键词，第一个为频道名本身，其后为别名
+}
+
+// ParseChannelList 解析批量测试使用的频道列表。每行一个频道，
+// 格式为 "频道名" 或 "频道名|别名1|别名2"，别名同时作为搜索关键词；
+// "分组,#genre#" 行开始一个新分组，# 开头的行为注释。
+func ParseChannelList(r io.Reader) ([]ChannelSpec, error) {
+	scanner := bufio.NewScanner(r)
+	channels := []ChannelSpec{}
+	seen := make(map[string]bool)
+	group := ""
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if line == "" {
+			continue
+		}
+
+		if comma := strings.IndexAny(line, ",，"); comma >= 0 && strings.Contains(strings.ToLower(line), "#genre#") {
+			group = strings.TrimSpace(line[:comma])
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		spec := ChannelSpec{Group: group}
+		termSeen := make(map[string]bool)
+		for _, term := range strings.Split(line, "|") {
+			term = strings.TrimSpace(term)
+			if term == "" || termSeen[NormalizeChannelName(term)] {
+				continue
+			}
+			termSeen[NormalizeChannelName(term)] = true
+			spec.Terms = append(spec.Terms, term)
+		}
+		if len(spec.Terms) == 0 {
+			continue
+		}
+		spec.Name = spec.Terms[0]
+
+		key := NormalizeChannelName(spec.Name)
+		if seen[key] {
+			return nil, fmt.Errorf("第 %d 行: 频道 %s 重复", lineNo, spec.Name)
+		}
+		seen[key] = true
+		channels = append(channels, spec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取频道列表失败: %v", err)
+	}
+
+	return channels, nil
+}
+
+// NormalizeChannelName 将频道名规范化用于比较：忽略大小写、空格、"-" 和 "_"
+func NormalizeChannelName(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "", "　", "").Replace(name))
+}
+
+// channelSuffixes 是搜索结果中常附在频道名后、不影响频道身份的画质标记，比较前去掉
+var channelSuffixes = []string{"高清", "超清", "标清", "蓝光", "fhd", "uhd", "hd", "hevc", "h265"}
+
+// trimChannelSuffixes 去掉规范化后频道名末尾的画质标记，只剩标记本身时原样返回
+func trimChannelSuffixes(name string) string {
+	for trimmed := true; trimmed; {
+		trimmed = false
+		for _, suffix := range channelSuffixes {
+			if len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+				name = strings.TrimSuffix(name, suffix)
+				trimmed = true
+			}
+		}
+	}
+	return name
+}
+
+// Matches 判断搜索结果中的频道名是否属于该频道。两边规范化并去掉画质标记后，
+// 结果与频道名或任一别名相同，或以其开头且后面不是字母、数字或 "+"
+// （"CCTV5体育" 属于 CCTV5，"CCTV13" 不属于 CCTV1，"CCTV5+" 不属于 CCTV5）
+func (c ChannelSpec) Matches(channel string) bool {
+	normalized := trimChannelSuffixes(NormalizeChannelName(channel))
+	for _, term := range c.Terms {
+		t := trimChannelSuffixes(NormalizeChannelName(term))
+		if t == "" || !strings.HasPrefix(normalized, t) {
+			continue
+		}
+		rest := normalized[len(t):]
+		if rest == "" || !isChannelNameContinuation(rest[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isChannelNameContinuation 判断字节是否会让频道名变成另一个频道（如 CCTV1 → CCTV13、CCTV5 → CCTV5+）
+func isChannelNameContinuation(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b == '+'
+}
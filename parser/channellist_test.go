@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChannelSpecMatches(t *testing.T) {
+	tests := []struct {
+		terms   []string
+		channel string
+		want    bool
+	}{
+		{[]string{"CCTV-5", "CCTV5"}, "cctv 5", true},
+		{[]string{"CCTV-5", "CCTV5"}, "CCTV5体育", true},
+		{[]string{"CCTV-5", "CCTV5"}, "CCTV-5 高清", true},
+		{[]string{"CCTV-5", "CCTV5"}, "CCTV5 HD", true},
+		{[]string{"CCTV-5", "CCTV5"}, "CCTV-5体育 超清", true},
+		{[]string{"CCTV-5", "CCTV5"}, "CCTV5+", false},
+		{[]string{"CCTV-5", "CCTV5"}, "CCTV5+体育赛事", false},
+		{[]string{"CCTV1"}, "CCTV13", false},
+		{[]string{"CCTV1"}, "CCTV-1 综合", true},
+		{[]string{"CCTV1"}, "CCTV1HD", true},
+		{[]string{"CCTV4K"}, "CCTV4K 超高清", true},
+		{[]string{"CCTV4"}, "CCTV4K", false},
+		{[]string{"湖南卫视", "湖南台"}, "湖南卫视高清", true},
+		{[]string{"湖南卫视", "湖南台"}, "湖南都市", false},
+		{[]string{"湖南卫视"}, "卫视", false},
+		{[]string{"HD"}, "HD", true},
+	}
+	for _, tt := range tests {
+		spec := ChannelSpec{Name: tt.terms[0], Terms: tt.terms}
+		if got := spec.Matches(tt.channel); got != tt.want {
+			t.Errorf("%v.Matches(%q) = %v, want %v", tt.terms, tt.channel, got, tt.want)
+		}
+	}
+}
+
+func TestParseChannelList(t *testing.T) {
+	input := "\uFEFF央视,#genre#\nCCTV-1|CCTV1|cctv 1\n# 注释\n\n卫视，#genre#\n湖南卫视|湖南台\n"
+	got, err := ParseChannelList(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ChannelSpec{
+		{Name: "CCTV-1", Group: "央视", Terms: []string{"CCTV-1"}},
+		{Name: "湖南卫视", Group: "卫视", Terms: []string{"湖南卫视", "湖南台"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseChannelList(strings.NewReader("CCTV1\ncctv-1\n")); err == nil {
+		t.Error("duplicate channel: expected error")
+	}
+}
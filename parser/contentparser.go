@@ -6,11 +6,11 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 )
 
-func FetchPageContent(searchURL string, client *http.Client) ([]string, error) {
-	fmt.Printf("正在搜索: %s\n", searchURL)
-
+// FetchPageContent 获取搜索页面，提取其中的流媒体链接、卡片上显示的元数据以及分页信息
+func FetchPageContent(searchURL string, client *http.Client) (*SearchPage, error) {
 	resp, err := client.Get(searchURL)
 	if err != nil {
 		return nil, fmt.Errorf("发送 GET 请求失败: %v", err)
@@ -27,60 +27,75 @@ func FetchPageContent(searchURL string, client *http.Client) ([]string, error) {
 	}
 	pageContent := string(bodyBytes)
 
-	streamLinks := []string{}
+	pageLinks := ExtractStreamLinks(pageContent)
+	results := make([]SearchResult, 0, len(pageLinks))
+	for _, link := range pageLinks {
+		result := SearchResult{URL: link.URL, Element: link.Element}
+		if link.card != nil {
+			fillCardMetadata(&result, link.card, time.Now())
+		}
+		results = append(results, result)
+	}
+
+	return &SearchPage{Results: results, LastPage: DetectLastPage(pageContent)}, nil
+}
+
+var (
+	classRegex   = regexp.MustCompile(`class\s*=\s*[\"']([^\"']*play[^\"']*|[^\"']*stream[^\"']*|[^\"']*link[^\"']*)[\"'][^>]*>([^<]*)`)
+	onclickRegex = regexp.MustCompile(`onclick\s*=\s*[\"']?[a-zA-Z0-9_]+\s*\(\s*[\"']([^\"']+)[\"']?\)[\"']?`)
+	dataURLRegex = regexp.MustCompile(`data-(?:url|link|stream)\s*=\s*[\"']([^\"']+)[\"']`)
+	hrefRegex    = regexp.MustCompile(`href\s*=\s*[\"']([^\"']+(?:\.m3u8|\.m3u|\.mpd|/live/|/hls/|/dash/|udp://|rtmp://|rtsp://)[^\"']*)[\"']`)
+	jsURLRegex   = regexp.MustCompile(`[\"'](https?://[^\"']+(?:\.m3u8|\.m3u|\.mpd|/live/|/hls/|/dash/)[^\"']*)[\"']`)
+
+	// contextURLRegexes 用于在元素上下文中查找URL，按优先级排列
+	contextURLRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`onclick\s*=\s*[\"']?[a-zA-Z0-9_]+\s*\(\s*[\"']([^\"']+)[\"']`),
+		regexp.MustCompile(`data-(?:url|link|stream)\s*=\s*[\"']([^\"']+)[\"']`),
+		regexp.MustCompile(`href\s*=\s*[\"']([^\"']+)[\"']`),
+		regexp.MustCompile(`src\s*=\s*[\"']([^\"']+)[\"']`),
+		regexp.MustCompile(`[\"'](https?://[^\"'\s]+)[\"']`),
+	}
+)
+
+// extractLinksByRegex 是页面结构无法识别时的后备方案，使用正则在原始HTML中查找链接
+func extractLinksByRegex(pageContent string) []PageLink {
+	links := []PageLink{}
+	seen := make(map[string]bool)
+	add := func(url, element string) {
+		if url == "" || seen[url] || !isValidStreamURL(url) {
+			return
+		}
+		seen[url] = true
+		links = append(links, PageLink{URL: url, Element: element, Strategy: "regex"})
+	}
 
 	// 方法1: 使用class属性查找链接
-	classRegex := regexp.MustCompile(`class\s*=\s*[\"']([^\"']*play[^\"']*|[^\"']*stream[^\"']*|[^\"']*link[^\"']*)[\"'][^>]*>([^<]*)`)
-	classMatches := classRegex.FindAllStringSubmatch(pageContent, -1)
-	for _, match := range classMatches {
+	for _, match := range classRegex.FindAllStringSubmatch(pageContent, -1) {
 		// 在匹配的元素附近查找URL
-		if url := extractURLFromContext(pageContent, match[0]); url != "" && isValidStreamURL(url) {
-			streamLinks = append(streamLinks, url)
-		}
+		add(extractURLFromContext(pageContent, match[0]), "class")
 	}
 
 	// 方法2: 查找所有onclick事件，不依赖具体函数名
-	onclickRegex := regexp.MustCompile(`onclick\s*=\s*[\"']?[a-zA-Z0-9_]+\s*\(\s*[\"']([^\"']+)[\"']?\)[\"']?`)
-	onclickMatches := onclickRegex.FindAllStringSubmatch(pageContent, -1)
-	for _, match := range onclickMatches {
-		url := match[1]
-		if isValidStreamURL(url) {
-			streamLinks = append(streamLinks, url)
-		}
+	for _, match := range onclickRegex.FindAllStringSubmatch(pageContent, -1) {
+		add(match[1], "onclick")
 	}
 
 	// 方法3: 查找data-url或类似属性
-	dataURLRegex := regexp.MustCompile(`data-(?:url|link|stream)\s*=\s*[\"']([^\"']+)[\"']`)
-	dataURLMatches := dataURLRegex.FindAllStringSubmatch(pageContent, -1)
-	for _, match := range dataURLMatches {
-		url := match[1]
-		if isValidStreamURL(url) {
-			streamLinks = append(streamLinks, url)
-		}
+	for _, match := range dataURLRegex.FindAllStringSubmatch(pageContent, -1) {
+		add(match[1], "data-url")
 	}
 
 	// 方法4: 查找href属性中的流媒体链接
-	hrefRegex := regexp.MustCompile(`href\s*=\s*[\"']([^\"']+(?:\.m3u8|\.m3u|/live/|/hls/|udp://|rtmp://|rtsp://)[^\"']*)[\"']`)
-	hrefMatches := hrefRegex.FindAllStringSubmatch(pageContent, -1)
-	for _, match := range hrefMatches {
-		url := match[1]
-		if isValidStreamURL(url) {
-			streamLinks = append(streamLinks, url)
-		}
+	for _, match := range hrefRegex.FindAllStringSubmatch(pageContent, -1) {
+		add(match[1], "href")
 	}
 
 	// 方法5: 在JavaScript代码中查找URL模式
-	jsURLRegex := regexp.MustCompile(`[\"'](https?://[^\"']+(?:\.m3u8|\.m3u|/live/|/hls/)[^\"']*)[\"']`)
-	jsURLMatches := jsURLRegex.FindAllStringSubmatch(pageContent, -1)
-	for _, match := range jsURLMatches {
-		url := match[1]
-		if isValidStreamURL(url) {
-			streamLinks = append(streamLinks, url)
-		}
+	for _, match := range jsURLRegex.FindAllStringSubmatch(pageContent, -1) {
+		add(match[1], "script")
 	}
 
-	fmt.Printf("本页找到 %d 个流媒体链接\n", len(streamLinks))
-	return streamLinks, nil
+	return links
 }
 
 // extractURLFromContext 从给定元素的上下文中提取URL
@@ -104,16 +119,7 @@ func extractURLFromContext(pageContent, elementHTML string) string {
 	contextText := pageContent[start:end]
 
 	// 在上下文中查找各种URL模式
-	urlPatterns := []string{
-		`onclick\s*=\s*[\"']?[a-zA-Z0-9_]+\s*\(\s*[\"']([^\"']+)[\"']`,
-		`data-(?:url|link|stream)\s*=\s*[\"']([^\"']+)[\"']`,
-		`href\s*=\s*[\"']([^\"']+)[\"']`,
-		`src\s*=\s*[\"']([^\"']+)[\"']`,
-		`[\"'](https?://[^\"'\s]+)[\"']`,
-	}
-
-	for _, pattern := range urlPatterns {
-		regex := regexp.MustCompile(pattern)
+	for _, regex := range contextURLRegexes {
 		matches := regex.FindAllStringSubmatch(contextText, -1)
 		for _, match := range matches {
 			if len(match) > 1 && isValidStreamURL(match[1]) {
@@ -160,6 +166,7 @@ func isValidStreamURL(url string) bool {
 	// 明确的流媒体链接特征 - 优先级高
 	if strings.Contains(lowerURL, ".m3u8") || strings.Contains(lowerURL, ".m3u") ||
 		strings.Contains(lowerURL, ".ts") || strings.Contains(lowerURL, ".m4s") ||
+		strings.Contains(lowerURL, ".mpd") || strings.Contains(lowerURL, "/dash/") ||
 		strings.Contains(lowerURL, "/live/") || strings.Contains(lowerURL, "/hls/") ||
 		strings.Contains(lowerURL, "udp://") || strings.Contains(lowerURL, "rtmp://") ||
 		strings.Contains(lowerURL, "rtsp://") {
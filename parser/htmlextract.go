@@ -0,0 +1,259 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// PageLink 是从搜索页面中提取出的一个流媒体链接
+type PageLink struct {
+	URL      string
+	Element  string // 链接所在的元素，如 "a@href"、"td#text"、"script"
+	Strategy string // 提取方式: "dom" 或 "regex"
+
+	card *html.Node // 链接所属的结果卡片，没有卡片结构时为 nil
+}
+
+// jsStringRegex 匹配 onclick 或脚本中的字符串字面量
+var jsStringRegex = regexp.MustCompile(`["']([^"'\s]+)["']`)
+
+// ExtractStreamLinks 从搜索页面 HTML 中提取流媒体链接，每个链接只返回一次。
+// 优先按 DOM 结构解析结果卡片，找不到任何链接时退回到正则匹配。
+func ExtractStreamLinks(pageContent string) []PageLink {
+	doc, err := html.Parse(strings.NewReader(pageContent))
+	if err == nil {
+		if links := extractLinksFromDOM(doc); len(links) > 0 {
+			return links
+		}
+	}
+	return extractLinksByRegex(pageContent)
+}
+
+// extractLinksFromDOM 遍历 DOM 树提取链接。页面中存在结果卡片时只在卡片内查找，
+// 保证每个链接都归属于正确的卡片。
+func extractLinksFromDOM(doc *html.Node) []PageLink {
+	collector := &linkCollector{seen: make(map[string]bool)}
+
+	cards := findResultCards(doc)
+	if len(cards) == 0 {
+		collector.walk(doc, nil)
+		return collector.links
+	}
+
+	for _, card := range cards {
+		collector.walk(card, card)
+	}
+	return collector.links
+}
+
+type linkCollector struct {
+	links []PageLink
+	seen  map[string]bool
+}
+
+func (c *linkCollector) add(rawURL, element string, card *html.Node) {
+	u := strings.TrimSpace(html.UnescapeString(rawURL))
+	if u == "" || c.seen[u] || !isValidStreamURL(u) {
+		return
+	}
+	c.seen[u] = true
+	c.links = append(c.links, PageLink{URL: u, Element: element, Strategy: "dom", card: card})
+}
+
+func (c *linkCollector) walk(n *html.Node, card *html.Node) {
+	switch n.Type {
+	case html.ElementNode:
+		for _, attr := range n.Attr {
+			key := strings.ToLower(attr.Key)
+			element := n.Data + "@" + key
+			switch {
+			case key == "data-url" || key == "data-link" || key == "data-stream":
+				c.add(attr.Val, element, card)
+			case key == "href" || key == "src":
+				c.add(attr.Val, element, card)
+			case key == "onclick":
+				for _, m := range jsStringRegex.FindAllStringSubmatch(attr.Val, -1) {
+					c.add(m[1], element, card)
+				}
+			}
+		}
+		if n.Data == "script" {
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				if child.Type == html.TextNode {
+					for _, m := range jsURLRegex.FindAllStringSubmatch(child.Data, -1) {
+						c.add(m[1], "script", card)
+					}
+				}
+			}
+			return
+		}
+		if n.Data == "style" {
+			return
+		}
+	case html.TextNode:
+		// 很多搜索站点直接把地址作为文本显示在卡片中
+		text := strings.TrimSpace(n.Data)
+		if strings.Contains(text, "://") && !strings.ContainsAny(text, " \t\n") {
+			parent := "text"
+			if n.Parent != nil {
+				parent = n.Parent.Data
+			}
+			c.add(text, parent+"#text", card)
+		}
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(child, card)
+	}
+}
+
+// resultClasses 是搜索结果卡片常用的 class，按完整的 class 名匹配，
+// 避免把 search-results 之类包含所有结果的外层容器当成一张卡片
+var resultClasses = map[string]bool{"result": true, "resultplus": true, "result-item": true, "search-result": true, "result-card": true}
+
+// findResultCards 查找承载单条搜索结果的元素（见 resultClasses），
+// 嵌套时只保留最内层的卡片，外层元素只是结果列表的容器
+func findResultCards(n *html.Node) []*html.Node {
+	cards := []*html.Node{}
+	var visit func(*html.Node) bool
+	visit = func(n *html.Node) bool {
+		found := false
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if visit(child) {
+				found = true
+			}
+		}
+		if !found && n.Type == html.ElementNode && isResultCard(n) {
+			cards = append(cards, n)
+			return true
+		}
+		return found
+	}
+	visit(n)
+	return cards
+}
+
+// isResultCard 判断元素的 class 中是否有结果卡片的名称
+func isResultCard(n *html.Node) bool {
+	for _, name := range strings.Fields(strings.ToLower(attrValue(n, "class"))) {
+		if resultClasses[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func attrValue(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if strings.EqualFold(attr.Key, key) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+var (
+	resolutionRegex = regexp.MustCompile(`(?i)\b(\d{3,4}\s*[x×*]\s*\d{3,4}|\d{3,4}[pi]|[48]k|uhd|fhd)\b`)
+	checkedAtRegex  = regexp.MustCompile(`(?:(\d{4})[-/.])?(\d{1,2})[-/.](\d{1,2})\s+(\d{1,2}):(\d{2})(?::(\d{2}))?`)
+	locationRegex   = regexp.MustCompile(`[\p{Han}]*(?:电信|联通|移动|广电|铁通|教育网)[\p{Han}]*`)
+)
+
+// fillCardMetadata 从结果卡片的文本中提取频道名、分辨率、检测时间和地区/运营商信息
+func fillCardMetadata(result *SearchResult, card *html.Node, now time.Time) {
+	texts := cardTexts(card)
+	joined := strings.Join(texts, " ")
+
+	result.Channel = cardChannel(card, texts)
+	if m := resolutionRegex.FindString(joined); m != "" {
+		result.Resolution = m
+	}
+	if m := checkedAtRegex.FindStringSubmatch(joined); m != nil {
+		result.CheckedAt = parseCheckedAt(m, now)
+	}
+	if m := locationRegex.FindString(joined); m != "" {
+		result.Location = m
+	}
+}
+
+// cardChannel 优先使用 class 中含 "channel" 的元素文本作为频道名，否则取卡片中第一段非链接文本
+func cardChannel(card *html.Node, texts []string) string {
+	var channelNode *html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if channelNode != nil {
+			return
+		}
+		if n.Type == html.ElementNode && strings.Contains(strings.ToLower(attrValue(n, "class")), "channel") {
+			channelNode = n
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			find(child)
+		}
+	}
+	find(card)
+
+	if channelNode != nil {
+		if channelTexts := cardTexts(channelNode); len(channelTexts) > 0 {
+			return channelTexts[0]
+		}
+	}
+	for _, text := range texts {
+		if !checkedAtRegex.MatchString(text) && !resolutionRegex.MatchString(text) {
+			return text
+		}
+	}
+	return ""
+}
+
+// cardTexts 收集元素内所有非空、非链接的文本片段
+func cardTexts(n *html.Node) []string {
+	texts := []string{}
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+			return
+		}
+		if n.Type == html.TextNode {
+			text := strings.TrimSpace(n.Data)
+			if text != "" && !strings.Contains(text, "://") {
+				texts = append(texts, text)
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(n)
+	return texts
+}
+
+// parseCheckedAt 解析 "08-15 12:34" 或 "2024-08-15 12:34:56" 形式的时间。
+// 没有年份时使用当前年份，若结果晚于当前时间则视为去年。
+func parseCheckedAt(m []string, now time.Time) time.Time {
+	year := now.Year()
+	if m[1] != "" {
+		year, _ = strconv.Atoi(m[1])
+	}
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+	hour, _ := strconv.Atoi(m[4])
+	minute, _ := strconv.Atoi(m[5])
+	second := 0
+	if m[6] != "" {
+		second, _ = strconv.Atoi(m[6])
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 {
+		return time.Time{}
+	}
+
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, now.Location())
+	if m[1] == "" && t.After(now.Add(24*time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
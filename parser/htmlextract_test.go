@@ -0,0 +1,228 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// linkSummary 是测试中比较用的 PageLink 字段，card 记录所属卡片的第一段文本
+type linkSummary struct {
+	URL, Element, Strategy, Card string
+}
+
+func summarizeLinks(links []PageLink) []linkSummary {
+	summaries := make([]linkSummary, len(links))
+	for i, link := range links {
+		summaries[i] = linkSummary{URL: link.URL, Element: link.Element, Strategy: link.Strategy}
+		if link.card != nil {
+			if texts := cardTexts(link.card); len(texts) > 0 {
+				summaries[i].Card = texts[0]
+			}
+		}
+	}
+	return summaries
+}
+
+func TestExtractStreamLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []linkSummary
+	}{
+		{
+			name: "cards inside a results wrapper",
+			html: `<div class="search-results">
+				<div class="result"><div class="channel">CCTV1</div><a href="http://1.1.1.1:8080/live/cctv1.m3u8">播放</a></div>
+				<div class="result"><div class="channel">CCTV2</div><a href="http://2.2.2.2:8080/live/cctv2.m3u8">播放</a></div>
+			</div>`,
+			want: []linkSummary{
+				{"http://1.1.1.1:8080/live/cctv1.m3u8", "a@href", "dom", "CCTV1"},
+				{"http://2.2.2.2:8080/live/cctv2.m3u8", "a@href", "dom", "CCTV2"},
+			},
+		},
+		{
+			name: "innermost of nested cards",
+			html: `<div class="result">
+				<div class="result-item"><span>湖南卫视</span><a href="http://3.3.3.3/hls/hunan.m3u8">x</a></div>
+				<div class="result-item"><span>浙江卫视</span><a href="http://4.4.4.4/hls/zj.m3u8">x</a></div>
+			</div>`,
+			want: []linkSummary{
+				{"http://3.3.3.3/hls/hunan.m3u8", "a@href", "dom", "湖南卫视"},
+				{"http://4.4.4.4/hls/zj.m3u8", "a@href", "dom", "浙江卫视"},
+			},
+		},
+		{
+			name: "links outside cards are ignored",
+			html: `<a href="http://ads.example.com/live/ad.m3u8">广告</a>
+				<div class="resultplus"><b>CCTV5</b><tba>http://5.5.5.5:9901/tsfile/live/0005_1.m3u8</tba></div>`,
+			want: []linkSummary{
+				{"http://5.5.5.5:9901/tsfile/live/0005_1.m3u8", "tba#text", "dom", "CCTV5"},
+			},
+		},
+		{
+			name: "onclick, data-url and script",
+			html: `<ul>
+				<li onclick="play('http://6.6.6.6/live/a.m3u8', 1)">A</li>
+				<li data-url="rtmp://7.7.7.7/live/b">B</li>
+				<li><script>var u = "http://8.8.8.8/hls/c.m3u8";</script></li>
+				<li><img src="http://example.com/logo.png"></li>
+			</ul>`,
+			want: []linkSummary{
+				{"http://6.6.6.6/live/a.m3u8", "li@onclick", "dom", ""},
+				{"rtmp://7.7.7.7/live/b", "li@data-url", "dom", ""},
+				{"http://8.8.8.8/hls/c.m3u8", "script", "dom", ""},
+			},
+		},
+		{
+			name: "duplicates are returned once",
+			html: `<a href="http://9.9.9.9/live/d.m3u8">d</a><span data-url="http://9.9.9.9/live/d.m3u8">d</span>`,
+			want: []linkSummary{
+				{"http://9.9.9.9/live/d.m3u8", "a@href", "dom", ""},
+			},
+		},
+		{
+			name: "regex fallback when the DOM has no links",
+			html: `<p>暂无结果</p><!-- <a href="http://10.0.0.1/live/e.m3u8">e</a> -->`,
+			want: []linkSummary{
+				{"http://10.0.0.1/live/e.m3u8", "href", "regex", ""},
+			},
+		},
+		{
+			name: "no links",
+			html: `<p>暂无结果</p>`,
+			want: []linkSummary{},
+		},
+	}
+	for _, tt := range tests {
+		got := summarizeLinks(ExtractStreamLinks(tt.html))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindResultCards(t *testing.T) {
+	tests := []struct {
+		html string
+		want int
+	}{
+		{`<div class="search-results"><div class="result">a</div><div class="result">b</div></div>`, 2},
+		{`<div class="result"><div class="result-title">a</div></div>`, 1},
+		{`<div class="results"><p>a</p></div>`, 0},
+		{`<div class="item Result">a</div><div class="result-card">b</div>`, 2},
+	}
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(tt.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(findResultCards(doc)); got != tt.want {
+			t.Errorf("findResultCards(%s) found %d cards, want %d", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestFillCardMetadata(t *testing.T) {
+	now := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	page := `<div class="search-results">
+		<div class="result"><div class="channel">CCTV1</div><span>1080p</span><i>12-31 23:00</i><i>北京联通</i>
+			<a href="http://1.1.1.1:8080/live/cctv1.m3u8">播放</a></div>
+		<div class="result"><b>CCTV2</b><span>720p</span><i>2025-01-02 08:30:15</i><i>广东电信</i>
+			<a href="http://2.2.2.2:8080/live/cctv2.m3u8">播放</a></div>
+		<div class="result"><a href="http://3.3.3.3:8080/live/cctv3.m3u8">CCTV3</a></div>
+	</div>`
+	want := []SearchResult{
+		{URL: "http://1.1.1.1:8080/live/cctv1.m3u8", Channel: "CCTV1", Resolution: "1080p",
+			CheckedAt: time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC), Location: "北京联通"},
+		{URL: "http://2.2.2.2:8080/live/cctv2.m3u8", Channel: "CCTV2", Resolution: "720p",
+			CheckedAt: time.Date(2025, 1, 2, 8, 30, 15, 0, time.UTC), Location: "广东电信"},
+		{URL: "http://3.3.3.3:8080/live/cctv3.m3u8", Channel: "CCTV3"},
+	}
+
+	links := ExtractStreamLinks(page)
+	if len(links) != len(want) {
+		t.Fatalf("got %d links, want %d", len(links), len(want))
+	}
+	for i, link := range links {
+		got := SearchResult{URL: link.URL}
+		fillCardMetadata(&got, link.card, now)
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("link %d:\n got %+v\nwant %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestResolutionRegex(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"1080p", "1080p"},
+		{"1920x1080", "1920x1080"},
+		{"1280 × 720", "1280 × 720"},
+		{"画质 1080P超清", "1080P"},
+		{"576i", "576i"},
+		{"4K", "4K"},
+		{"FHD", "FHD"},
+		{"uhd 频道", "uhd"},
+		{"CCTV4K", ""},
+		{"10800p", ""},
+		{"高清", ""},
+	}
+	for _, tt := range tests {
+		if got := resolutionRegex.FindString(tt.text); got != tt.want {
+			t.Errorf("resolutionRegex.FindString(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseCheckedAt(t *testing.T) {
+	now := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		{"2024-08-15 12:34:56", time.Date(2024, 8, 15, 12, 34, 56, 0, time.UTC)},
+		{"2024/08/15 12:34", time.Date(2024, 8, 15, 12, 34, 0, 0, time.UTC)},
+		{"01-02 09:15", time.Date(2025, 1, 2, 9, 15, 0, 0, time.UTC)},
+		// 没有年份且晚于当前时间超过一天时视为去年
+		{"12-31 23:00", time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC)},
+		{"1.3 08:00", time.Date(2025, 1, 3, 8, 0, 0, 0, time.UTC)},
+		{"01-05 08:00", time.Date(2024, 1, 5, 8, 0, 0, 0, time.UTC)},
+		{"13-01 08:00", time.Time{}},
+		{"02-30 25:00", time.Time{}},
+	}
+	for _, tt := range tests {
+		m := checkedAtRegex.FindStringSubmatch(tt.text)
+		if m == nil {
+			t.Errorf("checkedAtRegex did not match %q", tt.text)
+			continue
+		}
+		if got := parseCheckedAt(m, now); !got.Equal(tt.want) {
+			t.Errorf("parseCheckedAt(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+	if checkedAtRegex.MatchString("CCTV-13") {
+		t.Error("checkedAtRegex matched a channel name")
+	}
+}
+
+func TestLocationRegex(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"北京联通", "北京联通"},
+		{"来源: 广东省广州市电信", "广东省广州市电信"},
+		{"江苏移动 IPTV", "江苏移动"},
+		{"有线广电", "有线广电"},
+		{"CCTV1 1080p", ""},
+	}
+	for _, tt := range tests {
+		if got := locationRegex.FindString(tt.text); got != tt.want {
+			t.Errorf("locationRegex.FindString(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
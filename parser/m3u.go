@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"m3u8_selector/core"
+)
+
+// ParseM3U 解析 M3U/M3U8 频道播放列表（#EXTM3U / #EXTINF），
+// 保留 tvg-id、tvg-name、tvg-logo、group-title 等属性。base 用于解析相对地址。
+func ParseM3U(r io.Reader, base string) ([]core.Candidate, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	candidates := []core.Candidate{}
+	var pending *core.StreamMeta // 上一行 #EXTINF 中的信息，等待对应的 URL 行
+	group := ""                  // #EXTGRP 指定的分组，作用于下一个条目
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			meta := parseEXTINF(line[len("#EXTINF:"):])
+			pending = &meta
+		case strings.HasPrefix(line, "#EXTGRP:"):
+			group = strings.TrimSpace(line[len("#EXTGRP:"):])
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION"), strings.HasPrefix(line, "#EXT-X-STREAM-INF"):
+			return nil, fmt.Errorf("第 %d 行: 这是一个 HLS 媒体播放列表，请直接测试该地址", lineNo)
+		case strings.HasPrefix(line, "#"):
+			// 其他指令（#EXTM3U、#EXTVLCOPT 等）忽略
+		default:
+			meta := core.StreamMeta{}
+			if pending != nil {
+				meta = *pending
+			}
+			if meta.Group == "" {
+				meta.Group = group
+			}
+			candidates = append(candidates, core.Candidate{
+				URL:  resolvePlaylistURL(base, line),
+				Meta: meta,
+			})
+			pending = nil
+			group = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取播放列表失败: %v", err)
+	}
+
+	return candidates, nil
+}
+
+// parseEXTINF 解析 #EXTINF: 之后的内容，格式为 `时长 key="value" ...,频道名`
+func parseEXTINF(s string) core.StreamMeta {
+	meta := core.StreamMeta{}
+
+	// 找到属性之后的第一个逗号（引号内的逗号不算）
+	inQuote := false
+	titleStart := -1
+	for i, ch := range s {
+		if ch == '"' {
+			inQuote = !inQuote
+		} else if ch == ',' && !inQuote {
+			titleStart = i
+			break
+		}
+	}
+
+	attrPart := s
+	if titleStart >= 0 {
+		attrPart = s[:titleStart]
+		meta.Channel = strings.TrimSpace(s[titleStart+1:])
+	}
+
+	for key, value := range parseAttributes(attrPart) {
+		switch key {
+		case "tvg-id":
+			meta.TvgID = value
+		case "tvg-name":
+			meta.TvgName = value
+		case "tvg-logo":
+			meta.TvgLogo = value
+		case "group-title":
+			meta.Group = value
+		}
+	}
+	if meta.Channel == "" {
+		meta.Channel = meta.TvgName
+	}
+	return meta
+}
+
+// parseAttributes 解析 key="value" 形式的属性列表，key 统一转为小写
+func parseAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			return attrs
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		if sp := strings.LastIndexAny(key, " \t"); sp >= 0 {
+			key = key[sp+1:]
+		}
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				value, s = s, ""
+			} else {
+				value, s = s[:end], s[end:]
+			}
+		}
+		attrs[key] = value
+	}
+}
+
+// resolvePlaylistURL 将播放列表中的相对地址按播放列表所在位置解析，
+// 只有播放列表本身来自 HTTP 时才需要解析。绝对地址原样返回，无法解析的地址也原样返回
+func resolvePlaylistURL(base, ref string) string {
+	if strings.Contains(ref, "://") || !strings.HasPrefix(base, "http") {
+		return ref
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// ReadPlaylist 读取本地文件或 HTTP(S) 地址的播放列表内容
+func ReadPlaylist(location string, client *http.Client) ([]byte, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		resp, err := client.Get(location)
+		if err != nil {
+			return nil, fmt.Errorf("下载播放列表失败: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("下载播放列表失败: %d %s", resp.StatusCode, resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}
+
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return nil, fmt.Errorf("读取播放列表失败: %v", err)
+	}
+	return data, nil
+}
+
+// ImportPlaylist 从本地文件或 URL 导入播放列表，自动识别 M3U 与 DIYP/TVBox 文本格式，
+// 返回带元数据的候选源
+func ImportPlaylist(location string, client *http.Client) ([]core.Candidate, error) {
+	data, err := ReadPlaylist(location, client)
+	if err != nil {
+		return nil, err
+	}
+	content, err := decodePlaylistText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []core.Candidate
+	if isM3UPlaylist(content) {
+		candidates, err = ParseM3U(strings.NewReader(content), location)
+	} else {
+		candidates, err = ParseTXTPlaylist(strings.NewReader(content))
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		candidates[i].Meta.Source = location
+	}
+	return candidates, nil
+}
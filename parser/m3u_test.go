@@ -0,0 +1,24 @@
+package parser
+
+import "testing"
+
+func TestResolvePlaylistURL(t *testing.T) {
+	const base = "http://example.com/lists/tv.m3u?path=a/b/c"
+	tests := []struct {
+		base, ref, want string
+	}{
+		{base, "cctv1.m3u8", "http://example.com/lists/cctv1.m3u8"},
+		{base, "../live/cctv1.m3u8", "http://example.com/live/cctv1.m3u8"},
+		{base, "/live/cctv1.m3u8?token=1", "http://example.com/live/cctv1.m3u8?token=1"},
+		{base, "//cdn.example.net/cctv1.m3u8", "http://cdn.example.net/cctv1.m3u8"},
+		{"https://example.com", "cctv1.m3u8", "https://example.com/cctv1.m3u8"},
+		{base, "rtp://239.3.1.1:8000", "rtp://239.3.1.1:8000"},
+		{base, "http://other.example.org/a.m3u8", "http://other.example.org/a.m3u8"},
+		{"/home/user/tv.m3u", "cctv1.m3u8", "cctv1.m3u8"},
+	}
+	for _, tt := range tests {
+		if got := resolvePlaylistURL(tt.base, tt.ref); got != tt.want {
+			t.Errorf("resolvePlaylistURL(%q, %q) = %q, want %q", tt.base, tt.ref, got, tt.want)
+		}
+	}
+}
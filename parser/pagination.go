@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// pageParamNames 是常见的分页查询参数名
+var pageParamNames = []string{"page", "p", "pg", "pageno", "page_no"}
+
+// pagerClasses 是分页容器常用的 class 或 id，按完整的 class 名匹配，
+// 避免把 page-header、homepage 之类的元素当成分页控件
+var pagerClasses = map[string]bool{"pagination": true, "pager": true, "page-nav": true, "pages": true}
+
+// DetectLastPage 从页面的分页控件中识别最后一页的页码，无法识别时返回 0。
+// 分页链接的 href 中带有页码参数，或位于分页容器（见 pagerClasses）中且文本为数字。
+func DetectLastPage(pageContent string) int {
+	doc, err := html.Parse(strings.NewReader(pageContent))
+	if err != nil {
+		return 0
+	}
+
+	lastPage := 0
+	var visit func(n *html.Node, inPager bool)
+	visit = func(n *html.Node, inPager bool) {
+		if n.Type == html.ElementNode {
+			if isPager(n) {
+				inPager = true
+			}
+
+			if n.Data == "a" {
+				if page := pageFromHref(attrValue(n, "href")); page > lastPage {
+					lastPage = page
+				}
+			}
+			if inPager && (n.Data == "a" || n.Data == "span" || n.Data == "li" || n.Data == "b" || n.Data == "strong") {
+				if page, err := strconv.Atoi(strings.TrimSpace(nodeText(n))); err == nil && page > lastPage {
+					lastPage = page
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			visit(child, inPager)
+		}
+	}
+	visit(doc, false)
+
+	return lastPage
+}
+
+// isPager 判断元素的 class 或 id 中是否有分页容器的名称
+func isPager(n *html.Node) bool {
+	for _, name := range strings.Fields(strings.ToLower(attrValue(n, "class") + " " + attrValue(n, "id"))) {
+		if pagerClasses[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// pageFromHref 从链接地址的查询参数中取出页码
+func pageFromHref(href string) int {
+	if href == "" {
+		return 0
+	}
+	u, err := url.Parse(html.UnescapeString(href))
+	if err != nil {
+		return 0
+	}
+	query := u.Query()
+	for _, name := range pageParamNames {
+		if value := query.Get(name); value != "" {
+			if page, err := strconv.Atoi(value); err == nil && page > 0 {
+				return page
+			}
+		}
+	}
+	return 0
+}
+
+// nodeText 返回元素内的全部文本
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(n)
+	return sb.String()
+}
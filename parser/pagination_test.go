@@ -0,0 +1,25 @@
+package parser
+
+import "testing"
+
+func TestDetectLastPage(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want int
+	}{
+		{"pagination list", `<ul class="pagination"><li>1</li><li><a href="#">2</a></li><li><a href="#">7</a></li></ul>`, 7},
+		{"pager id", `<div id="pager"><span>1</span><a>2</a><b>3</b></div>`, 3},
+		{"page-nav among other classes", `<nav class="nav page-nav clearfix"><a>1</a><a>12</a></nav>`, 12},
+		{"pages", `<div class="pages"><strong>5</strong></div>`, 5},
+		{"page number in href", `<a href="/search?q=cctv&amp;page=9">下一页</a>`, 9},
+		{"numbers outside pager are ignored", `<div class="page-header"><span>2024</span></div><div class="homepage"><li>88</li></div>`, 0},
+		{"class containing pager is not a pager", `<div class="pagerank"><a>40</a></div>`, 0},
+		{"no pagination", `<p>1</p>`, 0},
+	}
+	for _, tt := range tests {
+		if got := DetectLastPage(tt.html); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
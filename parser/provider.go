@@ -0,0 +1,243 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"m3u8_selector/core"
+)
+
+// SearchResult 表示搜索源返回的一条候选直播源及页面上显示的相关信息
+type SearchResult struct {
+	URL        string
+	Channel    string    // 频道名称
+	Resolution string    // 页面标注的分辨率，如 "1080p"
+	CheckedAt  time.Time // 页面显示的检测时间，未知时为零值
+	Location   string    // 地区/运营商信息
+	Element    string    // 链接所在的页面元素
+	Provider   string    // 返回该结果的搜索源名称
+	Page       int       // 结果所在的分页
+}
+
+// Candidate 将搜索结果转换为待测试的候选源
+func (r SearchResult) Candidate() core.Candidate {
+	return core.Candidate{
+		URL: r.URL,
+		Meta: core.StreamMeta{
+			Channel:    r.Channel,
+			Resolution: r.Resolution,
+			CheckedAt:  r.CheckedAt,
+			Location:   r.Location,
+			Source:     r.Provider,
+		},
+	}
+}
+
+// SearchPage 是搜索源返回的一页结果
+type SearchPage struct {
+	Results  []SearchResult
+	LastPage int // 从分页控件识别出的最后一页，无法识别时为 0
+}
+
+// SearchProvider 是直播源搜索站点的抽象，按关键词和页码返回候选链接
+type SearchProvider interface {
+	Name() string
+	Search(keyword string, page int, client *http.Client) (*SearchPage, error)
+}
+
+var providers = map[string]SearchProvider{}
+
+// RegisterProvider 注册一个搜索源，名称重复时 panic
+func RegisterProvider(p SearchProvider) {
+	name := strings.ToLower(p.Name())
+	if _, exists := providers[name]; exists {
+		panic(fmt.Sprintf("search provider %q already registered", name))
+	}
+	providers[name] = p
+}
+
+// ProviderNames 返回所有已注册搜索源的名称（按字母排序）
+func ProviderNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveProviders 解析逗号分隔的搜索源列表，"all" 表示全部已注册的搜索源
+func ResolveProviders(spec string) ([]SearchProvider, error) {
+	if strings.TrimSpace(spec) == "all" {
+		result := []SearchProvider{}
+		for _, name := range ProviderNames() {
+			result = append(result, providers[name])
+		}
+		return result, nil
+	}
+
+	result := []SearchProvider{}
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		p, ok := providers[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的搜索源: %s (可用: %s)", name, strings.Join(ProviderNames(), ", "))
+		}
+		seen[name] = true
+		result = append(result, p)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("未指定搜索源")
+	}
+	return result, nil
+}
+
+// SearchAll 在每个搜索源中搜索关键词，并按 URL 合并去重。
+// 分页控件决定实际页数，maxPages 只是上限。分页控件只显示当前页附近的页码时，
+// 后续页面识别到更大的页码会继续翻页。每批并发抓取 workers 页，
+// 某一页没有带来新的唯一链接时停止翻页。所有搜索源共享 workers 个并发名额。
+// 搜索进度输出到 progress，为 nil 时不输出。
+func SearchAll(searchProviders []SearchProvider, keyword string, maxPages int, client *http.Client, workers int, progress io.Writer) []SearchResult {
+	if workers < 1 {
+		workers = 1
+	}
+	if progress == nil {
+		progress = io.Discard
+	}
+	semaphore := make(chan struct{}, workers)
+
+	providerResults := make([][]SearchResult, len(searchProviders))
+	var wg sync.WaitGroup
+	for i, p := range searchProviders {
+		wg.Add(1)
+		go func(index int, p SearchProvider) {
+			defer wg.Done()
+			providerResults[index] = searchProvider(p, keyword, maxPages, client, workers, semaphore, progress)
+		}(i, p)
+	}
+	wg.Wait()
+
+	allResults := []SearchResult{}
+	for _, results := range providerResults {
+		allResults = append(allResults, results...)
+	}
+	return RemoveDuplicateResults(allResults)
+}
+
+// searchProvider 在单个搜索源中翻页搜索
+func searchProvider(p SearchProvider, keyword string, maxPages int, client *http.Client, batchSize int, semaphore chan struct{}, progress io.Writer) []SearchResult {
+	fetch := func(page int) (*SearchPage, error) {
+		semaphore <- struct{}{}
+		defer func() { <-semaphore }()
+
+		fmt.Fprintf(progress, "[%s] 正在搜索第 %d 页\n", p.Name(), page)
+		result, err := p.Search(keyword, page, client)
+		if err == nil {
+			fmt.Fprintf(progress, "[%s] 第 %d 页找到 %d 个流媒体链接\n", p.Name(), page, len(result.Results))
+		}
+		return result, err
+	}
+
+	first, err := fetch(1)
+	if err != nil {
+		fmt.Fprintf(progress, "[%s] 第 1 页搜索失败: %v\n", p.Name(), err)
+		return nil
+	}
+
+	lastPage := maxPages
+	if first.LastPage > 0 && first.LastPage < lastPage {
+		lastPage = first.LastPage
+	}
+	fmt.Fprintf(progress, "[%s] 共需搜索 %d 页 (识别到的最后一页: %d, 上限: %d)\n", p.Name(), lastPage, first.LastPage, maxPages)
+
+	seen := make(map[string]bool)
+	results := []SearchResult{}
+	// addPage 合并一页结果，返回其中新的唯一链接数量
+	addPage := func(page *SearchPage) int {
+		added := 0
+		for _, r := range page.Results {
+			if !seen[r.URL] {
+				seen[r.URL] = true
+				results = append(results, r)
+				added++
+			}
+		}
+		return added
+	}
+
+	if addPage(first) == 0 {
+		return results
+	}
+
+	// 上一批可能因为当时的 lastPage 而不足 batchSize 页，下一批从其后一页开始
+	for start, end := 2, 1; start <= lastPage; start = end + 1 {
+		end = min(start+batchSize-1, lastPage)
+
+		pages := make([]*SearchPage, end-start+1)
+		errs := make([]error, len(pages))
+		var wg sync.WaitGroup
+		for page := start; page <= end; page++ {
+			wg.Add(1)
+			go func(page int) {
+				defer wg.Done()
+				pages[page-start], errs[page-start] = fetch(page)
+			}(page)
+		}
+		wg.Wait()
+
+		// 按页码顺序合并，遇到没有新链接的页面即停止
+		for i, page := range pages {
+			if errs[i] != nil {
+				fmt.Fprintf(progress, "[%s] 第 %d 页搜索失败: %v\n", p.Name(), start+i, errs[i])
+				continue
+			}
+			if addPage(page) == 0 {
+				fmt.Fprintf(progress, "[%s] 第 %d 页没有新的链接，停止翻页\n", p.Name(), start+i)
+				return results
+			}
+			if page.LastPage > lastPage && lastPage < maxPages {
+				lastPage = min(page.LastPage, maxPages)
+				fmt.Fprintf(progress, "[%s] 第 %d 页识别到更多分页，共需搜索 %d 页\n", p.Name(), start+i, lastPage)
+			}
+		}
+	}
+
+	return results
+}
+
+// RemoveDuplicateResults 按 URL 去重，保留最先出现的结果
+func RemoveDuplicateResults(results []SearchResult) []SearchResult {
+	seen := make(map[string]bool)
+	unique := []SearchResult{}
+
+	for _, r := range results {
+		if !seen[r.URL] {
+			seen[r.URL] = true
+			unique = append(unique, r)
+		}
+	}
+	return unique
+}
+
+// RemoveDuplicateCandidates 按 URL 去重，保留最先出现的候选源
+func RemoveDuplicateCandidates(candidates []core.Candidate) []core.Candidate {
+	seen := make(map[string]bool)
+	unique := []core.Candidate{}
+
+	for _, c := range candidates {
+		if !seen[c.URL] {
+			seen[c.URL] = true
+			unique = append(unique, c)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// windowedProvider 模拟分页控件只显示当前页前后各两页的搜索站点，共 total 页，每页一个不同的链接
+type windowedProvider struct {
+	total int
+
+	mu      sync.Mutex
+	fetched []int
+}
+
+func (p *windowedProvider) Name() string {
+	return "windowed"
+}
+
+func (p *windowedProvider) Search(keyword string, page int, client *http.Client) (*SearchPage, error) {
+	p.mu.Lock()
+	p.fetched = append(p.fetched, page)
+	p.mu.Unlock()
+
+	var pager strings.Builder
+	pager.WriteString(`<div class="pagination">`)
+	for n := max(1, page-2); n <= min(p.total, page+2); n++ {
+		fmt.Fprintf(&pager, `<a href="#">%d</a>`, n)
+	}
+	pager.WriteString(`<a href="#">下一页</a></div>`)
+
+	return &SearchPage{
+		Results:  []SearchResult{{URL: fmt.Sprintf("http://example.com/live/%d.m3u8", page)}},
+		LastPage: DetectLastPage(pager.String()),
+	}, nil
+}
+
+func (p *windowedProvider) fetchedPages() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pages := append([]int(nil), p.fetched...)
+	sort.Ints(pages)
+	return pages
+}
+
+func pageRange(n int) []int {
+	pages := make([]int, n)
+	for i := range pages {
+		pages[i] = i + 1
+	}
+	return pages
+}
+
+func TestSearchAllWindowedPager(t *testing.T) {
+	tests := []struct {
+		total, maxPages, workers int
+		want                     int
+	}{
+		{total: 12, maxPages: 20, workers: 1, want: 12},
+		{total: 12, maxPages: 20, workers: 4, want: 12},
+		{total: 12, maxPages: 8, workers: 3, want: 8},
+		{total: 2, maxPages: 20, workers: 2, want: 2},
+	}
+	for _, tt := range tests {
+		p := &windowedProvider{total: tt.total}
+		results := SearchAll([]SearchProvider{p}, "cctv", tt.maxPages, nil, tt.workers, nil)
+		if len(results) != tt.want {
+			t.Errorf("total %d, max %d, workers %d: got %d results, want %d", tt.total, tt.maxPages, tt.workers, len(results), tt.want)
+		}
+		if got := p.fetchedPages(); !reflect.DeepEqual(got, pageRange(tt.want)) {
+			t.Errorf("total %d, max %d, workers %d: fetched pages %v", tt.total, tt.maxPages, tt.workers, got)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostRateLimiter 为每个主机维护一个令牌桶，限制对同一站点的请求频率
+type HostRateLimiter struct {
+	rate  float64 // 每秒补充的令牌数
+	burst float64 // 桶容量
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time // 收到 Retry-After 后在此之前不再发放令牌
+}
+
+// NewHostRateLimiter 创建每个主机每秒 rate 个请求、最多突发 burst 个请求的限速器
+func NewHostRateLimiter(rate float64, burst int) *HostRateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &HostRateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// reserve 取走一个令牌并返回需要等待的时间
+func (l *HostRateLimiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[host] = b
+	}
+
+	if l.rate > 0 {
+		b.tokens += now.Sub(b.last).Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+	} else {
+		b.tokens = l.burst
+	}
+	b.last = now
+	b.tokens--
+
+	var wait time.Duration
+	if b.tokens < 0 && l.rate > 0 {
+		wait = time.Duration(-b.tokens / l.rate * float64(time.Second))
+	}
+	if pause := b.pausedUntil.Sub(now); pause > wait {
+		wait = pause
+	}
+	return wait
+}
+
+// Wait 阻塞直到该主机有可用令牌，或 done 被关闭（返回 false）
+func (l *HostRateLimiter) Wait(host string, done <-chan struct{}) bool {
+	wait := l.reserve(host)
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// Pause 让该主机在 d 时间内不再发放令牌，用于处理 429/503 和 Retry-After
+func (l *HostRateLimiter) Pause(host string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: time.Now()}
+		l.buckets[host] = b
+	}
+	until := time.Now().Add(d)
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// RateLimitedTransport 按主机限速发送请求，遇到 429/503 时按 Retry-After 或指数退避重试
+type RateLimitedTransport struct {
+	Base        http.RoundTripper
+	Limiter     *HostRateLimiter
+	MaxRetries  int
+	BaseBackoff time.Duration // 第一次重试的退避时间，之后每次翻倍
+	MaxBackoff  time.Duration // 单次等待的上限（包括 Retry-After）
+}
+
+func (t *RateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	host := req.URL.Host
+
+	for attempt := 0; ; attempt++ {
+		if t.Limiter != nil && !t.Limiter.Wait(host, req.Context().Done()) {
+			return nil, req.Context().Err()
+		}
+
+		resp, err := base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+		// 只重试可以安全重放的请求
+		canReplay := req.Body == nil || req.GetBody != nil
+		if !retryable || attempt >= t.MaxRetries || !canReplay {
+			return resp, nil
+		}
+
+		wait := t.backoff(attempt)
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			wait = retryAfter
+		}
+		if t.MaxBackoff > 0 && wait > t.MaxBackoff {
+			wait = t.MaxBackoff
+		}
+		resp.Body.Close()
+
+		if t.Limiter != nil {
+			t.Limiter.Pause(host, wait)
+		} else {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			}
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+func (t *RateLimitedTransport) backoff(attempt int) time.Duration {
+	backoff := t.BaseBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	return backoff << uint(attempt)
+}
+
+// parseRetryAfter 解析 Retry-After 头，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// NewRateLimitedClient 创建按主机限速、自动处理 429/503 重试的搜索用 HTTP 客户端。
+// timeout 限制单次请求等待响应头的时间，整个请求（含重试）另有总时长上限。
+func NewRateLimitedClient(timeout time.Duration, ratePerSecond float64, burst int) *http.Client {
+	transport := &RateLimitedTransport{
+		Base: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: timeout}).DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConnsPerHost:   4,
+		},
+		Limiter:     NewHostRateLimiter(ratePerSecond, burst),
+		MaxRetries:  3,
+		BaseBackoff: 2 * time.Second,
+		MaxBackoff:  30 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout*time.Duration(transport.MaxRetries+1) + transport.MaxBackoff*time.Duration(transport.MaxRetries),
+	}
+}
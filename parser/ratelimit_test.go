@@ -0,0 +1,267 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 120 * time.Second, true},
+		{" 5 ", 5 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{"Mon, 01 Jan 2024 12:01:00 GMT", time.Minute, true},
+		{"Monday, 01-Jan-24 12:00:10 GMT", 10 * time.Second, true},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+		{"", 0, false},
+		{"soon", 0, false},
+		{"1.5", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHostRateLimiterPerHost(t *testing.T) {
+	l := NewHostRateLimiter(1, 1)
+	if wait := l.reserve("a.example.com"); wait != 0 {
+		t.Errorf("first request waits %v", wait)
+	}
+	if wait := l.reserve("a.example.com"); wait < 900*time.Millisecond || wait > time.Second {
+		t.Errorf("second request to the same host waits %v, want about 1s", wait)
+	}
+	if wait := l.reserve("b.example.com"); wait != 0 {
+		t.Errorf("first request to another host waits %v", wait)
+	}
+
+	l.Pause("c.example.com", time.Hour)
+	if wait := l.reserve("c.example.com"); wait < 59*time.Minute {
+		t.Errorf("paused host waits %v, want about 1h", wait)
+	}
+	if wait := l.reserve("d.example.com"); wait != 0 {
+		t.Errorf("pausing one host throttles another: waits %v", wait)
+	}
+	// 较短的暂停不会缩短已有的暂停
+	l.Pause("c.example.com", time.Second)
+	if wait := l.reserve("c.example.com"); wait < 59*time.Minute {
+		t.Errorf("shorter pause replaced the longer one: waits %v", wait)
+	}
+}
+
+func TestHostRateLimiterBurst(t *testing.T) {
+	l := NewHostRateLimiter(10, 2)
+	for i := 0; i < 2; i++ {
+		if wait := l.reserve("h"); wait != 0 {
+			t.Errorf("request %d within burst waits %v", i, wait)
+		}
+	}
+	if wait := l.reserve("h"); wait < 90*time.Millisecond || wait > 100*time.Millisecond {
+		t.Errorf("request after burst waits %v, want about 100ms", wait)
+	}
+
+	// rate 为 0 表示不限速
+	unlimited := NewHostRateLimiter(0, 1)
+	for i := 0; i < 5; i++ {
+		if wait := unlimited.reserve("h"); wait != 0 {
+			t.Errorf("unlimited request %d waits %v", i, wait)
+		}
+	}
+}
+
+func TestHostRateLimiterWaitCancel(t *testing.T) {
+	l := NewHostRateLimiter(1, 1)
+	l.Pause("h", time.Hour)
+	done := make(chan struct{})
+	close(done)
+	if l.Wait("h", done) {
+		t.Error("Wait returned true after done was closed")
+	}
+	if !l.Wait("other", done) {
+		t.Error("Wait on an unthrottled host returned false")
+	}
+}
+
+// retryServer 对前 failures 个请求返回 status 和 Retry-After 头，之后返回 200，并记录每次收到的请求体
+type retryServer struct {
+	failures   int
+	status     int
+	retryAfter string
+
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (s *retryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.bodies = append(s.bodies, string(body))
+	n := len(s.bodies)
+	s.mu.Unlock()
+	if n <= s.failures {
+		if s.retryAfter != "" {
+			w.Header().Set("Retry-After", s.retryAfter)
+		}
+		w.WriteHeader(s.status)
+		return
+	}
+	io.WriteString(w, "ok")
+}
+
+func (s *retryServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func TestRateLimitedTransportRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		status     int
+		retryAfter string
+		maxRetries int
+		wantStatus int
+		wantTries  int
+	}{
+		{"429 then success", 2, http.StatusTooManyRequests, "0", 3, http.StatusOK, 3},
+		{"503 then success", 1, http.StatusServiceUnavailable, "", 3, http.StatusOK, 2},
+		{"retries exhausted", 10, http.StatusTooManyRequests, "0", 2, http.StatusTooManyRequests, 3},
+		{"other errors are not retried", 10, http.StatusInternalServerError, "0", 3, http.StatusInternalServerError, 1},
+	}
+	for _, tt := range tests {
+		server := &retryServer{failures: tt.failures, status: tt.status, retryAfter: tt.retryAfter}
+		ts := httptest.NewServer(server)
+		client := &http.Client{Transport: &RateLimitedTransport{
+			Limiter:     NewHostRateLimiter(1000, 10),
+			MaxRetries:  tt.maxRetries,
+			BaseBackoff: time.Millisecond,
+		}}
+
+		resp, err := client.Post(ts.URL, "text/plain", strings.NewReader("keyword=cctv"))
+		if err != nil {
+			ts.Close()
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		resp.Body.Close()
+		ts.Close()
+
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.wantStatus)
+		}
+		bodies := server.requests()
+		if len(bodies) != tt.wantTries {
+			t.Errorf("%s: %d requests, want %d", tt.name, len(bodies), tt.wantTries)
+		}
+		// 每次重试都重放完整的请求体
+		for i, body := range bodies {
+			if body != "keyword=cctv" {
+				t.Errorf("%s: request %d body %q", tt.name, i+1, body)
+			}
+		}
+	}
+}
+
+func TestRateLimitedTransportBodyWithoutGetBody(t *testing.T) {
+	server := &retryServer{failures: 1, status: http.StatusTooManyRequests, retryAfter: "0"}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	transport := &RateLimitedTransport{MaxRetries: 3, BaseBackoff: time.Millisecond}
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, io.NopCloser(strings.NewReader("x")))
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || len(server.requests()) != 1 {
+		t.Errorf("body that cannot be replayed was retried: status %d, %d requests", resp.StatusCode, len(server.requests()))
+	}
+}
+
+func TestRateLimitedTransportMaxBackoff(t *testing.T) {
+	for _, withLimiter := range []bool{false, true} {
+		server := &retryServer{failures: 1, status: http.StatusServiceUnavailable, retryAfter: "3600"}
+		ts := httptest.NewServer(server)
+		transport := &RateLimitedTransport{MaxRetries: 1, BaseBackoff: time.Hour, MaxBackoff: 50 * time.Millisecond}
+		if withLimiter {
+			transport.Limiter = NewHostRateLimiter(1000, 10)
+		}
+
+		start := time.Now()
+		resp, err := (&http.Client{Transport: transport, Timeout: 5 * time.Second}).Get(ts.URL)
+		elapsed := time.Since(start)
+		ts.Close()
+		if err != nil {
+			t.Errorf("limiter %v: %v", withLimiter, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("limiter %v: status %d", withLimiter, resp.StatusCode)
+		}
+		if elapsed < 50*time.Millisecond || elapsed > 2*time.Second {
+			t.Errorf("limiter %v: Retry-After 3600 waited %v, want MaxBackoff 50ms", withLimiter, elapsed)
+		}
+	}
+}
+
+func TestRateLimitedTransportHostIsolation(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") })
+	throttled := httptest.NewServer(ok)
+	defer throttled.Close()
+	other := httptest.NewServer(ok)
+	defer other.Close()
+
+	limiter := NewHostRateLimiter(1000, 10)
+	throttledURL, _ := url.Parse(throttled.URL)
+	limiter.Pause(throttledURL.Host, time.Hour)
+	client := &http.Client{Transport: &RateLimitedTransport{Limiter: limiter}}
+
+	start := time.Now()
+	resp, err := client.Get(other.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request to another host waited %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, throttled.URL, nil)
+	if _, err := client.Do(req); err == nil {
+		t.Error("request to the paused host did not wait")
+	}
+}
+
+func TestRateLimitedTransportBackoff(t *testing.T) {
+	transport := &RateLimitedTransport{}
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if got := transport.backoff(attempt); got != want {
+			t.Errorf("default backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+	transport.BaseBackoff = 500 * time.Millisecond
+	if got := transport.backoff(3); got != 4*time.Second {
+		t.Errorf("backoff(3) = %v, want 4s", got)
+	}
+}
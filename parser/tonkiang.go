@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// TonkiangProvider 通过 tonkiang.us 的 IPTV 搜索页面查找直播源
+type TonkiangProvider struct {
+	BaseURL string
+}
+
+func init() {
+	RegisterProvider(&TonkiangProvider{BaseURL: "http://tonkiang.us/"})
+}
+
+func (p *TonkiangProvider) Name() string {
+	return "tonkiang"
+}
+
+// SearchURL 构造指定关键词和页码的搜索地址
+func (p *TonkiangProvider) SearchURL(keyword string, page int) string {
+	params := url.Values{}
+	params.Add("iptv", keyword)
+	if page > 1 {
+		params.Add("page", fmt.Sprintf("%d", page))
+	}
+	return p.BaseURL + "?" + params.Encode()
+}
+
+func (p *TonkiangProvider) Search(keyword string, page int, client *http.Client) (*SearchPage, error) {
+	searchPage, err := FetchPageContent(p.SearchURL(keyword, page), client)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range searchPage.Results {
+		searchPage.Results[i].Provider = p.Name()
+		searchPage.Results[i].Page = page
+	}
+	return searchPage, nil
+}
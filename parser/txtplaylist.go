@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+
+	"m3u8_selector/core"
+)
+
+// ParseTXTPlaylist 解析 DIYP/TVBox 使用的 "频道名,URL" 文本列表。
+// "分类,#genre#" 行开始一个新分组；一行中的多个地址可以用 "#" 或 "$" 分隔，
+// 地址后面 "$" 引出的非地址文本（如 "$高清"）视为线路备注并忽略。
+func ParseTXTPlaylist(r io.Reader) ([]core.Candidate, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	candidates := []core.Candidate{}
+	group := ""
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		comma := strings.IndexAny(line, ",，")
+		if comma < 0 {
+			// 没有频道名的裸地址
+			for _, u := range splitTXTURLs(line) {
+				candidates = append(candidates, core.Candidate{URL: u, Meta: core.StreamMeta{Group: group}})
+			}
+			continue
+		}
+
+		name := strings.TrimSpace(line[:comma])
+		_, sepSize := utf8.DecodeRuneInString(line[comma:])
+		rest := strings.TrimSpace(line[comma+sepSize:])
+		if strings.EqualFold(rest, "#genre#") {
+			group = name
+			continue
+		}
+
+		for _, u := range splitTXTURLs(rest) {
+			candidates = append(candidates, core.Candidate{
+				URL:  u,
+				Meta: core.StreamMeta{Channel: name, Group: group},
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取播放列表失败: %v", err)
+	}
+
+	return candidates, nil
+}
+
+// splitTXTURLs 按 "#" 和 "$" 拆分一行中的多个地址，只在分隔符后紧跟 "scheme://" 时才拆分，
+// 其余 "$备注" 会被去掉
+func splitTXTURLs(s string) []string {
+	urls := []string{}
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && !((s[i] == '#' || s[i] == '$') && hasURLScheme(s[i+1:])) {
+			continue
+		}
+		piece := s[start:i]
+		if dollar := strings.Index(piece, "$"); dollar >= 0 {
+			piece = piece[:dollar]
+		}
+		if piece = strings.TrimSpace(piece); hasURLScheme(piece) {
+			urls = append(urls, piece)
+		}
+		start = i + 1
+	}
+	return urls
+}
+
+// hasURLScheme 判断字符串是否以 "scheme://" 开头
+func hasURLScheme(s string) bool {
+	sep := strings.Index(s, "://")
+	if sep <= 0 {
+		return false
+	}
+	for i, ch := range s[:sep] {
+		isLetter := (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+		if !isLetter && (i == 0 || !((ch >= '0' && ch <= '9') || ch == '+' || ch == '-' || ch == '.')) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodePlaylistText 将播放列表内容统一转换为 UTF-8：去掉 BOM，
+// 支持 UTF-16 BOM，非法的 UTF-8 内容按 GBK (GB18030) 解码
+func decodePlaylistText(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		decoder := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+		decoded, err := ioutil.ReadAll(transform.NewReader(bytes.NewReader(data), decoder))
+		if err != nil {
+			return "", fmt.Errorf("UTF-16 解码失败: %v", err)
+		}
+		return string(decoded), nil
+	case utf8.Valid(data):
+		return string(data), nil
+	}
+
+	decoded, err := ioutil.ReadAll(transform.NewReader(bytes.NewReader(data), simplifiedchinese.GB18030.NewDecoder()))
+	if err != nil {
+		return "", fmt.Errorf("GBK 解码失败: %v", err)
+	}
+	return string(decoded), nil
+}
+
+// isM3UPlaylist 判断文本内容是否为 M3U 格式
+func isM3UPlaylist(content string) bool {
+	trimmed := strings.TrimSpace(content)
+	return strings.HasPrefix(trimmed, "#EXTM3U") || strings.Contains(content, "#EXTINF")
+}
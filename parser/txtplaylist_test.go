@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+
+	"m3u8_selector/core"
+)
+
+func TestSplitTXTURLs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"http://a.com/1.m3u8", []string{"http://a.com/1.m3u8"}},
+		{"http://a.com/1.m3u8#http://b.com/2.m3u8", []string{"http://a.com/1.m3u8", "http://b.com/2.m3u8"}},
+		{"http://a.com/1.m3u8$rtmp://b.com/live/2", []string{"http://a.com/1.m3u8", "rtmp://b.com/live/2"}},
+		{"http://a.com/1.m3u8$高清#http://b.com/2.m3u8$标清", []string{"http://a.com/1.m3u8", "http://b.com/2.m3u8"}},
+		// 分隔符后不是地址时不拆分
+		{"http://a.com/play.html#top", []string{"http://a.com/play.html#top"}},
+		{"http://a.com/1.m3u8 # http://b.com/2.m3u8", []string{"http://a.com/1.m3u8 # http://b.com/2.m3u8"}},
+		{"rtp://239.3.1.1:8000$组播", []string{"rtp://239.3.1.1:8000"}},
+		{"线路一", []string{}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := splitTXTURLs(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitTXTURLs(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseTXTPlaylist(t *testing.T) {
+	playlist := `// 注释
+http://bare.example.com/live.m3u8
+
+央视频道,#genre#
+CCTV1,http://a.com/cctv1.m3u8#http://b.com/cctv1.m3u8
+CCTV2，http://a.com/cctv2.m3u8$高清
+卫视频道,#Genre#
+湖南卫视,http://a.com/hunan.m3u8$rtmp://b.com/live/hunan
+无效频道,暂无信号
+`
+	want := []core.Candidate{
+		{URL: "http://bare.example.com/live.m3u8"},
+		{URL: "http://a.com/cctv1.m3u8", Meta: core.StreamMeta{Channel: "CCTV1", Group: "央视频道"}},
+		{URL: "http://b.com/cctv1.m3u8", Meta: core.StreamMeta{Channel: "CCTV1", Group: "央视频道"}},
+		{URL: "http://a.com/cctv2.m3u8", Meta: core.StreamMeta{Channel: "CCTV2", Group: "央视频道"}},
+		{URL: "http://a.com/hunan.m3u8", Meta: core.StreamMeta{Channel: "湖南卫视", Group: "卫视频道"}},
+		{URL: "rtmp://b.com/live/hunan", Meta: core.StreamMeta{Channel: "湖南卫视", Group: "卫视频道"}},
+	}
+
+	got, err := ParseTXTPlaylist(strings.NewReader(playlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTXTPlaylist:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func encodeText(t *testing.T, enc encoding.Encoding, text string) []byte {
+	t.Helper()
+	data, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodePlaylistText(t *testing.T) {
+	const text = "央视频道,#genre#\nCCTV1,http://a.com/cctv1.m3u8\n"
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"utf-8", []byte(text)},
+		{"utf-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, text...)},
+		{"gbk", encodeText(t, simplifiedchinese.GBK, text)},
+		{"gb18030", encodeText(t, simplifiedchinese.GB18030, text)},
+		{"utf-16le with BOM", encodeText(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), text)},
+		{"utf-16be with BOM", encodeText(t, unicode.UTF16(unicode.BigEndian, unicode.UseBOM), text)},
+	}
+	for _, tt := range tests {
+		got, err := decodePlaylistText(tt.data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != text {
+			t.Errorf("%s: decoded %q, want %q", tt.name, got, text)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/exporter"
+	"m3u8_selector/tester"
+)
+
+// testAndReport 测试候选源、按排名输出结果，并返回退出码：没有可用直播源时返回 exitFailure
+func testAndReport(candidates []core.Candidate, test testOptions, out outputOptions) int {
+	if len(candidates) == 0 {
+		fmt.Fprintln(out.log, "\n未找到任何流媒体链接。")
+		return exitFailure
+	}
+
+	core.SortCandidates(candidates)
+	fmt.Fprintf(out.log, "\n总共找到 %d 个唯一的流媒体链接\n", len(candidates))
+
+	results := tester.TestAllSources(candidates, test.testerOptions(out.log))
+	validSources, failedSources := splitResults(results, test.rankBy())
+
+	if out.path != "" {
+		if err := writeResults(out, limitSources(validSources, out.limit), failedSources); err != nil {
+			fmt.Fprintf(out.log, "导出测试结果失败: %v\n", err)
+			return exitFailure
+		}
+	}
+
+	if len(validSources) == 0 {
+		fmt.Fprintln(out.log, "\n没有找到可用的直播源。")
+		fmt.Fprintln(out.log, "\n部分测试结果:")
+		for i, result := range results {
+			if i >= 5 {
+				break
+			}
+			fmt.Fprintf(out.log, "URL: %s\n响应时间: %v\n状态: %s\n\n", result.URL, result.Latency, result.Error)
+		}
+		return exitFailure
+	}
+
+	printRanking(out.log, validSources, out.limit, test.rankBy())
+	return exitOK
+}
+
+// splitResults 将测试结果分为可用（已按排名排序）和不可用两组
+func splitResults(results []core.M3U8Source, by core.RankBy) (valid, failed []core.M3U8Source) {
+	valid = []core.M3U8Source{}
+	failed = []core.M3U8Source{}
+	for _, result := range results {
+		if result.Valid {
+			valid = append(valid, result)
+		} else {
+			failed = append(failed, result)
+		}
+	}
+	core.RankSources(valid, by)
+	return valid, failed
+}
+
+// limitSources 返回前 limit 个直播源，limit 为 0 时不限制
+func limitSources(sources []core.M3U8Source, limit int) []core.M3U8Source {
+	if limit > 0 && len(sources) > limit {
+		return sources[:limit]
+	}
+	return sources
+}
+
+// printRanking 将排名前 limit 的可用直播源输出到 w
+func printRanking(w io.Writer, validSources []core.M3U8Source, limit int, by core.RankBy) {
+	order := "播放余量"
+	switch by {
+	case core.RankBySpeed:
+		order = "真实下载速度"
+	case core.RankByResolution:
+		order = "分辨率"
+	}
+	fmt.Fprintf(w, "\n=== 找到 %d 个可用的直播源，按%s排序 ===\n\n", len(validSources), order)
+
+	for i, source := range limitSources(validSources, limit) {
+		fmt.Fprintf(w, "第 %d 名 (下载速度: %.2f KB/s, 延迟: %v, 数据大小: %.2f KB%s%s):\n%s%s\n%s\n",
+			i+1, source.DownloadSpeed, source.Latency, float64(source.DataSize)/1024, describeLive(source.Live),
+			describeMedia(source), describeMeta(source.Meta), source.URL, describeVariants(source))
+	}
+
+	fmt.Fprintf(w, "=== 排名第一的直播源 ===\n%s\n下载速度: %.2f KB/s\n延迟: %v\n数据大小: %.2f KB\n下载时间: %v\n",
+		validSources[0].URL, validSources[0].DownloadSpeed, validSources[0].Latency,
+		float64(validSources[0].DataSize)/1024, validSources[0].DownloadTime)
+}
+
+// writeResults 按指定格式导出测试结果，路径为 "-" 时写到标准输出
+func writeResults(out outputOptions, ranked, failed []core.M3U8Source) error {
+	if out.path == "-" {
+		return exporter.Write(os.Stdout, out.format, ranked, failed)
+	}
+
+	f, err := os.Create(out.path)
+	if err != nil {
+		return err
+	}
+	if err := exporter.Write(f, out.format, ranked, failed); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out.log, "已将测试结果以 %s 格式导出到 %s\n", out.format, out.path)
+	return nil
+}
+
+// describeMeta 将来源元数据格式化为一行说明，没有元数据时返回空字符串
+func describeMeta(meta core.StreamMeta) string {
+	parts := []string{}
+	if meta.Channel != "" {
+		parts = append(parts, meta.Channel)
+	}
+	if meta.Group != "" {
+		parts = append(parts, meta.Group)
+	}
+	if meta.Resolution != "" {
+		parts = append(parts, meta.Resolution)
+	}
+	if meta.Location != "" {
+		parts = append(parts, meta.Location)
+	}
+	if !meta.CheckedAt.IsZero() {
+		parts = append(parts, "检测于 "+meta.CheckedAt.Format("2006-01-02 15:04"))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, " | ") + "\n"
+}
+
+// describeLive 返回直播状态说明，未验证时返回空字符串
+func describeLive(state core.LiveState) string {
+	switch state {
+	case core.LiveVerified:
+		return ", 直播已验证"
+	case core.LiveFrozen:
+		return ", 播放列表停止更新"
+	case core.LiveLooping:
+		return ", 循环播放"
+	case core.LiveStatic:
+		return ", 非直播"
+	}
+	return ""
+}
+
+// describeMedia 返回从媒体数据检测到的容器格式、编码和连续计数器错误等信息，没有检测结果时返回空字符串
+func describeMedia(source core.M3U8Source) string {
+	desc := ""
+	switch source.Measurement {
+	case core.Estimated:
+		desc += ", 速度为估算值（数据量不足）"
+	case core.Unmeasured:
+		desc += ", 未测速"
+	}
+	if source.Container != core.ContainerUnknown {
+		desc += ", 格式: " + string(source.Container)
+	}
+	if len(source.Codecs) > 0 {
+		desc += ", 编码: " + strings.Join(source.Codecs, "/")
+	}
+	if source.Width > 0 {
+		desc += fmt.Sprintf(", 画面: %dx%d", source.Width, source.Height)
+		if source.FrameRate > 0 {
+			desc += fmt.Sprintf("@%.4gfps", source.FrameRate)
+		}
+	}
+	if source.Bitrate > 0 {
+		desc += fmt.Sprintf(", 码率: %.0f kbps", source.Bitrate)
+	}
+	if source.Headroom > 0 {
+		desc += fmt.Sprintf(", 播放余量: %.2fx", source.Headroom)
+		if source.Headroom < core.MinHeadroom {
+			desc += " (无法实时播放)"
+		}
+	}
+	if source.Packets > 0 {
+		desc += fmt.Sprintf(", 收包: %d, 丢包: %d, 抖动: %v", source.Packets, source.LostPackets, source.Jitter.Round(time.Microsecond))
+	}
+	if source.ProxyClients > 0 {
+		desc += fmt.Sprintf(", 代理客户端: %d", source.ProxyClients)
+	}
+	if source.ContinuityErrors > 0 && source.Container == core.ContainerFMP4 {
+		desc += fmt.Sprintf(", 解码时间不连续: %d", source.ContinuityErrors)
+	} else if source.ContinuityErrors > 0 {
+		desc += fmt.Sprintf(", CC 错误: %d", source.ContinuityErrors)
+	}
+	return desc
+}
+
+// describeVariants 列出主播放列表中各变体的测试结果，并标出排名使用的变体
+func describeVariants(source core.M3U8Source) string {
+	if len(source.Variants) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, v := range source.Variants {
+		marker := " "
+		if v.URL == source.SelectedVariant {
+			marker = "*"
+		}
+		status := fmt.Sprintf("%.2f KB/s", v.DownloadSpeed)
+		if !v.Valid {
+			status = "失败: " + v.Error
+		}
+		fmt.Fprintf(&sb, "  %s 变体 %d kbps %s %s: %s\n", marker, v.Bandwidth/1000, v.Resolution, v.Codecs, status)
+	}
+	return sb.String()
+}
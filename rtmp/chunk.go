@@ -0,0 +1,217 @@
+package rtmp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// 消息类型
+const (
+	typeSetChunkSize     = 1
+	typeAbort            = 2
+	typeAck              = 3
+	typeUserControl      = 4
+	typeWindowAckSize    = 5
+	typeSetPeerBandwidth = 6
+	typeAudio            = 8
+	typeVideo            = 9
+	typeDataAMF3         = 15
+	typeCommandAMF3      = 17
+	typeDataAMF0         = 18
+	typeCommandAMF0      = 20
+	typeAggregate        = 22
+)
+
+// 发送消息使用的块流 ID，与 ffmpeg 一致
+const (
+	csidControl = 2
+	csidCommand = 3
+	csidSource  = 8
+)
+
+// defaultChunkSize 是对方发送 Set Chunk Size 之前的块大小
+const defaultChunkSize = 128
+
+// maxMessageSize 限制单条消息的大小，块头中的长度字段最大约 16MB
+const maxMessageSize = 16 * 1024 * 1024
+
+// extendedTimestamp 表示时间戳字段使用块头之后的 4 字节扩展时间戳
+const extendedTimestamp = 0xFFFFFF
+
+// Message 是一条完整的 RTMP 消息
+type Message struct {
+	Type      byte   // 音频、视频、数据消息的类型与 flv.TagAudio、flv.TagVideo、flv.TagScript 相同
+	Timestamp uint32 // 毫秒
+	StreamID  uint32
+	Payload   []byte
+}
+
+// chunkStream 是一个块流上次的消息头和正在组装的消息
+type chunkStream struct {
+	timestamp uint32
+	delta     uint32
+	length    int
+	typ       byte
+	streamID  uint32
+	extended  bool
+	payload   []byte
+}
+
+// chunkReader 将块流组装为消息
+type chunkReader struct {
+	r         *bufio.Reader
+	chunkSize int
+	streams   map[uint32]*chunkStream
+}
+
+func newChunkReader(r *bufio.Reader) *chunkReader {
+	return &chunkReader{r: r, chunkSize: defaultChunkSize, streams: make(map[uint32]*chunkStream)}
+}
+
+// readMessage 读取块直到组装出一条完整的消息
+func (c *chunkReader) readMessage() (*Message, error) {
+	for {
+		msg, err := c.readChunk()
+		if err != nil || msg != nil {
+			return msg, err
+		}
+	}
+}
+
+// messageHeaderSizes 是 fmt 0-3 对应的消息头长度
+var messageHeaderSizes = [4]int{11, 7, 3, 0}
+
+// readChunk 读取一个块，消息完整时返回该消息，否则返回 nil
+func (c *chunkReader) readChunk() (*Message, error) {
+	b, err := c.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	format := b >> 6
+	csid := uint32(b & 0x3F)
+	switch csid {
+	case 0:
+		b1, err := c.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		csid = 64 + uint32(b1)
+	case 1:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return nil, err
+		}
+		csid = 64 + uint32(ext[0]) + uint32(ext[1])*256
+	}
+
+	cs := c.streams[csid]
+	if cs == nil {
+		if format != 0 {
+			return nil, fmt.Errorf("chunk stream %d starts without a full header", csid)
+		}
+		cs = &chunkStream{}
+		c.streams[csid] = cs
+	}
+
+	var header [11]byte
+	if _, err := io.ReadFull(c.r, header[:messageHeaderSizes[format]]); err != nil {
+		return nil, err
+	}
+	if format < 3 {
+		// 带消息头的块总是开始一条新消息
+		cs.payload = nil
+		ts := uint32(header[0])<<16 | uint32(header[1])<<8 | uint32(header[2])
+		cs.extended = ts == extendedTimestamp
+		if cs.extended {
+			if ts, err = c.readUint32(); err != nil {
+				return nil, err
+			}
+		}
+		if format <= 1 {
+			cs.length = int(header[3])<<16 | int(header[4])<<8 | int(header[5])
+			cs.typ = header[6]
+		}
+		if format == 0 {
+			cs.streamID = binary.LittleEndian.Uint32(header[7:11])
+			cs.timestamp, cs.delta = ts, 0
+		} else {
+			cs.timestamp += ts
+			cs.delta = ts
+		}
+	} else {
+		if cs.extended {
+			if _, err := c.readUint32(); err != nil {
+				return nil, err
+			}
+		}
+		if cs.payload == nil {
+			cs.timestamp += cs.delta
+		}
+	}
+
+	if cs.length > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds limit", cs.length)
+	}
+	if cs.payload == nil {
+		cs.payload = make([]byte, 0, cs.length)
+	}
+	n := min(c.chunkSize, cs.length-len(cs.payload))
+	start := len(cs.payload)
+	cs.payload = cs.payload[:start+n]
+	if _, err := io.ReadFull(c.r, cs.payload[start:]); err != nil {
+		return nil, err
+	}
+	if len(cs.payload) < cs.length {
+		return nil, nil
+	}
+
+	msg := &Message{Type: cs.typ, Timestamp: cs.timestamp, StreamID: cs.streamID, Payload: cs.payload}
+	cs.payload = nil
+	return msg, nil
+}
+
+// abort 丢弃块流上未完成的消息
+func (c *chunkReader) abort(csid uint32) {
+	if cs := c.streams[csid]; cs != nil {
+		cs.payload = nil
+	}
+}
+
+func (c *chunkReader) readUint32() (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(c.r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+// writeMessage 按块大小拆分并发送一条消息，第一个块使用完整的消息头，后续块只有基本头
+func writeMessage(w *bufio.Writer, chunkSize int, csid byte, typ byte, streamID uint32, payload []byte) error {
+	header := make([]byte, 12)
+	header[0] = csid & 0x3F
+	// 时间戳固定为 0
+	header[4] = byte(len(payload) >> 16)
+	header[5] = byte(len(payload) >> 8)
+	header[6] = byte(len(payload))
+	header[7] = typ
+	binary.LittleEndian.PutUint32(header[8:], streamID)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	for len(payload) > 0 {
+		n := min(chunkSize, len(payload))
+		if _, err := w.Write(payload[:n]); err != nil {
+			return err
+		}
+		payload = payload[n:]
+		if len(payload) > 0 {
+			if err := w.WriteByte(0xC0 | csid&0x3F); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,149 @@
+package rtmp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// chunkHeader 构造块的基本头和消息头。fmt 0 使用全部字段，fmt 1 省略 streamID，
+// fmt 2 只有时间戳，fmt 3 没有消息头
+func chunkHeader(format byte, csid uint32, ts uint32, length int, typ byte, streamID uint32) []byte {
+	var b []byte
+	switch {
+	case csid >= 320:
+		b = append(b, format<<6|1, byte(csid-64), byte((csid-64)>>8))
+	case csid >= 64:
+		b = append(b, format<<6, byte(csid-64))
+	default:
+		b = append(b, format<<6|byte(csid))
+	}
+	if format == 3 {
+		return b
+	}
+	field := min(ts, extendedTimestamp)
+	b = append(b, byte(field>>16), byte(field>>8), byte(field))
+	if format <= 1 {
+		b = append(b, byte(length>>16), byte(length>>8), byte(length), typ)
+	}
+	if format == 0 {
+		b = binary.LittleEndian.AppendUint32(b, streamID)
+	}
+	if field == extendedTimestamp {
+		b = binary.BigEndian.AppendUint32(b, ts)
+	}
+	return b
+}
+
+func newTestReader(data []byte) *chunkReader {
+	return newChunkReader(bufio.NewReader(bytes.NewReader(data)))
+}
+
+func TestReadChunkHeaderFormats(t *testing.T) {
+	payload := func(n int, fill byte) []byte { return bytes.Repeat([]byte{fill}, n) }
+	var stream []byte
+	add := func(parts ...[]byte) {
+		for _, p := range parts {
+			stream = append(stream, p...)
+		}
+	}
+	// fmt 0：完整消息头，时间戳为绝对值
+	add(chunkHeader(0, 4, 1000, 5, typeVideo, 1), payload(5, 1))
+	// fmt 1：时间戳增量、新的长度和类型，沿用 streamID
+	add(chunkHeader(1, 4, 40, 3, typeAudio, 0), payload(3, 2))
+	// fmt 2：只有时间戳增量，沿用长度和类型
+	add(chunkHeader(2, 4, 20, 0, 0, 0), payload(3, 3))
+	// fmt 3：开始新消息时沿用上一个增量
+	add(chunkHeader(3, 4, 0, 0, 0, 0), payload(3, 4))
+	// 两字节和三字节基本头
+	add(chunkHeader(0, 70, 5, 2, typeDataAMF0, 1), payload(2, 5))
+	add(chunkHeader(0, 400, 6, 2, typeDataAMF0, 1), payload(2, 6))
+
+	want := []Message{
+		{Type: typeVideo, Timestamp: 1000, StreamID: 1, Payload: payload(5, 1)},
+		{Type: typeAudio, Timestamp: 1040, StreamID: 1, Payload: payload(3, 2)},
+		{Type: typeAudio, Timestamp: 1060, StreamID: 1, Payload: payload(3, 3)},
+		{Type: typeAudio, Timestamp: 1080, StreamID: 1, Payload: payload(3, 4)},
+		{Type: typeDataAMF0, Timestamp: 5, StreamID: 1, Payload: payload(2, 5)},
+		{Type: typeDataAMF0, Timestamp: 6, StreamID: 1, Payload: payload(2, 6)},
+	}
+	r := newTestReader(stream)
+	for i, w := range want {
+		msg, err := r.readMessage()
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if msg.Type != w.Type || msg.Timestamp != w.Timestamp || msg.StreamID != w.StreamID || !bytes.Equal(msg.Payload, w.Payload) {
+			t.Errorf("message %d = %+v, want %+v", i, *msg, w)
+		}
+	}
+	if _, err := r.readMessage(); err != io.EOF {
+		t.Errorf("after last message: err = %v, want EOF", err)
+	}
+}
+
+func TestReadChunkExtendedTimestamp(t *testing.T) {
+	// 200 字节的消息按默认块大小 128 拆成两块，fmt 3 的后续块同样带有扩展时间戳
+	const ts = 0x01000000
+	data := bytes.Repeat([]byte{7}, 200)
+	var stream []byte
+	stream = append(stream, chunkHeader(0, 6, ts, len(data), typeVideo, 1)...)
+	stream = append(stream, data[:defaultChunkSize]...)
+	stream = append(stream, chunkHeader(3, 6, 0, 0, 0, 0)...)
+	stream = binary.BigEndian.AppendUint32(stream, ts)
+	stream = append(stream, data[defaultChunkSize:]...)
+
+	r := newTestReader(stream)
+	msg, err := r.readChunk()
+	if err != nil || msg != nil {
+		t.Fatalf("first chunk: msg = %v, err = %v, want incomplete message", msg, err)
+	}
+	msg, err = r.readChunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Timestamp != ts {
+		t.Errorf("Timestamp = %#x, want %#x", msg.Timestamp, ts)
+	}
+	if !bytes.Equal(msg.Payload, data) {
+		t.Errorf("payload of %d bytes does not match", len(msg.Payload))
+	}
+}
+
+func TestReadChunkWithoutFullHeader(t *testing.T) {
+	r := newTestReader(append(chunkHeader(1, 5, 0, 1, typeAudio, 0), 0))
+	if _, err := r.readChunk(); err == nil {
+		t.Error("expected error for fmt 1 chunk on a new chunk stream")
+	}
+}
+
+func TestSetChunkSize(t *testing.T) {
+	const size = 256
+	data := bytes.Repeat([]byte{9}, 300)
+	var stream []byte
+	stream = append(stream, chunkHeader(0, csidControl, 0, 4, typeSetChunkSize, 0)...)
+	stream = binary.BigEndian.AppendUint32(stream, size)
+	stream = append(stream, chunkHeader(0, 6, 0, len(data), typeVideo, 1)...)
+	stream = append(stream, data[:size]...)
+	stream = append(stream, chunkHeader(3, 6, 0, 0, 0, 0)...)
+	stream = append(stream, data[size:]...)
+
+	counter := &countingReader{r: bytes.NewReader(stream)}
+	c := &Client{
+		counter: counter,
+		reader:  newChunkReader(bufio.NewReader(counter)),
+		writer:  bufio.NewWriter(io.Discard),
+	}
+	msg, err := c.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.reader.chunkSize != size {
+		t.Errorf("chunkSize = %d, want %d", c.reader.chunkSize, size)
+	}
+	if msg.Type != typeVideo || !bytes.Equal(msg.Payload, data) {
+		t.Errorf("got message type %d with %d bytes, want video with %d bytes", msg.Type, len(msg.Payload), len(data))
+	}
+}
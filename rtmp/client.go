@@ -0,0 +1,365 @@
+// Package rtmp 实现探测直播源所需的最小 RTMP 播放客户端：握手、connect、createStream、play，
+// 以及读取音视频消息时需要回应的协议控制消息
+package rtmp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"m3u8_selector/amf"
+	"m3u8_selector/flv"
+)
+
+// DefaultPort 是 rtmp:// 地址未指定端口时使用的端口
+const DefaultPort = "1935"
+
+// bufferLength 是通过 Set Buffer Length 告知服务器的客户端缓冲时长（毫秒）
+const bufferLength = 3000
+
+// 用户控制消息的事件类型
+const (
+	eventSetBufferLength = 3
+	eventPingRequest     = 6
+	eventPingResponse    = 7
+)
+
+// StatusError 是服务器通过 onStatus 通知的播放失败或结束，如 NetStream.Play.StreamNotFound
+type StatusError struct {
+	Code        string
+	Description string
+}
+
+func (e *StatusError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return fmt.Sprintf("%s (%s)", e.Code, e.Description)
+}
+
+// Client 是一个 RTMP 连接。一个 Client 只能在一个 goroutine 中使用
+type Client struct {
+	conn    net.Conn
+	counter *countingReader
+	reader  *chunkReader
+	writer  *bufio.Writer
+	timeout time.Duration
+
+	app    string
+	tcURL  string
+	stream string // 播放路径，包含查询参数
+
+	txn       float64
+	streamID  uint32
+	ackWindow uint32
+	lastAck   uint64
+	pending   []*Message // 聚合消息中尚未返回的子消息
+}
+
+// countingReader 统计从连接读取的字节数，用于发送确认消息
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+// Dial 连接 rtmp:// 地址中的服务器并完成握手
+func Dial(rawURL string, timeout time.Duration) (*Client, error) {
+	host, app, stream, err := parseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("tcp", host, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := handshake(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	counter := &countingReader{r: conn}
+	return &Client{
+		conn:    conn,
+		counter: counter,
+		reader:  newChunkReader(bufio.NewReaderSize(counter, 64*1024)),
+		writer:  bufio.NewWriter(conn),
+		timeout: timeout,
+		app:     app,
+		tcURL:   "rtmp://" + host + "/" + app,
+		stream:  stream,
+	}, nil
+}
+
+// parseURL 拆分 rtmp://主机[:端口]/应用/流名称[?参数]，查询参数属于流名称（常见于 CDN 鉴权）
+func parseURL(rawURL string) (host, app, stream string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid RTMP URL: %v", err)
+	}
+	if !strings.EqualFold(u.Scheme, "rtmp") {
+		return "", "", "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	host = u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), DefaultPort)
+	}
+	app, stream, _ = strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if app == "" || stream == "" {
+		return "", "", "", fmt.Errorf("RTMP URL has no application or stream name")
+	}
+	if u.RawQuery != "" {
+		stream += "?" + u.RawQuery
+	}
+	return host, app, stream, nil
+}
+
+// Close 删除播放流并关闭连接
+func (c *Client) Close() error {
+	if c.streamID != 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+		c.command(csidCommand, 0, "deleteStream", 0, nil, float64(c.streamID))
+	}
+	return c.conn.Close()
+}
+
+// SetReadDeadline 设置 ReadMessage 的截止时间
+func (c *Client) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// Connect 发送 connect 命令连接到应用
+func (c *Client) Connect() error {
+	_, err := c.call("connect", amf.Object{
+		"app":           c.app,
+		"flashVer":      "LNX 9,0,124,2",
+		"tcUrl":         c.tcURL,
+		"fpad":          false,
+		"capabilities":  15,
+		"audioCodecs":   4071,
+		"videoCodecs":   252,
+		"videoFunction": 1,
+	})
+	return err
+}
+
+// CreateStream 创建用于播放的消息流
+func (c *Client) CreateStream() error {
+	values, err := c.call("createStream", nil)
+	if err != nil {
+		return err
+	}
+	if len(values) < 4 {
+		return fmt.Errorf("createStream returned no stream ID")
+	}
+	id, ok := values[3].(float64)
+	if !ok {
+		return fmt.Errorf("createStream returned no stream ID")
+	}
+	c.streamID = uint32(id)
+	return nil
+}
+
+// Play 请求播放 URL 中的流。服务器的播放结果通过 ReadMessage 返回
+func (c *Client) Play() error {
+	event := make([]byte, 10)
+	binary.BigEndian.PutUint16(event, eventSetBufferLength)
+	binary.BigEndian.PutUint32(event[2:], c.streamID)
+	binary.BigEndian.PutUint32(event[6:], bufferLength)
+	if err := c.writeControl(typeUserControl, event); err != nil {
+		return err
+	}
+	// start 为 -2：先找直播流，找不到再找录播文件
+	return c.command(csidSource, c.streamID, "play", 0, nil, c.stream, -2)
+}
+
+// ReadMessage 读取下一条音频、视频或 AMF0 数据消息。聚合消息会拆成单独的消息返回，
+// 服务器通知播放失败或结束时返回 *StatusError
+func (c *Client) ReadMessage() (*Message, error) {
+	for {
+		if len(c.pending) > 0 {
+			msg := c.pending[0]
+			c.pending = c.pending[1:]
+			return msg, nil
+		}
+
+		msg, err := c.next()
+		if err != nil {
+			return nil, err
+		}
+		switch msg.Type {
+		case typeAudio, typeVideo, typeDataAMF0:
+			return msg, nil
+		case typeDataAMF3:
+			if len(msg.Payload) > 0 {
+				msg.Type, msg.Payload = typeDataAMF0, msg.Payload[1:]
+				return msg, nil
+			}
+		case typeAggregate:
+			c.splitAggregate(msg)
+		case typeCommandAMF0, typeCommandAMF3:
+			if err := checkStatus(commandPayload(msg)); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// splitAggregate 将聚合消息中的 FLV 标签转换为消息，子消息的时间戳相对于聚合消息的时间戳
+func (c *Client) splitAggregate(msg *Message) {
+	tags, _ := flv.SplitTags(msg.Payload)
+	for _, tag := range tags {
+		c.pending = append(c.pending, &Message{
+			Type:      tag.Type,
+			Timestamp: msg.Timestamp + tag.Timestamp - tags[0].Timestamp,
+			StreamID:  msg.StreamID,
+			Payload:   tag.Data,
+		})
+	}
+}
+
+// checkStatus 检查 onStatus 通知，播放失败或结束时返回错误
+func checkStatus(payload []byte) error {
+	values, _ := amf.Decode(payload)
+	if len(values) < 4 || values[0] != "onStatus" {
+		return nil
+	}
+	info, ok := values[3].(amf.Object)
+	if !ok {
+		return nil
+	}
+	code := info.String("code")
+	if info.String("level") == "error" || code == "NetStream.Play.Stop" || code == "NetStream.Play.UnpublishNotify" {
+		return &StatusError{Code: code, Description: info.String("description")}
+	}
+	return nil
+}
+
+// commandPayload 返回命令消息的 AMF0 数据，AMF3 命令消息的第一个字节是格式标记
+func commandPayload(msg *Message) []byte {
+	if msg.Type == typeCommandAMF3 && len(msg.Payload) > 0 {
+		return msg.Payload[1:]
+	}
+	return msg.Payload
+}
+
+// call 发送命令并等待对应事务 ID 的 _result 或 _error
+func (c *Client) call(name string, args ...interface{}) ([]interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	c.txn++
+	if err := c.command(csidCommand, 0, name, c.txn, args...); err != nil {
+		return nil, err
+	}
+	for {
+		msg, err := c.next()
+		if err != nil {
+			return nil, fmt.Errorf("%s failed: %v", name, err)
+		}
+		if msg.Type != typeCommandAMF0 && msg.Type != typeCommandAMF3 {
+			continue
+		}
+		values, _ := amf.Decode(commandPayload(msg))
+		if len(values) < 2 || values[1] != c.txn {
+			continue // onBWDone 等与本次调用无关的命令
+		}
+		switch values[0] {
+		case "_result":
+			return values, nil
+		case "_error":
+			err := &StatusError{Code: "_error"}
+			if len(values) >= 4 {
+				if info, ok := values[3].(amf.Object); ok {
+					err.Code, err.Description = info.String("code"), info.String("description")
+				}
+			}
+			return nil, fmt.Errorf("%s rejected: %v", name, err)
+		}
+	}
+}
+
+// command 发送一条 AMF0 命令消息
+func (c *Client) command(csid byte, streamID uint32, name string, txn float64, args ...interface{}) error {
+	payload, err := amf.Encode(append([]interface{}{name, txn}, args...)...)
+	if err != nil {
+		return err
+	}
+	return writeMessage(c.writer, defaultChunkSize, csid, typeCommandAMF0, streamID, payload)
+}
+
+// next 读取下一条消息并处理协议控制消息，返回其他消息
+func (c *Client) next() (*Message, error) {
+	for {
+		msg, err := c.reader.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.acknowledge(); err != nil {
+			return nil, err
+		}
+
+		p := msg.Payload
+		switch msg.Type {
+		case typeSetChunkSize:
+			if len(p) >= 4 {
+				size := int(binary.BigEndian.Uint32(p) & 0x7FFFFFFF)
+				if size < 1 || size > maxMessageSize {
+					return nil, fmt.Errorf("invalid chunk size %d", size)
+				}
+				c.reader.chunkSize = size
+			}
+		case typeAbort:
+			if len(p) >= 4 {
+				c.reader.abort(binary.BigEndian.Uint32(p))
+			}
+		case typeWindowAckSize:
+			if len(p) >= 4 {
+				c.ackWindow = binary.BigEndian.Uint32(p)
+			}
+		case typeSetPeerBandwidth:
+			if len(p) >= 4 {
+				if err := c.writeControl(typeWindowAckSize, p[:4]); err != nil {
+					return nil, err
+				}
+			}
+		case typeUserControl:
+			if len(p) >= 6 && binary.BigEndian.Uint16(p) == eventPingRequest {
+				pong := append([]byte{0, eventPingResponse}, p[2:6]...)
+				if err := c.writeControl(typeUserControl, pong); err != nil {
+					return nil, err
+				}
+			}
+		case typeAck:
+		default:
+			return msg, nil
+		}
+	}
+}
+
+// acknowledge 收到的数据超过确认窗口时发送确认消息，否则服务器会停止发送
+func (c *Client) acknowledge() error {
+	if c.ackWindow == 0 || c.counter.n-c.lastAck < uint64(c.ackWindow) {
+		return nil
+	}
+	c.lastAck = c.counter.n
+	ack := make([]byte, 4)
+	binary.BigEndian.PutUint32(ack, uint32(c.counter.n))
+	return c.writeControl(typeAck, ack)
+}
+
+// writeControl 发送协议控制消息。读取媒体数据期间只设置了读截止时间，这里单独设置写截止时间
+func (c *Client) writeControl(typ byte, payload []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	return writeMessage(c.writer, defaultChunkSize, csidControl, typ, 0, payload)
+}
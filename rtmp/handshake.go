@@ -0,0 +1,42 @@
+package rtmp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// handshakeSize 是 C1/S1/C2/S2 的长度
+const handshakeSize = 1536
+
+// rtmpVersion 是 C0/S0 中的协议版本
+const rtmpVersion = 3
+
+// handshake 执行简单握手：发送 C0+C1，读取 S0+S1+S2，回送 C2（S1 的副本）。
+// 不使用 Flash Player 的摘要握手，主流服务器（nginx-rtmp、SRS、各家 CDN）都接受简单握手。
+func handshake(rw io.ReadWriter) error {
+	c0c1 := make([]byte, 1+handshakeSize)
+	c0c1[0] = rtmpVersion
+	binary.BigEndian.PutUint32(c0c1[1:], uint32(time.Now().UnixMilli()))
+	// C1 的 4-8 字节必须为 0，其余为随机数据
+	if _, err := rand.Read(c0c1[9:]); err != nil {
+		return err
+	}
+	if _, err := rw.Write(c0c1); err != nil {
+		return err
+	}
+
+	s0s1s2 := make([]byte, 1+2*handshakeSize)
+	if _, err := io.ReadFull(rw, s0s1s2); err != nil {
+		return fmt.Errorf("handshake failed: %v", err)
+	}
+	if s0s1s2[0] != rtmpVersion {
+		return fmt.Errorf("handshake failed: unsupported RTMP version %d", s0s1s2[0])
+	}
+	// S2 应当回显 C1，但不少服务器并不遵守，这里不做校验
+	s1 := s0s1s2[1 : 1+handshakeSize]
+	_, err := rw.Write(s1)
+	return err
+}
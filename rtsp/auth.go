@@ -0,0 +1,120 @@
+package rtsp
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// challenge 是服务器 WWW-Authenticate 质询中的认证参数
+type challenge struct {
+	scheme string // "Basic" 或 "Digest"
+	realm  string
+	nonce  string
+	opaque string
+	qop    string // 只支持 "auth"
+	nc     int
+}
+
+// parseChallenge 从 WWW-Authenticate 头中选择认证方式，同时提供时优先使用 Digest
+func parseChallenge(values []string) (*challenge, error) {
+	var basic *challenge
+	for _, v := range values {
+		scheme, params, _ := strings.Cut(strings.TrimSpace(v), " ")
+		switch strings.ToLower(scheme) {
+		case "digest":
+			attrs := parseAuthParams(params)
+			ch := &challenge{scheme: "Digest", realm: attrs["realm"], nonce: attrs["nonce"], opaque: attrs["opaque"]}
+			if algorithm := attrs["algorithm"]; algorithm != "" && !strings.EqualFold(algorithm, "MD5") {
+				continue
+			}
+			for _, q := range strings.Split(attrs["qop"], ",") {
+				if strings.TrimSpace(q) == "auth" {
+					ch.qop = "auth"
+				}
+			}
+			return ch, nil
+		case "basic":
+			basic = &challenge{scheme: "Basic", realm: parseAuthParams(params)["realm"]}
+		}
+	}
+	if basic != nil {
+		return basic, nil
+	}
+	return nil, fmt.Errorf("unsupported RTSP authentication %q", strings.Join(values, ", "))
+}
+
+// authorization 生成请求的 Authorization 头
+func (ch *challenge) authorization(method, uri, username, password string) string {
+	if ch.scheme == "Basic" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	}
+
+	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s"`, username, ch.realm, ch.nonce, uri)
+	if ch.qop == "auth" {
+		ch.nc++
+		nc := fmt.Sprintf("%08x", ch.nc)
+		cnonce := randomHex(8)
+		response := ch.digestResponse(method, uri, username, password, nc, cnonce)
+		header += fmt.Sprintf(`, qop=auth, nc=%s, cnonce="%s", response="%s"`, nc, cnonce, response)
+	} else {
+		header += fmt.Sprintf(`, response="%s"`, ch.digestResponse(method, uri, username, password, "", ""))
+	}
+	if ch.opaque != "" {
+		header += fmt.Sprintf(`, opaque="%s"`, ch.opaque)
+	}
+	return header
+}
+
+// digestResponse 按 RFC 2617 计算 Digest 认证的 response，qop 为空时使用 RFC 2069 的计算方式
+func (ch *challenge) digestResponse(method, uri, username, password, nc, cnonce string) string {
+	ha1 := md5Hex(username + ":" + ch.realm + ":" + password)
+	ha2 := md5Hex(method + ":" + uri)
+	if ch.qop == "" {
+		return md5Hex(ha1 + ":" + ch.nonce + ":" + ha2)
+	}
+	return md5Hex(strings.Join([]string{ha1, ch.nonce, nc, cnonce, ch.qop, ha2}, ":"))
+}
+
+// parseAuthParams 解析 key="value", key=value 形式的认证参数
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else if comma := strings.Index(s, ","); comma >= 0 {
+			value, s = s[:comma], s[comma:]
+		} else {
+			value, s = s, ""
+		}
+		params[key] = strings.TrimSpace(value)
+	}
+	return params
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
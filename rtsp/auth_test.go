@@ -0,0 +1,114 @@
+package rtsp
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    *challenge
+		wantErr bool
+	}{
+		{
+			name:   "digest with qop",
+			values: []string{`Digest realm="IP Camera(21388)", nonce="8a3a9b", qop="auth,auth-int", opaque="5ccc069c"`},
+			want:   &challenge{scheme: "Digest", realm: "IP Camera(21388)", nonce: "8a3a9b", opaque: "5ccc069c", qop: "auth"},
+		},
+		{
+			name:   "digest without qop",
+			values: []string{`Digest realm="LIVE555 Streaming Media", nonce="a1b2c3"`},
+			want:   &challenge{scheme: "Digest", realm: "LIVE555 Streaming Media", nonce: "a1b2c3"},
+		},
+		{
+			name:   "digest preferred over basic",
+			values: []string{`Basic realm="cam"`, `Digest realm="cam", nonce="n1", algorithm=MD5`},
+			want:   &challenge{scheme: "Digest", realm: "cam", nonce: "n1"},
+		},
+		{
+			name:   "unsupported digest algorithm falls back to basic",
+			values: []string{`Digest realm="cam", nonce="n1", algorithm=SHA-256`, `Basic realm="cam"`},
+			want:   &challenge{scheme: "Basic", realm: "cam"},
+		},
+		{
+			name:   "unquoted values and lower-case scheme",
+			values: []string{`digest realm=cam, nonce=n2`},
+			want:   &challenge{scheme: "Digest", realm: "cam", nonce: "n2"},
+		},
+		{
+			name:    "unsupported scheme",
+			values:  []string{`Bearer realm="cam"`},
+			wantErr: true,
+		},
+		{
+			name:    "no header",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		got, err := parseChallenge(tt.values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDigestResponse(t *testing.T) {
+	// RFC 2617 第 3.5 节的示例
+	ch := &challenge{scheme: "Digest", realm: "testrealm@host.com", nonce: "dcd98b7102dd2f0e8b11d0f600bfb0c093", qop: "auth"}
+	if got := ch.digestResponse("GET", "/dir/index.html", "Mufasa", "Circle Of Life", "00000001", "0a4f113b"); got != "6629fae49393a05397450978507c4ef1" {
+		t.Errorf("qop=auth response = %s", got)
+	}
+
+	// 没有 qop 时 response = MD5(HA1:nonce:HA2)
+	ch.qop = ""
+	if got := ch.digestResponse("GET", "/dir/index.html", "Mufasa", "CircleOfLife", "", ""); got != "1949323746fe6a43ef61f9606e7febea" {
+		t.Errorf("RFC 2069 response = %s", got)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	basic := &challenge{scheme: "Basic"}
+	if got := basic.authorization("DESCRIBE", "rtsp://cam/live", "Aladdin", "open sesame"); got != "Basic QWxhZGRpbjpvcGVuIHNlc2FtZQ==" {
+		t.Errorf("Basic = %q", got)
+	}
+
+	digest := &challenge{scheme: "Digest", realm: "cam", nonce: "n1", opaque: "op", qop: "auth"}
+	headerRegex := regexp.MustCompile(`^Digest username="admin", realm="cam", nonce="n1", uri="rtsp://cam/live", ` +
+		`qop=auth, nc=(\d{8}), cnonce="([0-9a-f]{16})", response="([0-9a-f]{32})", opaque="op"$`)
+	for _, wantNC := range []string{"00000001", "00000002"} {
+		header := digest.authorization("DESCRIBE", "rtsp://cam/live", "admin", "12345")
+		m := headerRegex.FindStringSubmatch(header)
+		if m == nil {
+			t.Fatalf("Digest header = %q", header)
+		}
+		if m[1] != wantNC {
+			t.Errorf("nc = %s, want %s", m[1], wantNC)
+		}
+		if want := digest.digestResponse("DESCRIBE", "rtsp://cam/live", "admin", "12345", m[1], m[2]); m[3] != want {
+			t.Errorf("response = %s, want %s", m[3], want)
+		}
+	}
+
+	legacy := &challenge{scheme: "Digest", realm: "cam", nonce: "n1"}
+	want := `Digest username="admin", realm="cam", nonce="n1", uri="rtsp://cam/live", response="` +
+		legacy.digestResponse("PLAY", "rtsp://cam/live", "admin", "12345", "", "") + `"`
+	if got := legacy.authorization("PLAY", "rtsp://cam/live", "admin", "12345"); got != want {
+		t.Errorf("Digest without qop = %q, want %q", got, want)
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	got := parseAuthParams(` realm="a, b", nonce=xyz ,qop="auth",stale=FALSE`)
+	want := map[string]string{"realm": "a, b", "nonce": "xyz", "qop": "auth", "stale": "FALSE"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,269 @@
+// Package rtsp 实现探测直播源所需的最小 RTSP/1.0 客户端：OPTIONS、DESCRIBE、
+// 基于 TCP 交织传输的 SETUP/PLAY，以及 Basic/Digest 认证
+package rtsp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPort 是 rtsp:// 地址未指定端口时使用的端口
+const DefaultPort = "554"
+
+// maxBodySize 限制响应体大小，SDP 通常只有几 KB
+const maxBodySize = 1024 * 1024
+
+// Response 是一个 RTSP 响应
+type Response struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+}
+
+// Client 是一个 RTSP 连接。一个 Client 只能在一个 goroutine 中使用
+type Client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+
+	url       *url.URL // 去掉用户信息后的请求地址
+	user      *url.Userinfo
+	cseq      int
+	session   string
+	auth      *challenge
+	userAgent string
+}
+
+// Dial 连接 rtsp:// 地址中的服务器。地址中的用户名和密码用于后续请求的认证
+func Dial(rawURL string, timeout time.Duration) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RTSP URL: %v", err)
+	}
+	if !strings.EqualFold(u.Scheme, "rtsp") {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), DefaultPort)
+	}
+
+	conn, err := net.DialTimeout("tcp", host, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conn:      conn,
+		reader:    bufio.NewReaderSize(conn, 64*1024),
+		timeout:   timeout,
+		user:      u.User,
+		userAgent: "m3u8_selector",
+	}
+	clean := *u
+	clean.User = nil
+	c.url = &clean
+	return c, nil
+}
+
+// Close 关闭连接
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// URL 返回去掉用户信息的请求地址
+func (c *Client) URL() *url.URL {
+	return c.url
+}
+
+// Options 发送 OPTIONS 请求
+func (c *Client) Options() (*Response, error) {
+	return c.Do("OPTIONS", c.url.String(), nil)
+}
+
+// Describe 发送 DESCRIBE 请求，返回 SDP 响应
+func (c *Client) Describe() (*Response, error) {
+	return c.Do("DESCRIBE", c.url.String(), http.Header{"Accept": {"application/sdp"}})
+}
+
+// SetupInterleaved 使用 TCP 交织传输 SETUP 一个媒体，RTP/RTCP 使用 channel 和 channel+1
+func (c *Client) SetupInterleaved(control string, channel int) (*Response, error) {
+	transport := fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", channel, channel+1)
+	return c.Do("SETUP", control, http.Header{"Transport": {transport}})
+}
+
+// Play 从当前位置开始播放
+func (c *Client) Play(uri string) (*Response, error) {
+	return c.Do("PLAY", uri, http.Header{"Range": {"npt=0.000-"}})
+}
+
+// Teardown 结束会话，不等待响应
+func (c *Client) Teardown(uri string) {
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	c.writeRequest("TEARDOWN", uri, nil)
+}
+
+// Do 发送请求并读取响应。服务器返回 401 时按质询使用地址中的用户名密码重试一次
+func (c *Client) Do(method, uri string, header http.Header) (*Response, error) {
+	resp, err := c.roundTrip(method, uri, header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == 401 && c.user != nil && c.auth == nil {
+		ch, err := parseChallenge(resp.Header.Values("WWW-Authenticate"))
+		if err != nil {
+			return resp, err
+		}
+		c.auth = ch
+		resp, err = c.roundTrip(method, uri, header)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s := resp.Header.Get("Session"); s != "" {
+		// 去掉 ";timeout=60" 等参数
+		c.session = strings.TrimSpace(strings.SplitN(s, ";", 2)[0])
+	}
+	return resp, nil
+}
+
+func (c *Client) roundTrip(method, uri string, header http.Header) (*Response, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if err := c.writeRequest(method, uri, header); err != nil {
+		return nil, err
+	}
+	for {
+		// 播放开始后服务器可能先发送交织数据，跳过它们直到读到响应
+		b, err := c.reader.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != '$' {
+			break
+		}
+		if _, _, err := c.ReadInterleaved(); err != nil {
+			return nil, err
+		}
+	}
+	return c.readResponse()
+}
+
+func (c *Client) writeRequest(method, uri string, header http.Header) error {
+	c.cseq++
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s RTSP/1.0\r\nCSeq: %d\r\nUser-Agent: %s\r\n", method, uri, c.cseq, c.userAgent)
+	if c.session != "" {
+		fmt.Fprintf(&sb, "Session: %s\r\n", c.session)
+	}
+	if c.auth != nil && c.user != nil {
+		password, _ := c.user.Password()
+		fmt.Fprintf(&sb, "Authorization: %s\r\n", c.auth.authorization(method, uri, c.user.Username(), password))
+	}
+	for key, values := range header {
+		for _, v := range values {
+			fmt.Fprintf(&sb, "%s: %s\r\n", key, v)
+		}
+	}
+	sb.WriteString("\r\n")
+	_, err := io.WriteString(c.conn, sb.String())
+	return err
+}
+
+func (c *Client) readResponse() (*Response, error) {
+	tp := textproto.NewReader(c.reader)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "RTSP/") {
+		return nil, fmt.Errorf("malformed RTSP status line %q", line)
+	}
+	code, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed RTSP status line %q", line)
+	}
+	mime, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	resp := &Response{StatusCode: code, Status: line, Header: http.Header(mime)}
+
+	if cl := resp.Header.Get("Content-Length"); cl != "" {
+		n, err := strconv.Atoi(cl)
+		if err != nil || n < 0 || n > maxBodySize {
+			return nil, fmt.Errorf("invalid Content-Length %q", cl)
+		}
+		resp.Body = make([]byte, n)
+		if _, err := io.ReadFull(c.reader, resp.Body); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// ReadInterleaved 读取一个 TCP 交织数据帧 ('$' 通道号 长度 数据)，
+// 遇到服务器发来的 RTSP 消息（响应或 SET_PARAMETER 等请求）时读取并丢弃
+func (c *Client) ReadInterleaved() (channel int, data []byte, err error) {
+	for {
+		b, err := c.reader.Peek(1)
+		if err != nil {
+			return 0, nil, err
+		}
+		if b[0] == '$' {
+			break
+		}
+		if err := c.skipMessage(); err != nil {
+			return 0, nil, err
+		}
+	}
+	var header [4]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := int(header[2])<<8 | int(header[3])
+	data = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		return 0, nil, err
+	}
+	return int(header[1]), data, nil
+}
+
+// skipMessage 读取并丢弃一条 RTSP 消息，按 Content-Length 跳过消息体
+func (c *Client) skipMessage() error {
+	tp := textproto.NewReader(c.reader)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "RTSP/") && !strings.HasSuffix(line, " RTSP/1.0") {
+		return fmt.Errorf("malformed RTSP message %q", line)
+	}
+	mime, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if cl := mime.Get("Content-Length"); cl != "" {
+		n, err := strconv.Atoi(cl)
+		if err != nil || n < 0 || n > maxBodySize {
+			return fmt.Errorf("invalid Content-Length %q", cl)
+		}
+		if _, err := c.reader.Discard(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetReadDeadline 设置读取交织数据的截止时间
+func (c *Client) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
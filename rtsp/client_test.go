@@ -0,0 +1,205 @@
+package rtsp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// request 是测试服务器收到的 RTSP 请求
+type request struct {
+	method, uri string
+	header      textproto.MIMEHeader
+}
+
+func readRequest(r *bufio.Reader) (*request, error) {
+	tp := textproto.NewReader(r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Fields(line)
+	if len(parts) != 3 || parts[2] != "RTSP/1.0" {
+		return nil, fmt.Errorf("malformed request line %q", line)
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	return &request{method: parts[0], uri: parts[1], header: header}, nil
+}
+
+// interleaved 构造一个 TCP 交织数据帧
+func interleaved(channel byte, data []byte) []byte {
+	return append([]byte{'$', channel, byte(len(data) >> 8), byte(len(data))}, data...)
+}
+
+func md5String(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+var digestResponseRegex = regexp.MustCompile(`response="([0-9a-f]{32})"`)
+
+// serveClientScript 依次处理 OPTIONS、需要 Digest 认证的 DESCRIBE、SETUP 和 PLAY，
+// 在 PLAY 响应前后夹杂交织数据和服务器发来的 RTSP 消息
+func serveClientScript(t *testing.T, conn net.Conn, large []byte) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	reply := func(req *request, status string, lines ...string) {
+		msg := fmt.Sprintf("RTSP/1.0 %s\r\nCSeq: %s\r\n", status, req.header.Get("CSeq"))
+		for _, line := range lines {
+			msg += line + "\r\n"
+		}
+		io.WriteString(conn, msg+"\r\n")
+	}
+	next := func(method string) *request {
+		req, err := readRequest(r)
+		if err != nil {
+			t.Errorf("fixture: %v", err)
+			return nil
+		}
+		if req.method != method {
+			t.Errorf("fixture: got %s, want %s", req.method, method)
+			return nil
+		}
+		return req
+	}
+
+	req := next("OPTIONS")
+	if req == nil {
+		return
+	}
+	if req.header.Get("CSeq") != "1" || req.header.Get("Authorization") != "" {
+		t.Errorf("OPTIONS header = %v", req.header)
+	}
+	reply(req, "200 OK", "Public: OPTIONS, DESCRIBE, SETUP, PLAY, TEARDOWN")
+
+	if req = next("DESCRIBE"); req == nil {
+		return
+	}
+	reply(req, "401 Unauthorized", `WWW-Authenticate: Digest realm="cam", nonce="abc123"`)
+	if req = next("DESCRIBE"); req == nil {
+		return
+	}
+	m := digestResponseRegex.FindStringSubmatch(req.header.Get("Authorization"))
+	want := md5String(md5String("admin:cam:secret") + ":abc123:" + md5String("DESCRIBE:rtsp://"+conn.LocalAddr().String()+"/live"))
+	if m == nil || m[1] != want {
+		t.Errorf("DESCRIBE Authorization = %q, want response %s", req.header.Get("Authorization"), want)
+	}
+	sdp := "v=0\r\ns=test\r\nm=video 0 RTP/AVP 96\r\na=rtpmap:96 H264/90000\r\na=control:trackID=0\r\n"
+	reply(req, "200 OK", "Content-Type: application/sdp", fmt.Sprintf("Content-Length: %d", len(sdp)))
+	io.WriteString(conn, sdp)
+
+	if req = next("SETUP"); req == nil {
+		return
+	}
+	if req.header.Get("Transport") != "RTP/AVP/TCP;unicast;interleaved=0-1" || req.header.Get("Authorization") == "" {
+		t.Errorf("SETUP header = %v", req.header)
+	}
+	reply(req, "200 OK", "Session: 12345678;timeout=60", "Transport: RTP/AVP/TCP;unicast;interleaved=0-1")
+
+	if req = next("PLAY"); req == nil {
+		return
+	}
+	if req.header.Get("Session") != "12345678" {
+		t.Errorf("PLAY Session = %q", req.header.Get("Session"))
+	}
+	conn.Write(interleaved(0, []byte("early")))
+	reply(req, "200 OK", "RTP-Info: url=trackID=0;seq=1")
+	conn.Write(interleaved(0, []byte("abc")))
+	io.WriteString(conn, "SET_PARAMETER rtsp://cam/live RTSP/1.0\r\nCSeq: 1\r\nContent-Length: 4\r\n\r\nping")
+	conn.Write(interleaved(1, large))
+}
+
+func TestClientScript(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("TCP not available: %v", err)
+	}
+	defer ln.Close()
+	large := bytes.Repeat([]byte{0xAB}, 300)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		serveClientScript(t, conn, large)
+	}()
+
+	c, err := Dial(fmt.Sprintf("rtsp://admin:secret@%s/live", ln.Addr()), 3*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.URL().User != nil {
+		t.Errorf("URL() keeps user info: %s", c.URL())
+	}
+
+	resp, err := c.Options()
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("OPTIONS: %v %+v", err, resp)
+	}
+	resp, err = c.Describe()
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("DESCRIBE: %v %+v", err, resp)
+	}
+	session, err := ParseSDP(resp.Body)
+	if err != nil || len(session.Medias) != 1 || session.Medias[0].Codec != "H.264" {
+		t.Fatalf("SDP: %v %+v", err, session)
+	}
+	if resp, err = c.SetupInterleaved(ControlURL(c.URL().String(), session.Medias[0].Control), 0); err != nil || resp.StatusCode != 200 {
+		t.Fatalf("SETUP: %v %+v", err, resp)
+	}
+	// PLAY 响应之前的交织数据被跳过
+	if resp, err = c.Play(c.URL().String()); err != nil || resp.StatusCode != 200 || resp.Header.Get("RTP-Info") == "" {
+		t.Fatalf("PLAY: %v %+v", err, resp)
+	}
+
+	channel, data, err := c.ReadInterleaved()
+	if err != nil || channel != 0 || string(data) != "abc" {
+		t.Errorf("first frame = %d %q %v", channel, data, err)
+	}
+	// 服务器发来的 RTSP 消息被丢弃，超过 255 字节的帧使用两字节长度
+	channel, data, err = c.ReadInterleaved()
+	if err != nil || channel != 1 || !bytes.Equal(data, large) {
+		t.Errorf("second frame = %d, %d bytes, %v", channel, len(data), err)
+	}
+	if _, _, err = c.ReadInterleaved(); err == nil {
+		t.Error("expected error after the server closed the connection")
+	}
+}
+
+func TestReadInterleavedTruncated(t *testing.T) {
+	tests := [][]byte{
+		{'$', 0, 0},
+		{'$', 0, 0, 10, 1, 2, 3},
+		[]byte("RTSP/1.0 200 OK\r\nCSeq: 1\r\nContent-Length: 10\r\n\r\nabc"),
+		[]byte("garbage\r\n\r\n"),
+	}
+	for _, data := range tests {
+		c := &Client{reader: bufio.NewReader(bytes.NewReader(data))}
+		if _, _, err := c.ReadInterleaved(); err == nil {
+			t.Errorf("ReadInterleaved(%q): expected error", data)
+		}
+	}
+}
+
+func TestDialErrors(t *testing.T) {
+	for _, rawURL := range []string{"http://example.com/live", "rtsp://[::1/live"} {
+		if c, err := Dial(rawURL, time.Second); err == nil {
+			c.Close()
+			t.Errorf("Dial(%q): expected error", rawURL)
+		}
+	}
+}
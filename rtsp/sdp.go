@@ -0,0 +1,163 @@
+package rtsp
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Session 是 SDP 会话描述中探测需要的部分
+type Session struct {
+	Name    string
+	Control string // 会话级的 a=control
+	Medias  []Media
+}
+
+// Media 是 SDP 中的一个 m= 媒体描述
+type Media struct {
+	Type        string // video、audio 等
+	Proto       string // RTP/AVP 等
+	PayloadType int    // 第一个格式的负载类型
+	Encoding    string // a=rtpmap 中的编码名称，如 H264、MPEG4-GENERIC
+	Codec       string // 规范化的编码名称，如 H.264、AAC
+	ClockRate   int
+	Channels    int
+	Control     string            // 媒体级的 a=control
+	Fmtp        map[string]string // a=fmtp 参数
+}
+
+// 没有 a=rtpmap 时的静态负载类型 (RFC 3551)
+var staticPayloads = map[int]struct {
+	encoding  string
+	clockRate int
+}{
+	0:  {"PCMU", 8000},
+	8:  {"PCMA", 8000},
+	14: {"MPA", 90000},
+	26: {"JPEG", 90000},
+	32: {"MPV", 90000},
+	33: {"MP2T", 90000},
+}
+
+// codecNames 将 rtpmap 编码名称转换为与 MPEG-TS 检测一致的名称
+var codecNames = map[string]string{
+	"H264":          "H.264",
+	"H265":          "H.265",
+	"MPEG4-GENERIC": "AAC",
+	"MP4A-LATM":     "AAC-LATM",
+	"MPA":           "MP2",
+	"MPV":           "MPEG-2 Video",
+	"MP2T":          "MPEG-TS",
+	"PCMU":          "G.711 μ-law",
+	"PCMA":          "G.711 A-law",
+	"JPEG":          "JPEG",
+	"OPUS":          "Opus",
+	"AC3":           "AC-3",
+}
+
+// ParseSDP 解析 SDP 会话描述
+func ParseSDP(body []byte) (*Session, error) {
+	s := &Session{}
+	var media *Media
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 2 || line[1] != '=' {
+			continue
+		}
+		value := line[2:]
+		switch line[0] {
+		case 's':
+			s.Name = value
+		case 'm':
+			s.Medias = append(s.Medias, parseMediaLine(value))
+			media = &s.Medias[len(s.Medias)-1]
+		case 'a':
+			key, attr, _ := strings.Cut(value, ":")
+			if media == nil {
+				if key == "control" {
+					s.Control = attr
+				}
+				continue
+			}
+			parseMediaAttribute(media, key, attr)
+		}
+	}
+	for i := range s.Medias {
+		m := &s.Medias[i]
+		if m.Encoding == "" {
+			if static, ok := staticPayloads[m.PayloadType]; ok {
+				m.Encoding, m.ClockRate = static.encoding, static.clockRate
+			}
+		}
+		m.Codec = codecNames[strings.ToUpper(m.Encoding)]
+		if m.Codec == "" {
+			m.Codec = m.Encoding
+		}
+	}
+	return s, scanner.Err()
+}
+
+func parseMediaLine(value string) Media {
+	fields := strings.Fields(value)
+	m := Media{PayloadType: -1}
+	if len(fields) > 0 {
+		m.Type = fields[0]
+	}
+	if len(fields) > 2 {
+		m.Proto = fields[2]
+	}
+	if len(fields) > 3 {
+		if pt, err := strconv.Atoi(fields[3]); err == nil {
+			m.PayloadType = pt
+		}
+	}
+	return m
+}
+
+func parseMediaAttribute(m *Media, key, value string) {
+	switch key {
+	case "control":
+		m.Control = value
+	case "rtpmap":
+		pt, rest, _ := strings.Cut(value, " ")
+		if n, err := strconv.Atoi(pt); err != nil || n != m.PayloadType {
+			return
+		}
+		parts := strings.Split(rest, "/")
+		m.Encoding = parts[0]
+		if len(parts) > 1 {
+			m.ClockRate, _ = strconv.Atoi(parts[1])
+		}
+		if len(parts) > 2 {
+			m.Channels, _ = strconv.Atoi(parts[2])
+		}
+	case "fmtp":
+		pt, rest, _ := strings.Cut(value, " ")
+		if n, err := strconv.Atoi(pt); err != nil || n != m.PayloadType {
+			return
+		}
+		m.Fmtp = make(map[string]string)
+		for _, param := range strings.Split(rest, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if k != "" {
+				m.Fmtp[strings.ToLower(k)] = v
+			}
+		}
+	}
+}
+
+// ControlURL 解析媒体的控制地址：绝对地址直接使用，"*" 或空表示基地址（Content-Base
+// 或请求地址），相对地址与 ffmpeg 等播放器一样直接拼接在基地址之后
+func ControlURL(base string, control string) string {
+	switch {
+	case control == "" || control == "*":
+		return base
+	case strings.HasPrefix(strings.ToLower(control), "rtsp://"):
+		return control
+	case strings.HasSuffix(base, "/"):
+		return base + control
+	}
+	return base + "/" + control
+}
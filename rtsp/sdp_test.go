@@ -0,0 +1,91 @@
+package rtsp
+
+import (
+	"reflect"
+	"testing"
+)
+
+// cameraSDP 是常见网络摄像机 DESCRIBE 返回的 SDP
+const cameraSDP = "v=0\r\n" +
+	"o=- 1109162014219182 1 IN IP4 0.0.0.0\r\n" +
+	"s=Media Presentation\r\n" +
+	"c=IN IP4 0.0.0.0\r\n" +
+	"t=0 0\r\n" +
+	"a=control:*\r\n" +
+	"m=video 0 RTP/AVP 96\r\n" +
+	"a=rtpmap:96 H264/90000\r\n" +
+	"a=fmtp:96 profile-level-id=420029; packetization-mode=1; sprop-parameter-sets=Z00AKpWoHgCJ+WEAAAcIAAFfkAQ=,aO48gA==\r\n" +
+	"a=control:trackID=1\r\n" +
+	"m=audio 0 RTP/AVP 97\r\n" +
+	"a=rtpmap:97 MPEG4-GENERIC/16000/2\r\n" +
+	"a=fmtp:97 streamtype=5;profile-level-id=15;mode=AAC-hbr;config=1410;SizeLength=13\r\n" +
+	"a=control:rtsp://192.168.1.64:554/Streaming/Channels/101/trackID=2\r\n" +
+	"m=audio 0 RTP/AVP 8\r\n" +
+	"a=control:trackID=3\r\n" +
+	"m=application 0 RTP/AVP 107\r\n" +
+	"a=rtpmap:96 H265/90000\r\n" +
+	"a=rtpmap:107 vnd.onvif.metadata/90000\r\n"
+
+func TestParseSDP(t *testing.T) {
+	s, err := ParseSDP([]byte(cameraSDP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "Media Presentation" || s.Control != "*" {
+		t.Errorf("session name %q control %q", s.Name, s.Control)
+	}
+	want := []Media{
+		{
+			Type: "video", Proto: "RTP/AVP", PayloadType: 96, Encoding: "H264", Codec: "H.264", ClockRate: 90000,
+			Control: "trackID=1",
+			Fmtp: map[string]string{
+				"profile-level-id":     "420029",
+				"packetization-mode":   "1",
+				"sprop-parameter-sets": "Z00AKpWoHgCJ+WEAAAcIAAFfkAQ=,aO48gA==",
+			},
+		},
+		{
+			Type: "audio", Proto: "RTP/AVP", PayloadType: 97, Encoding: "MPEG4-GENERIC", Codec: "AAC", ClockRate: 16000, Channels: 2,
+			Control: "rtsp://192.168.1.64:554/Streaming/Channels/101/trackID=2",
+			Fmtp: map[string]string{
+				"streamtype": "5", "profile-level-id": "15", "mode": "AAC-hbr", "config": "1410", "sizelength": "13",
+			},
+		},
+		// 静态负载类型没有 rtpmap
+		{Type: "audio", Proto: "RTP/AVP", PayloadType: 8, Encoding: "PCMA", Codec: "G.711 A-law", ClockRate: 8000, Control: "trackID=3"},
+		// 负载类型不符的 rtpmap 被忽略，未知编码保留原名
+		{Type: "application", Proto: "RTP/AVP", PayloadType: 107, Encoding: "vnd.onvif.metadata", Codec: "vnd.onvif.metadata", ClockRate: 90000},
+	}
+	if !reflect.DeepEqual(s.Medias, want) {
+		t.Errorf("medias:\n got %+v\nwant %+v", s.Medias, want)
+	}
+}
+
+func TestParseSDPMalformed(t *testing.T) {
+	s, err := ParseSDP([]byte("garbage\nm=video\na=rtpmap:x H264/90000\nm=audio 0 RTP/AVP abc\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Media{{Type: "video", PayloadType: -1}, {Type: "audio", Proto: "RTP/AVP", PayloadType: -1}}
+	if !reflect.DeepEqual(s.Medias, want) {
+		t.Errorf("got %+v, want %+v", s.Medias, want)
+	}
+}
+
+func TestControlURL(t *testing.T) {
+	tests := []struct {
+		base, control, want string
+	}{
+		{"rtsp://cam/live", "", "rtsp://cam/live"},
+		{"rtsp://cam/live", "*", "rtsp://cam/live"},
+		{"rtsp://cam/live", "trackID=1", "rtsp://cam/live/trackID=1"},
+		{"rtsp://cam/live/", "trackID=1", "rtsp://cam/live/trackID=1"},
+		{"rtsp://cam/live", "rtsp://other/stream/track2", "rtsp://other/stream/track2"},
+		{"rtsp://cam/live", "RTSP://other/track2", "RTSP://other/track2"},
+	}
+	for _, tt := range tests {
+		if got := ControlURL(tt.base, tt.control); got != tt.want {
+			t.Errorf("ControlURL(%q, %q) = %q, want %q", tt.base, tt.control, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,443 @@
+package tester
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/dash"
+	"m3u8_selector/mp4"
+	"m3u8_selector/mpegts"
+	"m3u8_selector/video"
+)
+
+// dashSegments 是每个表示下载测速的片段数
+const dashSegments = 3
+
+// minLiveThreshold 是判断 DASH 直播停止更新的最短时间
+const minLiveThreshold = 30 * time.Second
+
+// isDASHURL 判断地址是否指向 MPEG-DASH 的 .mpd 清单
+func isDASHURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(u.Path), ".mpd")
+}
+
+// isMPD 判断数据是否为 MPD 清单，用于识别地址不以 .mpd 结尾的 DASH 直播源
+func isMPD(data []byte) bool {
+	return bytes.Contains(data[:min(len(data), 2048)], []byte("<MPD"))
+}
+
+// testDASH 测试 MPEG-DASH 直播源：解析 MPD，按 opts.VariantPolicy 选择表示，
+// 下载直播边缘的几个片段测速，并根据 MPD 的时间信息判断直播是否仍在更新
+func testDASH(rawURL string, opts Options) core.M3U8Source {
+	client := &http.Client{Timeout: opts.Timeout}
+
+	start := time.Now()
+	resp, err := client.Get(rawURL)
+	latency := time.Since(start)
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: fmt.Sprintf("HTTP %d", resp.StatusCode)}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSegmentSize))
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: err.Error()}
+	}
+
+	manifest, err := dash.Parse(body, resp.Request.URL.String())
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: fmt.Sprintf("Not a valid MPD file (%v)", err)}
+	}
+	if !manifest.Dynamic {
+		return core.M3U8Source{
+			URL:     rawURL,
+			Latency: latency,
+			Valid:   false,
+			Error:   "Static MPD (on demand), not live",
+			Live:    core.LiveStatic,
+		}
+	}
+	now := time.Now()
+	if manifest.AvailabilityStartTime.After(now) {
+		return core.M3U8Source{
+			URL:     rawURL,
+			Latency: latency,
+			Valid:   false,
+			Error:   fmt.Sprintf("Live stream starts at %s, not available yet", manifest.AvailabilityStartTime.Local().Format("2006-01-02 15:04:05")),
+		}
+	}
+
+	period := manifest.PeriodAt(now)
+	reps := dashRepresentations(period)
+	if len(reps) == 0 {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: "MPD has no representations"}
+	}
+	// 码率从高到低排列，相同码率保持清单中的顺序
+	sort.SliceStable(reps, func(i, j int) bool {
+		return reps[i].Bandwidth > reps[j].Bandwidth
+	})
+	reps = selectVariants(reps, opts.VariantPolicy)
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	results := make([]core.M3U8Source, len(reps))
+	var wg sync.WaitGroup
+	for i, rep := range reps {
+		wg.Add(1)
+		go func(i int, rep *dash.Representation) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i] = testDASHRepresentation(client, manifest, period, rep, now, opts.Timeout)
+		}(i, rep)
+	}
+	wg.Wait()
+
+	// 表示没有独立的地址，用 "清单地址#表示 ID" 标识
+	variantResults := make([]core.VariantResult, len(reps))
+	selected := -1
+	for i, rep := range reps {
+		variantResults[i] = core.VariantResult{
+			URL:           rawURL + "#" + rep.ID,
+			Bandwidth:     rep.Bandwidth,
+			Codecs:        rep.Codecs,
+			Valid:         results[i].Valid,
+			Error:         results[i].Error,
+			Latency:       results[i].Latency,
+			DownloadSpeed: results[i].DownloadSpeed,
+		}
+		if rep.Width > 0 && rep.Height > 0 {
+			variantResults[i].Resolution = fmt.Sprintf("%dx%d", rep.Width, rep.Height)
+		}
+		if selected < 0 && results[i].Valid {
+			selected = i
+		}
+	}
+
+	if selected < 0 {
+		return core.M3U8Source{
+			URL:      rawURL,
+			Latency:  latency,
+			Valid:    false,
+			Error:    fmt.Sprintf("All %d tested representations failed (first: %s)", len(reps), results[0].Error),
+			Variants: variantResults,
+		}
+	}
+
+	result := results[selected]
+	result.URL = rawURL
+	result.Latency += latency
+	result.Variants = variantResults
+	result.SelectedVariant = variantResults[selected].URL
+	if !opts.VerifyLive {
+		return result
+	}
+
+	edge := dashTimelineEdge(manifest, period, reps[selected], now)
+	state, detail := dashLiveness(manifest, edge, result.Measurement != core.Unmeasured, now)
+	if state == core.LiveVerified && edge.IsZero() && manifest.MinimumUpdatePeriod > 0 && !manifest.PublishTime.IsZero() {
+		state, detail = verifyDASHUpdate(client, rawURL, manifest, now, opts.Timeout)
+	}
+	result.Live = state
+	switch state {
+	case core.LiveFrozen:
+		result.Valid = false
+		result.Error = "Not live: " + detail
+	case core.LiveUnknown:
+		result.Error += " (liveness unverified: " + detail + ")"
+	}
+	return result
+}
+
+// dashRepresentations 返回时段中视频自适应集的表示，没有视频时返回所有表示（如纯音频直播）
+func dashRepresentations(p *dash.Period) []*dash.Representation {
+	var videos, all []*dash.Representation
+	for i := range p.AdaptationSets {
+		as := &p.AdaptationSets[i]
+		for j := range as.Representations {
+			all = append(all, &as.Representations[j])
+			if as.ContentType == "video" {
+				videos = append(videos, &as.Representations[j])
+			}
+		}
+	}
+	if len(videos) > 0 {
+		return videos
+	}
+	return all
+}
+
+// testDASHRepresentation 下载表示的初始化片段和直播边缘的几个媒体片段，检查片段格式并测速
+func testDASHRepresentation(client *http.Client, m *dash.Manifest, p *dash.Period, r *dash.Representation, now time.Time, timeout time.Duration) core.M3U8Source {
+	init, segments, err := m.Segments(p, r, now, dashSegments)
+	if err != nil {
+		return core.M3U8Source{Valid: false, Error: fmt.Sprintf("Representation %s: %v", r.ID, err)}
+	}
+
+	// 初始化片段不计入测速，fMP4 的初始化片段用于识别编码和画面参数
+	testStart := time.Now()
+	var fragments *fmp4Checker
+	if init != "" {
+		resp, err := client.Get(init)
+		if err != nil {
+			return core.M3U8Source{Valid: false, Error: fmt.Sprintf("Initialization segment: %v", err)}
+		}
+		data, err := readSegment(resp)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			return core.M3U8Source{Valid: false, Error: fmt.Sprintf("Initialization segment: HTTP %d", resp.StatusCode)}
+		}
+		if err != nil {
+			return core.M3U8Source{Valid: false, Error: fmt.Sprintf("Initialization segment: %v", err)}
+		}
+		if mp4.IsTopLevelBox(data) {
+			fragments = &fmp4Checker{client: client}
+			if err := fragments.setInit(init, data); err != nil {
+				return core.M3U8Source{Valid: false, Error: err.Error()}
+			}
+		}
+	}
+
+	var totalDataSize int64
+	var totalDownloadTime, totalMediaDuration time.Duration
+	var speeds []float64
+	var inspectErr error
+	var container core.Container
+	var codecs []string
+	continuityErrors := 0
+	var picture video.Info
+	var lastNumber uint64
+	hasLast := false
+
+	for _, segment := range segments {
+		if time.Since(testStart) > timeout {
+			break
+		}
+
+		downloadStart := time.Now()
+		resp, err := client.Get(segment.URL)
+		if err != nil {
+			continue
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			resp.Body.Close()
+			continue
+		}
+		data, err := readSegment(resp)
+		resp.Body.Close()
+		downloadTime := time.Since(downloadStart)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+
+		contiguous := hasLast && segment.Number == lastNumber+1
+		segContainer, info, err := inspectDASHSegment(r, fragments, data, contiguous)
+		if err != nil {
+			inspectErr = err
+			continue
+		}
+		if segContainer == core.ContainerUnknown && len(data) <= 10*1024 {
+			// 无法检查内容的片段，只有下载到足够的数据才算成功
+			continue
+		}
+		container = segContainer
+		switch {
+		case info != nil:
+			codecs = mergeCodecs(codecs, info.Codecs())
+			continuityErrors += info.ContinuityErrors
+			if picture.Width == 0 {
+				picture = detectPicture(info)
+			}
+		case segContainer == core.ContainerFMP4 && fragments != nil:
+			codecs = mergeCodecs(codecs, fragments.init.Codecs())
+			if picture.Width == 0 {
+				picture = fragments.picture()
+			}
+		}
+		lastNumber, hasLast = segment.Number, true
+
+		totalDataSize += int64(len(data))
+		totalDownloadTime += downloadTime
+		totalMediaDuration += segment.Duration
+		speeds = append(speeds, float64(len(data))/downloadTime.Seconds()/1024)
+	}
+
+	if len(speeds) == 0 && inspectErr != nil {
+		return core.M3U8Source{Valid: false, Error: inspectErr.Error()}
+	}
+	if len(speeds) == 0 {
+		return core.M3U8Source{
+			Valid:       true,
+			Error:       "OK (MPD valid, all segment downloads failed)",
+			Measurement: core.Unmeasured,
+		}
+	}
+
+	// 使用各片段速度的中位数，避免异常值影响
+	sort.Float64s(speeds)
+	n := len(speeds)
+	result := core.M3U8Source{
+		DownloadSpeed: speeds[n/2],
+		Valid:         true,
+		Error:         "OK",
+		DataSize:      totalDataSize / int64(n),
+		DownloadTime:  totalDownloadTime / time.Duration(n),
+		Measurement:   measurementFor(totalDataSize),
+		Container:     container,
+
+		Codecs:           codecs,
+		ContinuityErrors: continuityErrors,
+		Width:            picture.Width,
+		Height:           picture.Height,
+		FrameRate:        picture.FrameRate,
+	}
+	result.Bitrate, result.Headroom = playbackRate(totalDataSize, totalMediaDuration, totalDownloadTime)
+	if continuityErrors > 0 {
+		result.Error = fmt.Sprintf("OK (%d MPEG-TS continuity errors)", continuityErrors)
+	}
+	if fragments != nil && fragments.gaps > 0 {
+		result.ContinuityErrors += fragments.gaps
+		result.Error = fmt.Sprintf("OK (%d fMP4 decode time gaps)", fragments.gaps)
+	}
+	return result
+}
+
+// inspectDASHSegment 按数据开头识别媒体片段的格式并检查结构，MPEG-TS 片段同时返回节目信息。
+// 有 fMP4 初始化片段时由 fragments 检查 moof/mdat 和解码时间连续性。
+// WebM 等无法检查的格式返回 ContainerUnknown 和 nil 错误
+func inspectDASHSegment(r *dash.Representation, fragments *fmp4Checker, data []byte, contiguous bool) (core.Container, *mpegts.Info, error) {
+	switch container := sniffContainer(data); container {
+	case core.ContainerMPEGTS:
+		info, err := mpegts.Inspect(data)
+		if err != nil {
+			return container, nil, fmt.Errorf("segment is not valid MPEG-TS: %v", err)
+		}
+		return container, info, nil
+	case core.ContainerMP4:
+		if fragments != nil {
+			if err := fragments.check(data, contiguous); err != nil {
+				return container, nil, err
+			}
+			return core.ContainerFMP4, nil, nil
+		}
+		info, err := mp4.Inspect(data)
+		if err != nil {
+			return container, nil, fmt.Errorf("segment is not valid MP4: %v", err)
+		}
+		if info.Fragmented {
+			container = core.ContainerFMP4
+		}
+		return container, nil, nil
+	}
+	if strings.Contains(r.MimeType, "webm") {
+		return core.ContainerUnknown, nil, nil
+	}
+	return core.ContainerUnknown, nil, unrecognizedStream(data)
+}
+
+// dashTimelineEdge 返回使用 SegmentTimeline 的表示最后一个片段结束的时刻。
+// 按 availabilityStartTime 推算片段的表示没有独立的直播边缘，返回零值
+func dashTimelineEdge(m *dash.Manifest, p *dash.Period, r *dash.Representation, now time.Time) time.Time {
+	if r.Template == nil || len(r.Template.Timeline) == 0 || m.AvailabilityStartTime.IsZero() {
+		return time.Time{}
+	}
+	_, segments, err := m.Segments(p, r, now, 1)
+	if err != nil || len(segments) == 0 {
+		return time.Time{}
+	}
+	last := segments[len(segments)-1]
+	return m.AvailabilityStartTime.Add(p.Start + last.Start + last.Duration)
+}
+
+// dashLiveness 根据 MPD 的时间信息判断直播是否仍在更新。SegmentTimeline 的最后一个片段
+// 或 publishTime 距今超过阈值时认为已停止更新；按 availabilityStartTime 推算片段的 MPD
+// 没有直播边缘，直播边缘的片段能下载时先认为正在直播，声明了 minimumUpdatePeriod 的
+// 再由 verifyDASHUpdate 比较重新获取的 publishTime
+func dashLiveness(m *dash.Manifest, edge time.Time, downloaded bool, now time.Time) (core.LiveState, string) {
+	threshold := max(minLiveThreshold, 3*m.MaxSegmentDuration, 3*m.MinimumUpdatePeriod)
+
+	if edge.IsZero() {
+		if !downloaded {
+			return core.LiveUnknown, "segments at the live edge could not be downloaded"
+		}
+		return core.LiveVerified, ""
+	}
+	if lag := now.Sub(edge); lag > threshold {
+		return core.LiveFrozen, fmt.Sprintf("last segment in SegmentTimeline ended %v ago", lag.Round(time.Second))
+	}
+	if m.MinimumUpdatePeriod > 0 && !m.PublishTime.IsZero() {
+		if age := now.Sub(m.PublishTime); age > threshold {
+			return core.LiveFrozen, fmt.Sprintf("MPD publishTime is %v old", age.Round(time.Second))
+		}
+	}
+	return core.LiveVerified, ""
+}
+
+// verifyDASHUpdate 在 minimumUpdatePeriod 之后（不超过 timeout）重新获取 MPD，比较两次的 publishTime
+func verifyDASHUpdate(client *http.Client, manifestURL string, first *dash.Manifest, fetchedAt time.Time, timeout time.Duration) (core.LiveState, string) {
+	interval := min(max(first.MinimumUpdatePeriod, minReloadInterval), timeout)
+	time.Sleep(time.Until(fetchedAt.Add(interval)))
+
+	reloaded, err := reloadMPD(client, manifestURL)
+	if err != nil {
+		return core.LiveUnknown, err.Error()
+	}
+	if !reloaded.Dynamic {
+		return core.LiveStatic, "MPD became static on reload"
+	}
+	return dashPublishLiveness(first, reloaded, time.Now())
+}
+
+// dashPublishLiveness 比较两次获取的 MPD：publishTime 前进说明清单仍在更新；
+// 没有前进且距今超过阈值时认为已停止更新，否则可能只是还没到下一次发布
+func dashPublishLiveness(first, reloaded *dash.Manifest, now time.Time) (core.LiveState, string) {
+	if reloaded.PublishTime.After(first.PublishTime) {
+		return core.LiveVerified, ""
+	}
+	threshold := max(minLiveThreshold, 3*first.MaxSegmentDuration, 3*first.MinimumUpdatePeriod)
+	if age := now.Sub(first.PublishTime); age > threshold {
+		return core.LiveFrozen, fmt.Sprintf("MPD publishTime did not advance on reload and is %v old", age.Round(time.Second))
+	}
+	return core.LiveVerified, ""
+}
+
+// reloadMPD 重新获取并解析 MPD
+func reloadMPD(client *http.Client, manifestURL string) (*dash.Manifest, error) {
+	resp, err := client.Get(manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("MPD reload failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("MPD reload returned HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSegmentSize))
+	if err != nil {
+		return nil, fmt.Errorf("MPD reload failed: %v", err)
+	}
+	manifest, err := dash.Parse(body, resp.Request.URL.String())
+	if err != nil {
+		return nil, fmt.Errorf("reloaded MPD is invalid: %v", err)
+	}
+	return manifest, nil
+}
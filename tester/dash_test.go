@@ -0,0 +1,75 @@
+package tester
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/dash"
+)
+
+func TestDASHPublishLiveness(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		first, reload time.Time
+		want          core.LiveState
+	}{
+		{"publishTime advanced", now.Add(-time.Hour), now.Add(-time.Second), core.LiveVerified},
+		{"old publishTime unchanged", now.Add(-time.Hour), now.Add(-time.Hour), core.LiveFrozen},
+		{"recent publishTime unchanged", now.Add(-5 * time.Second), now.Add(-5 * time.Second), core.LiveVerified},
+		{"publishTime went back", now.Add(-time.Hour), now.Add(-2 * time.Hour), core.LiveFrozen},
+	}
+	for _, tt := range tests {
+		first := &dash.Manifest{Dynamic: true, PublishTime: tt.first, MinimumUpdatePeriod: 2 * time.Second}
+		reloaded := &dash.Manifest{Dynamic: true, PublishTime: tt.reload, MinimumUpdatePeriod: 2 * time.Second}
+		if got, detail := dashPublishLiveness(first, reloaded, now); got != tt.want {
+			t.Errorf("%s: got %s (%s), want %s", tt.name, got, detail, tt.want)
+		}
+	}
+}
+
+// numberMPD 是按 $Number$ 推算片段、没有 SegmentTimeline 的直播 MPD
+const numberMPD = `<MPD type="dynamic" availabilityStartTime="2024-01-01T00:00:00Z" publishTime="%s" minimumUpdatePeriod="PT1S">
+	<Period start="PT0S"><AdaptationSet mimeType="video/mp4">
+		<SegmentTemplate timescale="1" duration="2" media="$Number$.m4s"/>
+		<Representation id="v" bandwidth="1000000"/>
+	</AdaptationSet></Period>
+</MPD>`
+
+func TestVerifyDASHUpdate(t *testing.T) {
+	frozenAt := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	tests := []struct {
+		name        string
+		publishTime func() string
+		want        core.LiveState
+	}{
+		{"frozen", func() string { return frozenAt }, core.LiveFrozen},
+		{"updating", func() string { return time.Now().UTC().Format(time.RFC3339Nano) }, core.LiveVerified},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, numberMPD, tt.publishTime())
+		}))
+		client := server.Client()
+		manifestURL := server.URL + "/live.mpd"
+
+		fetchedAt := time.Now()
+		first, err := reloadMPD(client, manifestURL)
+		if err != nil {
+			server.Close()
+			t.Fatal(err)
+		}
+		got, detail := verifyDASHUpdate(client, manifestURL, first, fetchedAt, 5*time.Second)
+		server.Close()
+		if got != tt.want {
+			t.Errorf("%s: got %s (%s), want %s", tt.name, got, detail, tt.want)
+		}
+		if elapsed := time.Since(fetchedAt); elapsed < time.Second {
+			t.Errorf("%s: reloaded after %v, before minimumUpdatePeriod", tt.name, elapsed)
+		}
+	}
+}
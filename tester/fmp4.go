@@ -0,0 +1,87 @@
+package tester
+
+import (
+	"fmt"
+	"net/http"
+
+	"m3u8_selector/hls"
+	"m3u8_selector/mp4"
+	"m3u8_selector/video"
+)
+
+// fmp4Checker 检查 fMP4/CMAF 媒体片段：从初始化片段识别编码和画面参数，
+// 检查媒体片段的 moof/mdat 结构以及相邻片段的 baseMediaDecodeTime 是否连续
+type fmp4Checker struct {
+	client   *http.Client
+	init     *mp4.Init
+	initKey  string // 已解析的初始化片段的地址和范围
+	timeline *mp4.Timeline
+	gaps     int       // 解码时间不连续的次数
+	last     *mp4.Info // 最后一个检查通过的媒体片段
+}
+
+// loadInit 下载并解析 #EXT-X-MAP 指定的初始化片段，地址和范围不变时沿用已解析的结果
+func (c *fmp4Checker) loadInit(m *hls.Map) error {
+	key := m.URI
+	if r := m.ByteRange; r != nil {
+		key += fmt.Sprintf("@%d-%d", r.Offset, r.Offset+r.Length-1)
+	}
+	if key == c.initKey {
+		return nil
+	}
+
+	resp, err := fetchSegment(c.client, hls.Segment{URI: m.URI, ByteRange: m.ByteRange})
+	if err != nil {
+		return fmt.Errorf("init segment: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("init segment: HTTP %d", resp.StatusCode)
+	}
+	data, err := readSegment(resp)
+	if err != nil {
+		return fmt.Errorf("init segment: %v", err)
+	}
+	return c.setInit(key, data)
+}
+
+// setInit 解析初始化片段，并为新的初始化片段重新开始时间线
+func (c *fmp4Checker) setInit(key string, data []byte) error {
+	init, err := mp4.ParseInit(data)
+	if err != nil {
+		return fmt.Errorf("invalid fMP4 init segment: %v", err)
+	}
+	c.init, c.initKey, c.timeline = init, key, mp4.NewTimeline(init)
+	return nil
+}
+
+// check 检查一个媒体片段的结构并记录解码时间。contiguous 为 false 时片段与上一个检查的片段不相邻，
+// 不比较解码时间
+func (c *fmp4Checker) check(data []byte, contiguous bool) error {
+	info, err := mp4.Inspect(data)
+	if err != nil {
+		return fmt.Errorf("segment is not valid fMP4: %v", err)
+	}
+	if info.Fragments == 0 {
+		return fmt.Errorf("segment is not valid fMP4: no moof box found")
+	}
+	if !contiguous {
+		c.timeline.Reset()
+	}
+	c.gaps += c.timeline.Add(info.TrackFragments)
+	c.last = info
+	return nil
+}
+
+// picture 返回视频轨道的画面参数，SPS 中没有帧率时按最后一个媒体片段的样本时长估算
+func (c *fmp4Checker) picture() video.Info {
+	t := c.init.VideoTrack()
+	if t == nil {
+		return video.Info{}
+	}
+	picture := video.Info{Codec: t.Codec, Width: t.Width, Height: t.Height, FrameRate: t.FrameRate}
+	if picture.FrameRate == 0 && c.last != nil {
+		picture.FrameRate = c.init.FrameRate(c.last.TrackFragments)
+	}
+	return picture
+}
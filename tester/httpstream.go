@@ -0,0 +1,273 @@
+package tester
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/flv"
+	"m3u8_selector/mp4"
+	"m3u8_selector/mpegts"
+)
+
+// testHTTPStream 拉取非 HLS 的 HTTP 流，在采样窗口内统计吞吐量，
+// 再按数据开头识别容器格式，交给对应格式的检查
+func testHTTPStream(rawURL string, opts Options) core.M3U8Source {
+	window := opts.SampleWindow
+	if window <= 0 || window > opts.Timeout {
+		window = opts.Timeout
+	}
+	client := &http.Client{Timeout: opts.Timeout + window}
+
+	start := time.Now()
+	resp, err := client.Get(rawURL)
+	latency := time.Since(start)
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: fmt.Sprintf("HTTP %d", resp.StatusCode)}
+	}
+
+	// 直播流没有结尾，从收到第一块数据开始读取一个采样窗口；普通文件读到结尾为止
+	var data []byte
+	var first, last time.Time
+	buf := make([]byte, 64*1024)
+	for len(data) < maxSegmentSize {
+		n, err := resp.Body.Read(buf)
+		now := time.Now()
+		if n > 0 {
+			if first.IsZero() {
+				first = now
+			}
+			data = append(data, buf[:n]...)
+			last = now
+		}
+		if err != nil {
+			if err != io.EOF && len(data) == 0 {
+				return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: fmt.Sprintf("Read failed: %v", err)}
+			}
+			break
+		}
+		if !first.IsZero() && now.Sub(first) >= window {
+			break
+		}
+	}
+	if len(data) == 0 {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: "Empty response"}
+	}
+
+	elapsed := last.Sub(first)
+	if elapsed <= 0 {
+		elapsed = time.Since(first)
+	}
+	size := int64(len(data))
+	result := core.M3U8Source{
+		URL:           rawURL,
+		Latency:       latency,
+		DownloadSpeed: float64(size) / elapsed.Seconds() / 1024,
+		Valid:         true,
+		Error:         "OK",
+		DataSize:      size,
+		DownloadTime:  elapsed,
+		Measurement:   measurementFor(size),
+		Container:     sniffContainer(data),
+	}
+
+	// 地址不以 .mpd 结尾的 DASH 清单
+	if result.Container == core.ContainerUnknown && isMPD(data) {
+		return testDASH(rawURL, opts)
+	}
+
+	switch result.Container {
+	case core.ContainerMPEGTS:
+		err = checkTSStream(&result, data)
+	case core.ContainerFLV:
+		err = checkFLVStream(&result, data)
+	case core.ContainerMP4:
+		err = checkMP4Stream(&result, data)
+	case core.ContainerADTS:
+		err = checkADTSStream(&result, data)
+	default:
+		err = unrecognizedStream(data)
+	}
+	if err != nil {
+		result.Valid = false
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// sniffContainer 根据数据开头识别容器格式，无法识别时返回 ContainerUnknown
+func sniffContainer(data []byte) core.Container {
+	switch {
+	case flv.IsFLV(data):
+		return core.ContainerFLV
+	case hasTSSync(data):
+		return core.ContainerMPEGTS
+	case mp4.IsTopLevelBox(data):
+		// 是否分片由 checkMP4Stream 根据盒子结构确定
+		return core.ContainerMP4
+	case isADTS(skipID3(data)):
+		return core.ContainerADTS
+	}
+	return core.ContainerUnknown
+}
+
+// hasTSSync 判断数据开头的 188 字节内是否有连续三个间隔 188 字节的同步字节。数据不足三个包时只检查第一个字节
+func hasTSSync(data []byte) bool {
+	if len(data) < 3*mpegts.PacketSize {
+		return len(data) > 0 && data[0] == mpegts.SyncByte
+	}
+	for i := 0; i < mpegts.PacketSize && i+2*mpegts.PacketSize < len(data); i++ {
+		if data[i] == mpegts.SyncByte && data[i+mpegts.PacketSize] == mpegts.SyncByte && data[i+2*mpegts.PacketSize] == mpegts.SyncByte {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTSStream 按 MPEG-TS 检查数据，记录编码、连续计数器错误和画面参数
+func checkTSStream(result *core.M3U8Source, data []byte) error {
+	info, err := mpegts.Inspect(data)
+	if err != nil {
+		return fmt.Errorf("Stream is not valid MPEG-TS: %v", err)
+	}
+	result.Codecs = info.Codecs()
+	result.ContinuityErrors = info.ContinuityErrors
+	picture := detectPicture(info)
+	result.Width, result.Height, result.FrameRate = picture.Width, picture.Height, picture.FrameRate
+	if info.ContinuityErrors > 0 {
+		result.Error = fmt.Sprintf("OK (%d MPEG-TS continuity errors)", info.ContinuityErrors)
+	}
+	return nil
+}
+
+// checkFLVStream 按 FLV 检查数据，从 onMetaData 和音视频标签中识别编码和画面参数，按标签时间戳计算媒体码率
+func checkFLVStream(result *core.M3U8Source, data []byte) error {
+	info, err := flv.Inspect(data)
+	if err != nil {
+		return fmt.Errorf("Stream is not valid FLV: %v", err)
+	}
+	result.Codecs = info.Codecs()
+	result.Width, result.Height, result.FrameRate = info.Width, info.Height, info.FrameRate
+	result.Bitrate, _ = playbackRate(result.DataSize, info.Duration, result.DownloadTime)
+	if info.VideoTags == 0 {
+		result.Error = "OK (audio only)"
+	}
+	return nil
+}
+
+// checkMP4Stream 检查 MP4/fMP4 的顶层盒子结构，有分片时记录为 fMP4
+func checkMP4Stream(result *core.M3U8Source, data []byte) error {
+	info, err := mp4.Inspect(data)
+	if err != nil {
+		return fmt.Errorf("Stream is not valid MP4: %v", err)
+	}
+	if info.Fragmented {
+		result.Container = core.ContainerFMP4
+	}
+	return nil
+}
+
+// checkADTSStream 逐帧检查 ADTS 音频，按帧数和采样率计算媒体码率
+func checkADTSStream(result *core.M3U8Source, data []byte) error {
+	frames, duration, err := inspectADTS(skipID3(data))
+	if err != nil {
+		return fmt.Errorf("Stream is not valid ADTS: %v", err)
+	}
+	result.Codecs = []string{"AAC"}
+	result.Bitrate, _ = playbackRate(result.DataSize, duration, result.DownloadTime)
+	result.Error = fmt.Sprintf("OK (audio only, %d AAC frames)", frames)
+	return nil
+}
+
+// adtsSampleRates 是 ADTS 头中采样率索引对应的采样率
+var adtsSampleRates = [...]int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// adtsHeaderSize 是不带 CRC 的 ADTS 头长度
+const adtsHeaderSize = 7
+
+// isADTS 判断数据是否以 ADTS 同步字开头（12 位全 1，layer 为 0）
+func isADTS(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0xFF && data[1]&0xF6 == 0xF0
+}
+
+// inspectADTS 从数据开头逐帧检查 ADTS，返回完整帧数和按采样率计算的音频时长。
+// 至少需要两个连续的帧，最后一个不完整的帧会被忽略。
+func inspectADTS(data []byte) (frames int, duration time.Duration, err error) {
+	var samples float64
+	for len(data) >= adtsHeaderSize && isADTS(data) {
+		frameLen := int(data[3]&0x03)<<11 | int(data[4])<<3 | int(data[5])>>5
+		rateIndex := int(data[2]>>2) & 0x0F
+		if frameLen < adtsHeaderSize || rateIndex >= len(adtsSampleRates) {
+			break
+		}
+		if frameLen > len(data) {
+			break
+		}
+		blocks := int(data[6]&0x03) + 1
+		samples += float64(1024*blocks) / float64(adtsSampleRates[rateIndex])
+		frames++
+		data = data[frameLen:]
+	}
+	if frames < 2 {
+		return frames, 0, fmt.Errorf("found %d consecutive frames", frames)
+	}
+	return frames, time.Duration(samples * float64(time.Second)), nil
+}
+
+// skipID3 跳过数据开头的 ID3v2 标签
+func skipID3(data []byte) []byte {
+	if len(data) < 10 || !bytes.HasPrefix(data, []byte("ID3")) {
+		return data
+	}
+	// 标签大小是 4 个 7 位的同步安全整数，不含 10 字节的标签头
+	size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+	size += 10
+	if data[5]&0x10 != 0 { // 带标签尾
+		size += 10
+	}
+	return data[min(size, len(data)):]
+}
+
+// unrecognizedStream 为无法识别的数据生成错误，区分 HTML 页面、JSON 响应和其他文本
+func unrecognizedStream(data []byte) error {
+	head := data[:min(len(data), 1024)]
+	text := strings.TrimSpace(strings.TrimPrefix(string(head), "\uFEFF"))
+	lower := strings.ToLower(text)
+	switch {
+	case strings.HasPrefix(lower, "<!doctype html") || strings.Contains(lower, "<html"):
+		return fmt.Errorf("HTML page instead of stream")
+	case isText(head) && (strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")):
+		return fmt.Errorf("JSON error response: %s", firstLine(text))
+	case isText(head):
+		return fmt.Errorf("Text response instead of stream: %s", firstLine(text))
+	}
+	return fmt.Errorf("Unrecognized stream format (starts with % x)", data[:min(len(data), 8)])
+}
+
+// isText 判断数据中是否没有除空白以外的控制字符
+func isText(data []byte) bool {
+	for _, b := range data {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// firstLine 返回文本的第一行，最多 80 个字符，用于错误信息
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	line = strings.TrimSpace(line)
+	if runes := []rune(line); len(runes) > 80 {
+		line = string(runes[:80]) + "..."
+	}
+	return line
+}
@@ -0,0 +1,153 @@
+package tester
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"m3u8_selector/core"
+)
+
+// adtsFrame 构造一个 48kHz 双声道、不带 CRC、总长 size 字节的 ADTS 帧
+func adtsFrame(size int) []byte {
+	frame := make([]byte, size)
+	frame[0] = 0xFF
+	frame[1] = 0xF1
+	frame[2] = 1<<6 | 3<<2 // AAC LC，采样率索引 3 (48kHz)
+	frame[3] = 2<<6 | byte(size>>11)&0x03
+	frame[4] = byte(size >> 3)
+	frame[5] = byte(size)<<5 | 0x1F
+	frame[6] = 0xFC
+	return frame
+}
+
+func adtsStream(frames int) []byte {
+	var data []byte
+	for i := 0; i < frames; i++ {
+		data = append(data, adtsFrame(100)...)
+	}
+	return data
+}
+
+// id3Tag 构造一个内容长度为 size 的 ID3v2.4 标签，footer 为 true 时带 10 字节标签尾
+func id3Tag(size int, footer bool) []byte {
+	tag := []byte{'I', 'D', '3', 4, 0, 0,
+		byte(size>>21) & 0x7F, byte(size>>14) & 0x7F, byte(size>>7) & 0x7F, byte(size) & 0x7F}
+	if footer {
+		tag[5] = 0x10
+	}
+	tag = append(tag, make([]byte, size)...)
+	if footer {
+		tag = append(tag, '3', 'D', 'I', 4, 0, 0x10, tag[6], tag[7], tag[8], tag[9])
+	}
+	return tag
+}
+
+// mp4Box 构造一个 32 位长度的 MP4 盒子
+func mp4Box(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(box, boxType...), body...)
+}
+
+func flvHeader() []byte {
+	return []byte{'F', 'L', 'V', 1, 0x05, 0, 0, 0, 9, 0, 0, 0, 0}
+}
+
+func TestSniffContainer(t *testing.T) {
+	ftyp := mp4Box("ftyp", []byte("iso6\x00\x00\x00\x00iso6cmfc"))
+	tests := []struct {
+		name string
+		data []byte
+		want core.Container
+	}{
+		{"mpeg-ts", buildTS(5, 0), core.ContainerMPEGTS},
+		{"mpeg-ts with leading garbage", append([]byte{0, 0, 0}, buildTS(5, 0)...), core.ContainerMPEGTS},
+		{"single ts packet", buildTS(0, 0)[:188], core.ContainerMPEGTS},
+		{"flv", append(flvHeader(), 0x12, 0, 0, 0), core.ContainerFLV},
+		{"fmp4 init segment", append(ftyp, mp4Box("moov", mp4Box("mvex"))...), core.ContainerMP4},
+		{"fmp4 media segment", append(mp4Box("moof", mp4Box("mfhd", make([]byte, 8))), mp4Box("mdat", make([]byte, 16))...), core.ContainerMP4},
+		{"adts", adtsStream(3), core.ContainerADTS},
+		{"id3 and adts", append(id3Tag(200, false), adtsStream(3)...), core.ContainerADTS},
+		{"id3 with footer and adts", append(id3Tag(20, true), adtsStream(3)...), core.ContainerADTS},
+		{"id3 only", id3Tag(20, false), core.ContainerUnknown},
+		{"html", []byte("<!DOCTYPE html><html><body>404</body></html>"), core.ContainerUnknown},
+		{"unknown bytes", []byte{0x1A, 0x45, 0xDF, 0xA3, 0x9F, 0x42, 0x86, 0x81}, core.ContainerUnknown},
+		{"flv version 2", []byte{'F', 'L', 'V', 2, 0x05, 0, 0, 0, 9}, core.ContainerUnknown},
+		{"empty", nil, core.ContainerUnknown},
+	}
+	for _, tt := range tests {
+		if got := sniffContainer(tt.data); got != tt.want {
+			t.Errorf("%s: sniffContainer = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSkipID3(t *testing.T) {
+	audio := adtsStream(1)
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{"no tag", audio, audio},
+		{"tag", append(id3Tag(300, false), audio...), audio},
+		{"tag with footer", append(id3Tag(30, true), audio...), audio},
+		{"truncated tag", id3Tag(300, false)[:100], []byte{}},
+		{"short data", []byte("ID3"), []byte("ID3")},
+	}
+	for _, tt := range tests {
+		if got := skipID3(tt.data); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: skipID3 left % x, want % x", tt.name, got[:min(len(got), 8)], tt.want[:min(len(tt.want), 8)])
+		}
+	}
+}
+
+func TestInspectADTS(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         []byte
+		wantFrames   int
+		wantDuration time.Duration
+		wantErr      bool
+	}{
+		{"three frames", adtsStream(3), 3, 64 * time.Millisecond, false},
+		{"truncated last frame", adtsStream(4)[:350], 3, 64 * time.Millisecond, false},
+		{"trailing garbage", append(adtsStream(2), 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07), 2, 42666666 * time.Nanosecond, false},
+		{"single frame", adtsStream(1), 1, 0, true},
+		{"not adts", []byte("#EXTM3U\n#EXTINF:10,\n"), 0, 0, true},
+	}
+	for _, tt := range tests {
+		frames, duration, err := inspectADTS(tt.data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if frames != tt.wantFrames || duration != tt.wantDuration {
+			t.Errorf("%s: got %d frames, %v, want %d frames, %v", tt.name, frames, duration, tt.wantFrames, tt.wantDuration)
+		}
+	}
+}
+
+func TestCheckMP4Stream(t *testing.T) {
+	ftyp := mp4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2"))
+	tests := []struct {
+		name string
+		data []byte
+		want core.Container
+	}{
+		{"mp4", bytes.Join([][]byte{ftyp, mp4Box("moov", mp4Box("mvhd", make([]byte, 100))), mp4Box("mdat", make([]byte, 64))}, nil), core.ContainerMP4},
+		{"fmp4", bytes.Join([][]byte{ftyp, mp4Box("moov", mp4Box("mvex")), mp4Box("moof", mp4Box("mfhd", make([]byte, 8))), mp4Box("mdat", make([]byte, 64))}, nil), core.ContainerFMP4},
+	}
+	for _, tt := range tests {
+		result := core.M3U8Source{Container: sniffContainer(tt.data)}
+		if err := checkMP4Stream(&result, tt.data); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if result.Container != tt.want {
+			t.Errorf("%s: container %q, want %q", tt.name, result.Container, tt.want)
+		}
+	}
+}
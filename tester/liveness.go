@@ -0,0 +1,113 @@
+package tester
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/hls"
+)
+
+// minReloadInterval 是重新加载播放列表的最短等待时间
+const minReloadInterval = time.Second
+
+// verifyLiveness 在第一次获取播放列表约一个目标时长后重新加载媒体播放列表，
+// 检查 #EXT-X-MEDIA-SEQUENCE 是否前进、是否出现新片段。第一次重新加载没有变化时
+// 再等待一个目标时长重试一次，避免把更新稍慢的服务器误判为停止更新。
+func verifyLiveness(client *http.Client, playlistURL string, first *hls.MediaPlaylist, fetchedAt time.Time, timeout time.Duration) (core.LiveState, string) {
+	interval := first.TargetDuration
+	if interval < minReloadInterval {
+		interval = minReloadInterval
+	}
+	if interval > timeout {
+		interval = timeout
+	}
+
+	firstURIs := make(map[string]bool, len(first.Segments))
+	for _, segment := range first.Segments {
+		firstURIs[segment.URI] = true
+	}
+	lastSequence := first.Segments[len(first.Segments)-1].Sequence
+
+	deadline := fetchedAt
+	for attempt := 0; attempt < 2; attempt++ {
+		deadline = deadline.Add(interval)
+		time.Sleep(time.Until(deadline))
+
+		reloaded, err := reloadMediaPlaylist(client, playlistURL)
+		if err != nil {
+			return core.LiveUnknown, err.Error()
+		}
+		if reloaded.EndList || reloaded.PlaylistType == "VOD" {
+			return core.LiveStatic, "playlist ended (#EXT-X-ENDLIST) on reload"
+		}
+
+		newSegments := []hls.Segment{}
+		for _, segment := range reloaded.Segments {
+			if segment.Sequence > lastSequence {
+				newSegments = append(newSegments, segment)
+			}
+		}
+		if reloaded.MediaSequence == first.MediaSequence && len(newSegments) == 0 {
+			continue
+		}
+
+		for _, segment := range newSegments {
+			if firstURIs[segment.URI] {
+				return core.LiveLooping, fmt.Sprintf("new segment #%d reuses earlier segment %s", segment.Sequence, segment.URI)
+			}
+		}
+		if reloaded.MediaSequence < first.MediaSequence {
+			return core.LiveLooping, fmt.Sprintf("media sequence went back from %d to %d", first.MediaSequence, reloaded.MediaSequence)
+		}
+		return core.LiveVerified, ""
+	}
+
+	return core.LiveFrozen, fmt.Sprintf("media sequence stayed at %d with no new segments after %v",
+		first.MediaSequence, time.Since(fetchedAt).Round(time.Second))
+}
+
+// reloadMediaPlaylist 重新获取并解析媒体播放列表
+func reloadMediaPlaylist(client *http.Client, playlistURL string) (*hls.MediaPlaylist, error) {
+	resp, err := client.Get(playlistURL)
+	if err != nil {
+		return nil, fmt.Errorf("reload failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("reload returned HTTP %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reload failed: %v", err)
+	}
+
+	playlist, err := hls.Parse(string(body), resp.Request.URL.String())
+	if err != nil {
+		return nil, fmt.Errorf("reloaded playlist is invalid: %v", err)
+	}
+	media, ok := playlist.(*hls.MediaPlaylist)
+	if !ok {
+		return nil, fmt.Errorf("reloaded playlist is not a media playlist")
+	}
+	return media, nil
+}
+
+// repeatedSegment 返回在播放列表中重复出现的片段地址
+func repeatedSegment(segments []hls.Segment) (string, bool) {
+	seen := make(map[string]bool, len(segments))
+	for _, segment := range segments {
+		// 使用字节范围的片段共享同一个地址，不算重复
+		if segment.ByteRange != nil {
+			continue
+		}
+		if seen[segment.URI] {
+			return segment.URI, true
+		}
+		seen[segment.URI] = true
+	}
+	return "", false
+}
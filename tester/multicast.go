@@ -0,0 +1,293 @@
+package tester
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/mpegts"
+)
+
+// udpReadBuffer 是接收组播数据的 socket 缓冲区大小，码率较高的节目需要较大的缓冲区避免丢包
+const udpReadBuffer = 4 * 1024 * 1024
+
+// isMulticastURL 判断是否为 udp:// 或 rtp:// 地址
+func isMulticastURL(rawURL string) bool {
+	lower := strings.ToLower(rawURL)
+	return strings.HasPrefix(lower, "udp://") || strings.HasPrefix(lower, "rtp://")
+}
+
+// parseMulticastURL 解析 udp://[源地址]@组地址:端口 或 rtp://组地址:端口，
+// 返回组地址。源地址（SSM）只用于说明，加入组播组时不区分发送源。
+func parseMulticastURL(rawURL string) (*net.UDPAddr, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid multicast URL: %v", err)
+	}
+	addr, err := net.ResolveUDPAddr("udp", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve UDP address: %v", err)
+	}
+	if addr.Port == 0 {
+		return nil, fmt.Errorf("multicast URL %s has no port", rawURL)
+	}
+	return addr, nil
+}
+
+// listenMulticast 在指定网卡上加入组播组。地址不是组播地址时监听本机端口，接收单播推流
+func listenMulticast(group *net.UDPAddr, ifname string) (*net.UDPConn, error) {
+	var ifi *net.Interface
+	if ifname != "" {
+		var err error
+		if ifi, err = net.InterfaceByName(ifname); err != nil {
+			return nil, fmt.Errorf("network interface %s: %v", ifname, err)
+		}
+	}
+	if group.IP != nil && group.IP.IsMulticast() {
+		return net.ListenMulticastUDP("udp", ifi, group)
+	}
+	return net.ListenUDP("udp", &net.UDPAddr{Port: group.Port})
+}
+
+// testMulticast 加入组播组，在采样窗口内接收数据，统计码率、RTP 丢包和抖动，
+// 并按 MPEG-TS 检查收到的数据。等待第一个包的时间计为延迟，采样窗口从第一个包开始。
+func testMulticast(rawURL string, opts Options) core.M3U8Source {
+	start := time.Now()
+	group, err := parseMulticastURL(rawURL)
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Valid: false, Error: err.Error()}
+	}
+
+	conn, err := listenMulticast(group, opts.MulticastInterface)
+	if err != nil {
+		return core.M3U8Source{
+			URL:     rawURL,
+			Latency: time.Since(start),
+			Valid:   false,
+			Error:   fmt.Sprintf("Failed to join multicast group: %v", err),
+		}
+	}
+	defer conn.Close()
+	conn.SetReadBuffer(udpReadBuffer)
+
+	window := opts.SampleWindow
+	if window <= 0 || window > opts.Timeout {
+		window = opts.Timeout
+	}
+
+	var stats udpStats
+	var latency time.Duration
+	deadline := start.Add(opts.Timeout)
+	buf := make([]byte, 64*1024)
+	for {
+		conn.SetReadDeadline(deadline)
+		n, _, err := conn.ReadFromUDP(buf)
+		now := time.Now()
+		if err != nil {
+			break
+		}
+		if stats.packets == 0 {
+			latency = now.Sub(start)
+			deadline = now.Add(window)
+		}
+		stats.add(buf[:n], now)
+	}
+
+	if stats.packets == 0 {
+		return core.M3U8Source{
+			URL:     rawURL,
+			Latency: time.Since(start),
+			Valid:   false,
+			Error:   fmt.Sprintf("No data received from %s within %v", group, opts.Timeout),
+		}
+	}
+	return stats.result(rawURL, latency, window)
+}
+
+// udpStats 统计收到的 UDP/RTP 数据
+type udpStats struct {
+	packets int64
+	bytes   int64 // 去掉 RTP 头后的负载字节数
+	first   time.Time
+	last    time.Time
+	ts      []byte // 用于 MPEG-TS 检查的负载数据
+
+	rtp *rtpTracker // 由第一个包判断是否为 RTP 封装，不是 RTP 时为 nil
+
+	// 没有 RTP 时间戳时，按到达间隔的变化估算抖动
+	jitter  float64
+	lastGap time.Duration
+}
+
+// rtpClockRate 是 MPEG-TS over RTP (payload type 33) 的时钟频率
+const rtpClockRate = 90000
+
+func (s *udpStats) add(packet []byte, now time.Time) {
+	if s.packets == 0 {
+		s.first = now
+		if len(packet) > 0 && packet[0] != mpegts.SyncByte && isRTP(packet) {
+			s.rtp = &rtpTracker{clockRate: rtpClockRate}
+		}
+	}
+
+	payload := packet
+	if s.rtp != nil {
+		var seq uint16
+		var timestamp uint32
+		var ok bool
+		if payload, seq, timestamp, ok = rtpPayload(packet); !ok {
+			return
+		}
+		s.rtp.add(seq, timestamp, now)
+	} else if s.packets > 0 {
+		gap := now.Sub(s.last)
+		d := (gap - s.lastGap).Seconds()
+		if d < 0 {
+			d = -d
+		}
+		s.jitter += (d - s.jitter) / 16
+		s.lastGap = gap
+	}
+
+	s.packets++
+	s.bytes += int64(len(payload))
+	s.last = now
+	if len(s.ts)+len(payload) <= maxSegmentSize {
+		s.ts = append(s.ts, payload...)
+	}
+}
+
+// lost 返回根据 RTP 序列号计算的丢包数，不是 RTP 时返回 0
+func (s *udpStats) lost() int64 {
+	if s.rtp == nil {
+		return 0
+	}
+	return s.rtp.lost()
+}
+
+// jitterDuration 返回到达间隔抖动
+func (s *udpStats) jitterDuration() time.Duration {
+	if s.rtp != nil {
+		return s.rtp.jitterDuration()
+	}
+	return time.Duration(s.jitter * float64(time.Second))
+}
+
+func (s *udpStats) result(rawURL string, latency, window time.Duration) core.M3U8Source {
+	elapsed := s.last.Sub(s.first)
+	if elapsed <= 0 {
+		elapsed = window
+	}
+	result := core.M3U8Source{
+		URL:           rawURL,
+		Latency:       latency,
+		DownloadSpeed: float64(s.bytes) / elapsed.Seconds() / 1024,
+		Valid:         true,
+		Error:         "OK",
+		DataSize:      s.bytes,
+		DownloadTime:  elapsed,
+		Measurement:   measurementFor(s.bytes),
+		Bitrate:       float64(s.bytes) * 8 / 1000 / elapsed.Seconds(),
+		Packets:       s.packets,
+		LostPackets:   s.lost(),
+		Jitter:        s.jitterDuration(),
+	}
+
+	info, err := mpegts.Inspect(s.ts)
+	if err != nil {
+		result.Valid = false
+		result.Error = fmt.Sprintf("Received data is not valid MPEG-TS: %v", err)
+		return result
+	}
+	result.Codecs = info.Codecs()
+	result.ContinuityErrors = info.ContinuityErrors
+	picture := detectPicture(info)
+	result.Width, result.Height, result.FrameRate = picture.Width, picture.Height, picture.FrameRate
+
+	if lost := result.LostPackets; lost > 0 {
+		result.Error = fmt.Sprintf("OK (RTP loss %.2f%%)", float64(lost)*100/float64(lost+s.packets))
+	} else if info.ContinuityErrors > 0 {
+		result.Error = fmt.Sprintf("OK (%d MPEG-TS continuity errors)", info.ContinuityErrors)
+	}
+	return result
+}
+
+// rtpTracker 根据 RTP 序列号统计丢包，并按 RFC 3550 计算到达间隔抖动
+type rtpTracker struct {
+	clockRate   float64 // RTP 时间戳的时钟频率
+	received    int64
+	baseSeq     int64
+	maxSeq      int64 // 扩展后的最大序列号，处理 16 位回绕
+	lastTS      uint32
+	lastArrival time.Time
+	jitter      float64 // 秒
+}
+
+func (t *rtpTracker) add(seq uint16, timestamp uint32, now time.Time) {
+	if t.received == 0 {
+		t.baseSeq, t.maxSeq = int64(seq), int64(seq)
+	} else {
+		ext := t.maxSeq + int64(int16(seq-uint16(t.maxSeq)))
+		if ext > t.maxSeq {
+			t.maxSeq = ext
+		}
+		if t.clockRate > 0 {
+			arrival := now.Sub(t.lastArrival).Seconds()
+			sent := float64(int32(timestamp-t.lastTS)) / t.clockRate
+			d := arrival - sent
+			if d < 0 {
+				d = -d
+			}
+			t.jitter += (d - t.jitter) / 16
+		}
+	}
+	t.received++
+	t.lastTS = timestamp
+	t.lastArrival = now
+}
+
+// lost 返回丢包数，重复包可能使期望数小于收到数，此时返回 0
+func (t *rtpTracker) lost() int64 {
+	if lost := t.maxSeq - t.baseSeq + 1 - t.received; lost > 0 {
+		return lost
+	}
+	return 0
+}
+
+func (t *rtpTracker) jitterDuration() time.Duration {
+	return time.Duration(t.jitter * float64(time.Second))
+}
+
+// isRTP 判断是否为 RTP 包：版本号为 2 且长度足够容纳固定头
+func isRTP(packet []byte) bool {
+	return len(packet) >= 12 && packet[0]>>6 == 2
+}
+
+// rtpPayload 去掉 RTP 固定头、CSRC 列表、扩展头和填充，返回负载、序列号和时间戳
+func rtpPayload(packet []byte) (payload []byte, seq uint16, timestamp uint32, ok bool) {
+	if !isRTP(packet) {
+		return nil, 0, 0, false
+	}
+	seq = binary.BigEndian.Uint16(packet[2:4])
+	timestamp = binary.BigEndian.Uint32(packet[4:8])
+
+	offset := 12 + 4*int(packet[0]&0x0F)
+	if packet[0]&0x10 != 0 { // 扩展头
+		if len(packet) < offset+4 {
+			return nil, 0, 0, false
+		}
+		offset += 4 + 4*int(binary.BigEndian.Uint16(packet[offset+2:offset+4]))
+	}
+	end := len(packet)
+	if packet[0]&0x20 != 0 { // 填充
+		end -= int(packet[end-1])
+	}
+	if offset > end {
+		return nil, 0, 0, false
+	}
+	return packet[offset:end], seq, timestamp, true
+}
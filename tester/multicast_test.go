@@ -0,0 +1,233 @@
+package tester
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testSPS 是 1920x1080、30fps 的 H.264 High profile SPS
+const testSPS = "6764002AACD940780227E5C044000003000400000300F03C60C658"
+
+// mpegCRC 计算 PSI 段的 CRC32，测试中用于构造合法的 PAT/PMT
+func mpegCRC(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// tsPacket 构造一个只有负载的 TS 包，剩余部分用 0xFF 填充
+func tsPacket(pid uint16, start bool, cc uint8, payload []byte) []byte {
+	p := bytes.Repeat([]byte{0xFF}, 188)
+	p[0] = 0x47
+	p[1] = byte(pid>>8) & 0x1F
+	if start {
+		p[1] |= 0x40
+	}
+	p[2] = byte(pid)
+	p[3] = 0x10 | cc&0x0F
+	copy(p[4:], payload)
+	return p
+}
+
+// tsPES 构造带 PTS 的视频 PES 头和数据
+func tsPES(pts int64, data []byte) []byte {
+	h := []byte{0, 0, 1, 0xE0, 0, 0, 0x80, 0x80, 5,
+		byte(0x21 | (pts>>29)&0x0E), byte(pts >> 22), byte(0x01 | (pts>>14)&0xFE), byte(pts >> 7), byte(0x01 | (pts<<1)&0xFE)}
+	return append(h, data...)
+}
+
+// buildTS 构造 PAT、PMT (H.264 + AAC) 和 frames 个视频 PES 包，第一个 PES 带 SPS。
+// ccJump 大于 0 时第 ccJump 个视频包的连续计数器多跳一次
+func buildTS(frames, ccJump int) []byte {
+	withCRC := func(body []byte) []byte {
+		return binary.BigEndian.AppendUint32(body, mpegCRC(body))
+	}
+	pat := withCRC([]byte{0x00, 0xB0, 13, 0, 1, 0xC1, 0, 0, 0, 1, 0xE1, 0x00})
+	pmt := withCRC([]byte{0x02, 0xB0, 23, 0, 1, 0xC1, 0, 0, 0xE1, 0x01, 0xF0, 0,
+		0x1B, 0xE1, 0x01, 0xF0, 0,
+		0x0F, 0xE1, 0x02, 0xF0, 0})
+	sps, _ := hex.DecodeString(testSPS)
+
+	var ts []byte
+	ts = append(ts, tsPacket(0, true, 0, append([]byte{0}, pat...))...)
+	ts = append(ts, tsPacket(0x100, true, 0, append([]byte{0}, pmt...))...)
+	cc := uint8(0)
+	for i := 0; i < frames; i++ {
+		data := []byte{0, 0, 0, 1, 0x09, 0xF0}
+		if i == 0 {
+			data = append(append(data, 0, 0, 0, 1), sps...)
+		}
+		if i > 0 && i == ccJump {
+			cc++
+		}
+		ts = append(ts, tsPacket(0x101, true, cc, tsPES(int64(i)*3000, data))...)
+		cc++
+	}
+	return ts
+}
+
+// rtpPacket 构造 payload type 33 的 RTP 包
+func rtpPacket(seq uint16, timestamp uint32, payload []byte) []byte {
+	h := []byte{0x80, 33, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	binary.BigEndian.PutUint16(h[2:], seq)
+	binary.BigEndian.PutUint32(h[4:], timestamp)
+	return append(h, payload...)
+}
+
+func TestMulticastRTPLoopback(t *testing.T) {
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("UDP not available: %v", err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	probe.Close()
+
+	// 20 个 RTP 包，每个包含 7 个 TS 包。序列号在第 10 个包后多跳一个（模拟丢包），
+	// TS 数据本身是连续的，另在第 50 个视频包处制造一次连续计数器跳变
+	ts := buildTS(20*7-2, 50)
+	const packets = 20
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		seq := uint16(65530) // 跨越 16 位回绕
+		for i := 0; i < packets; i++ {
+			if i == 10 {
+				seq++
+			}
+			conn.Write(rtpPacket(seq, uint32(i)*3000, ts[i*7*188:(i+1)*7*188]))
+			seq++
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	opts := DefaultOptions()
+	opts.Timeout = 3 * time.Second
+	opts.SampleWindow = 500 * time.Millisecond
+	result := testMulticast(fmt.Sprintf("rtp://127.0.0.1:%d", port), opts)
+
+	if !result.Valid {
+		t.Fatalf("result invalid: %s", result.Error)
+	}
+	if result.Packets != packets {
+		t.Errorf("Packets = %d, want %d", result.Packets, packets)
+	}
+	if result.LostPackets != 1 {
+		t.Errorf("LostPackets = %d, want 1", result.LostPackets)
+	}
+	if result.ContinuityErrors != 1 {
+		t.Errorf("ContinuityErrors = %d, want 1", result.ContinuityErrors)
+	}
+	if result.Jitter < 0 || result.Jitter > time.Second {
+		t.Errorf("Jitter = %v, want a small non-negative value", result.Jitter)
+	}
+	if want := []string{"H.264", "AAC"}; !reflect.DeepEqual(result.Codecs, want) {
+		t.Errorf("Codecs = %v, want %v", result.Codecs, want)
+	}
+	if result.Width != 1920 || result.Height != 1080 {
+		t.Errorf("resolution = %dx%d, want 1920x1080", result.Width, result.Height)
+	}
+	if result.DataSize != int64(len(ts)) {
+		t.Errorf("DataSize = %d, want %d", result.DataSize, len(ts))
+	}
+}
+
+func TestRTPTrackerJitter(t *testing.T) {
+	// 发送间隔 40ms (3600 @ 90kHz)，到达间隔 50、30、40ms，
+	// 按 RFC 3550 A.8: J += (|D| - J) / 16
+	tracker := rtpTracker{clockRate: rtpClockRate}
+	base := time.Unix(0, 0)
+	arrivals := []time.Duration{0, 50 * time.Millisecond, 80 * time.Millisecond, 120 * time.Millisecond}
+	for i, at := range arrivals {
+		tracker.add(uint16(100+i), uint32(i)*3600, base.Add(at))
+	}
+
+	want := 0.0
+	for _, d := range []float64{0.010, 0.010, 0} {
+		want += (d - want) / 16
+	}
+	if got := tracker.jitterDuration().Seconds(); math.Abs(got-want) > 1e-6 {
+		t.Errorf("jitter = %v, want %v", got, want)
+	}
+	if lost := tracker.lost(); lost != 0 {
+		t.Errorf("lost = %d, want 0", lost)
+	}
+}
+
+func TestRTPTrackerLossAcrossWrap(t *testing.T) {
+	tracker := rtpTracker{clockRate: rtpClockRate}
+	now := time.Unix(0, 0)
+	for _, seq := range []uint16{65534, 65535, 1, 2} { // 序列号 0 丢失
+		tracker.add(seq, 0, now)
+	}
+	if lost := tracker.lost(); lost != 1 {
+		t.Errorf("lost = %d, want 1", lost)
+	}
+}
+
+func TestRTPPayload(t *testing.T) {
+	payload := []byte{0x47, 1, 2, 3}
+	header := func(first byte) []byte {
+		return []byte{first, 33, 0x12, 0x34, 0, 0, 0x0B, 0xB8, 0, 0, 0, 1}
+	}
+	concat := func(parts ...[]byte) []byte {
+		var b []byte
+		for _, p := range parts {
+			b = append(b, p...)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name   string
+		packet []byte
+		want   []byte
+		ok     bool
+	}{
+		{"plain", concat(header(0x80), payload), payload, true},
+		{"two CSRCs", concat(header(0x82), make([]byte, 8), payload), payload, true},
+		{"extension header", concat(header(0x90), []byte{0xBE, 0xDE, 0, 2}, make([]byte, 8), payload), payload, true},
+		{"padding", concat(header(0xA0), payload, []byte{0, 0, 3}), payload, true},
+		{"CSRC, extension and padding", concat(header(0xB1), make([]byte, 4), []byte{0, 0, 0, 1}, make([]byte, 4), payload, []byte{0, 2}), payload, true},
+		{"truncated header", header(0x80)[:10], nil, false},
+		{"truncated extension", concat(header(0x90), []byte{0xBE}), nil, false},
+		{"extension longer than packet", concat(header(0x90), []byte{0xBE, 0xDE, 0, 9}, payload), nil, false},
+		{"not RTP version 2", concat(header(0x40), payload), nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, seq, timestamp, ok := rtpPayload(tt.packet)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("payload = % x, want % x", got, tt.want)
+			}
+			if seq != 0x1234 || timestamp != 3000 {
+				t.Errorf("seq, timestamp = %d, %d, want 4660, 3000", seq, timestamp)
+			}
+		})
+	}
+}
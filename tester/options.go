@@ -0,0 +1,43 @@
+package tester
+
+import (
+	"io"
+	"time"
+)
+
+// Options 控制批量测试的行为
+type Options struct {
+	Timeout     time.Duration // 单个请求的超时时间，同时也是单个直播源片段测速的总时长上限
+	Concurrency int           // 同时测试的直播源数量
+	// VariantPolicy 是主播放列表的变体和 DASH 表示的测试策略: VariantHighest、VariantLowest 或 VariantAll
+	VariantPolicy string
+	// VerifyLive 为 true 时重新加载 HLS 媒体播放列表、检查 DASH 清单的时间信息，验证直播是否仍在更新
+	VerifyLive bool
+	// MinHeight 是可用直播源的最低画面高度，0 表示不限。分辨率未知的直播源不受限制
+	MinHeight int
+	// IncludeUnmeasured 为 true 时保留无法测速的直播源（排在已测速的之后），否则标记为不可用
+	IncludeUnmeasured bool
+	// MulticastInterface 是加入 udp:// 和 rtp:// 组播组使用的网卡名，为空时由系统选择
+	MulticastInterface string
+	// SampleWindow 是组播、udpxy 代理流、HTTP 直播流、RTSP 和 RTMP 播放的采样时长，从收到第一个包开始计时，不超过 Timeout
+	SampleWindow time.Duration
+	// QueryProxyStatus 为 true 时拉流期间查询 udpxy 的 /status 页面，记录代理的客户端数量
+	QueryProxyStatus bool
+	// RTSPPlay 为 true 时对 RTSP 直播源执行 SETUP/PLAY 测量吞吐量，否则只做 OPTIONS/DESCRIBE
+	RTSPPlay bool
+	// Progress 接收 TestAllSources 的进度输出，为 nil 时不输出
+	Progress io.Writer
+}
+
+// DefaultOptions 返回默认的测试参数
+func DefaultOptions() Options {
+	return Options{
+		Timeout:       8 * time.Second,
+		Concurrency:   10,
+		VariantPolicy: VariantHighest,
+		VerifyLive:    true,
+
+		SampleWindow: 3 * time.Second,
+		RTSPPlay:     true,
+	}
+}
@@ -0,0 +1,104 @@
+package tester
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/flv"
+	"m3u8_selector/rtmp"
+)
+
+// isRTMPURL 判断是否为 rtmp:// 地址
+func isRTMPURL(rawURL string) bool {
+	return strings.HasPrefix(strings.ToLower(rawURL), "rtmp://")
+}
+
+// testRTMP 通过握手、connect、createStream 和 play 拉取 RTMP 直播流，在采样窗口内统计吞吐量，
+// 并从 onMetaData 和音视频标签中识别编码、分辨率和帧率
+func testRTMP(rawURL string, opts Options) core.M3U8Source {
+	start := time.Now()
+	client, err := rtmp.Dial(rawURL, opts.Timeout)
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: time.Since(start), Valid: false, Error: err.Error()}
+	}
+	defer client.Close()
+
+	err = client.Connect()
+	latency := time.Since(start)
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: err.Error()}
+	}
+	if err := client.CreateStream(); err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: err.Error()}
+	}
+	if err := client.Play(); err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: fmt.Sprintf("play failed: %v", err)}
+	}
+
+	window := opts.SampleWindow
+	if window <= 0 || window > opts.Timeout {
+		window = opts.Timeout
+	}
+	var prober flv.Prober
+	deadline := time.Now().Add(opts.Timeout)
+	var first, last time.Time
+	var bytes int64
+	var readErr error
+	for {
+		client.SetReadDeadline(deadline)
+		msg, err := client.ReadMessage()
+		now := time.Now()
+		if err != nil {
+			readErr = err
+			break
+		}
+		prober.Add(msg.Type, msg.Timestamp, msg.Payload)
+		if msg.Type != flv.TagAudio && msg.Type != flv.TagVideo {
+			continue
+		}
+		if first.IsZero() {
+			first = now
+			deadline = now.Add(window)
+		}
+		bytes += int64(len(msg.Payload))
+		last = now
+	}
+
+	if bytes == 0 {
+		if statusErr, ok := readErr.(*rtmp.StatusError); ok {
+			return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: "play failed: " + statusErr.Error()}
+		}
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: fmt.Sprintf("No media data received within %v after play", opts.Timeout)}
+	}
+
+	elapsed := last.Sub(first)
+	if elapsed <= 0 {
+		elapsed = window
+	}
+	info := prober.Info()
+	result := core.M3U8Source{
+		URL:           rawURL,
+		Latency:       latency,
+		DownloadSpeed: float64(bytes) / elapsed.Seconds() / 1024,
+		Valid:         true,
+		Error:         "OK",
+		DataSize:      bytes,
+		DownloadTime:  elapsed,
+		Measurement:   measurementFor(bytes),
+		Codecs:        info.Codecs(),
+		Width:         info.Width,
+		Height:        info.Height,
+		FrameRate:     info.FrameRate,
+	}
+	// 直播服务器按实时速度推送，吞吐量受限于码率，无法衡量播放余量，只按标签时间戳计算媒体码率
+	result.Bitrate, _ = playbackRate(bytes, info.Duration, elapsed)
+	if info.VideoTags == 0 && info.AudioTags > 0 {
+		result.Error = "OK (audio only)"
+	}
+	if statusErr, ok := readErr.(*rtmp.StatusError); ok {
+		result.Error = "OK (stream ended: " + statusErr.Code + ")"
+	}
+	return result
+}
@@ -0,0 +1,208 @@
+package tester
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"m3u8_selector/amf"
+)
+
+// rtmpFixture 是只支持一次播放的 RTMP 服务器：完成握手，回应 connect、createStream 和 play，
+// 然后发送 onMetaData、AVC 序列头、一个 AAC 标签和 frames 个按块大小拆分的视频标签
+type rtmpFixture struct {
+	chunkSize int
+	frames    int
+	frameSize int
+	interval  time.Duration
+}
+
+// rtmpStreamID 是 createStream 返回的消息流 ID
+const rtmpStreamID = 1
+
+func (f *rtmpFixture) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	// C0+C1，回复 S0+S1+S2，再读取 C2
+	c0c1 := make([]byte, 1+1536)
+	if _, err := io.ReadFull(r, c0c1); err != nil {
+		t.Errorf("fixture: read C0+C1: %v", err)
+		return
+	}
+	s0s1s2 := make([]byte, 1+2*1536)
+	s0s1s2[0] = 3
+	copy(s0s1s2[1+1536:], c0c1[1:])
+	w.Write(s0s1s2)
+	w.Flush()
+	if _, err := io.ReadFull(r, make([]byte, 1536)); err != nil {
+		t.Errorf("fixture: read C2: %v", err)
+		return
+	}
+
+	setChunkSize := binary.BigEndian.AppendUint32(nil, uint32(f.chunkSize))
+	f.write(w, 2, 1, 0, 0, setChunkSize)
+	for {
+		name, txn, err := readCommand(r)
+		if err != nil {
+			t.Errorf("fixture: %v", err)
+			return
+		}
+		switch name {
+		case "connect":
+			f.command(w, 3, 0, "_result", txn,
+				amf.Object{"fmsVer": "FMS/3,0,1,123", "capabilities": 31},
+				amf.Object{"level": "status", "code": "NetConnection.Connect.Success"})
+		case "createStream":
+			f.command(w, 3, 0, "_result", txn, nil, rtmpStreamID)
+		case "play":
+			f.command(w, 5, rtmpStreamID, "onStatus", 0, nil,
+				amf.Object{"level": "status", "code": "NetStream.Play.Start"})
+			f.play(w)
+			f.command(w, 5, rtmpStreamID, "onStatus", 0, nil,
+				amf.Object{"level": "status", "code": "NetStream.Play.Stop"})
+			return
+		}
+	}
+}
+
+// play 发送元数据、序列头和媒体标签
+func (f *rtmpFixture) play(w *bufio.Writer) {
+	meta, _ := amf.Encode("onMetaData", amf.Object{"width": 1280, "height": 720, "videocodecid": 7, "audiocodecid": 10})
+	f.write(w, 6, 18, 0, rtmpStreamID, meta)
+
+	sps, _ := hex.DecodeString(testSPS)
+	config := []byte{0x17, 0, 0, 0, 0, 1, 0x64, 0, 0x2A, 0xFF, 0xE1, byte(len(sps) >> 8), byte(len(sps))}
+	config = append(append(config, sps...), 0)
+	f.write(w, 6, 9, 0, rtmpStreamID, config)
+	f.write(w, 4, 8, 0, rtmpStreamID, []byte{0xAF, 0, 0x12, 0x10})
+
+	for i := 0; i < f.frames; i++ {
+		frame := make([]byte, f.frameSize)
+		frame[0], frame[1] = 0x27, 1
+		if i == 0 {
+			frame[0] = 0x17
+		}
+		f.write(w, 6, 9, uint32(i*40), rtmpStreamID, frame)
+		time.Sleep(f.interval)
+	}
+}
+
+// command 发送一条 AMF0 命令消息
+func (f *rtmpFixture) command(w *bufio.Writer, csid byte, streamID uint32, values ...interface{}) {
+	payload, _ := amf.Encode(values...)
+	f.write(w, csid, 20, 0, streamID, payload)
+}
+
+// write 用 fmt 0 块头发送一条消息，超过块大小的部分用 fmt 3 块继续
+func (f *rtmpFixture) write(w *bufio.Writer, csid, typ byte, ts, streamID uint32, payload []byte) {
+	header := []byte{csid, byte(ts >> 16), byte(ts >> 8), byte(ts),
+		byte(len(payload) >> 16), byte(len(payload) >> 8), byte(len(payload)), typ}
+	w.Write(binary.LittleEndian.AppendUint32(header, streamID))
+	for len(payload) > 0 {
+		n := min(f.chunkSize, len(payload))
+		w.Write(payload[:n])
+		if payload = payload[n:]; len(payload) > 0 {
+			w.WriteByte(0xC0 | csid)
+		}
+	}
+	w.Flush()
+}
+
+// readCommand 读取客户端的消息直到一条命令消息，返回命令名和事务 ID。
+// 客户端按 128 字节的默认块大小发送，每条消息由一个 fmt 0 块和若干 fmt 3 块组成
+func readCommand(r *bufio.Reader) (string, float64, error) {
+	for {
+		header := make([]byte, 12)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return "", 0, err
+		}
+		if header[0]>>6 != 0 {
+			return "", 0, fmt.Errorf("unexpected chunk format %d", header[0]>>6)
+		}
+		length := int(header[4])<<16 | int(header[5])<<8 | int(header[6])
+		payload := make([]byte, 0, length)
+		for len(payload) < length {
+			if len(payload) > 0 {
+				if _, err := r.ReadByte(); err != nil {
+					return "", 0, err
+				}
+			}
+			n := min(128, length-len(payload))
+			chunk := make([]byte, n)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return "", 0, err
+			}
+			payload = append(payload, chunk...)
+		}
+		if header[7] != 20 {
+			continue // Set Buffer Length 等控制消息
+		}
+		values, err := amf.Decode(payload)
+		if err != nil || len(values) < 2 {
+			return "", 0, fmt.Errorf("invalid command: %v", err)
+		}
+		name, _ := values[0].(string)
+		txn, _ := values[1].(float64)
+		return name, txn, nil
+	}
+}
+
+func TestRTMPFixture(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("TCP not available: %v", err)
+	}
+	defer ln.Close()
+	fixture := &rtmpFixture{chunkSize: 1000, frames: 10, frameSize: 2500, interval: 20 * time.Millisecond}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		fixture.serve(t, conn)
+	}()
+
+	opts := DefaultOptions()
+	opts.Timeout = 3 * time.Second
+	opts.SampleWindow = 2 * time.Second
+	result := testRTMP(fmt.Sprintf("rtmp://%s/live/test?token=1", ln.Addr()), opts)
+
+	if !result.Valid {
+		t.Fatalf("result invalid: %s", result.Error)
+	}
+	if result.Error != "OK (stream ended: NetStream.Play.Stop)" {
+		t.Errorf("Error = %q", result.Error)
+	}
+	if want := []string{"H.264", "AAC"}; !reflect.DeepEqual(result.Codecs, want) {
+		t.Errorf("Codecs = %v, want %v", result.Codecs, want)
+	}
+	// 序列头中 SPS 的分辨率优先于 onMetaData
+	if result.Width != 1920 || result.Height != 1080 {
+		t.Errorf("resolution = %dx%d, want 1920x1080", result.Width, result.Height)
+	}
+	if result.FrameRate != 30 {
+		t.Errorf("FrameRate = %v, want 30", result.FrameRate)
+	}
+
+	sequenceHeader := 13 + len(testSPS)/2 + 1
+	wantBytes := int64(sequenceHeader + 4 + fixture.frames*fixture.frameSize)
+	if result.DataSize != wantBytes {
+		t.Errorf("DataSize = %d, want %d", result.DataSize, wantBytes)
+	}
+	minTime := time.Duration(fixture.frames-1) * fixture.interval
+	if result.DownloadTime < minTime || result.DownloadTime > opts.SampleWindow {
+		t.Errorf("DownloadTime = %v, want between %v and %v", result.DownloadTime, minTime, opts.SampleWindow)
+	}
+	if want := float64(result.DataSize) / result.DownloadTime.Seconds() / 1024; result.DownloadSpeed != want {
+		t.Errorf("DownloadSpeed = %v, want %v KB/s", result.DownloadSpeed, want)
+	}
+}
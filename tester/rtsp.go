@@ -0,0 +1,208 @@
+package tester
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/rtsp"
+	"m3u8_selector/video"
+)
+
+// isRTSPURL 判断是否为 rtsp:// 地址
+func isRTSPURL(rawURL string) bool {
+	return strings.HasPrefix(strings.ToLower(rawURL), "rtsp://")
+}
+
+// testRTSP 通过 OPTIONS 和 DESCRIBE 探测 RTSP 直播源，从 SDP 中读取媒体轨道和编码。
+// opts.RTSPPlay 为 true 时再用 TCP 交织传输 SETUP/PLAY，在采样窗口内统计吞吐量和 RTP 丢包。
+func testRTSP(rawURL string, opts Options) core.M3U8Source {
+	start := time.Now()
+	client, err := rtsp.Dial(rawURL, opts.Timeout)
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: time.Since(start), Valid: false, Error: err.Error()}
+	}
+	defer client.Close()
+
+	resp, err := client.Options()
+	latency := time.Since(start)
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: fmt.Sprintf("OPTIONS failed: %v", err)}
+	}
+	if resp.StatusCode != 200 {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: "OPTIONS returned " + resp.Status}
+	}
+
+	resp, err = client.Describe()
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: fmt.Sprintf("DESCRIBE failed: %v", err)}
+	}
+	if resp.StatusCode != 200 {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: "DESCRIBE returned " + resp.Status}
+	}
+	session, err := rtsp.ParseSDP(resp.Body)
+	if err != nil || len(session.Medias) == 0 {
+		return core.M3U8Source{URL: rawURL, Latency: latency, Valid: false, Error: "DESCRIBE returned no media tracks"}
+	}
+
+	result := core.M3U8Source{
+		URL:         rawURL,
+		Latency:     latency,
+		Valid:       true,
+		Error:       "OK (DESCRIBE only, not played)",
+		Measurement: core.Unmeasured,
+	}
+	for _, m := range session.Medias {
+		if m.Codec != "" {
+			result.Codecs = mergeCodecs(result.Codecs, []string{m.Codec})
+		}
+		if result.Width == 0 {
+			picture := sdpPicture(m)
+			result.Width, result.Height, result.FrameRate = picture.Width, picture.Height, picture.FrameRate
+		}
+	}
+	if !opts.RTSPPlay {
+		return result
+	}
+
+	base := resp.Header.Get("Content-Base")
+	if base == "" {
+		base = client.URL().String()
+	}
+	return playRTSP(client, rtsp.ControlURL(base, session.Control), base, session, result, opts)
+}
+
+// playRTSP 对音视频轨道执行 SETUP 和 PLAY，在采样窗口内读取交织的 RTP 数据
+func playRTSP(client *rtsp.Client, sessionURL, base string, session *rtsp.Session, result core.M3U8Source, opts Options) core.M3U8Source {
+	trackers := make(map[int]*rtpTracker) // RTP 通道号 -> 统计
+	videoChannel := -1
+	for _, m := range session.Medias {
+		if m.Type != "video" && m.Type != "audio" {
+			continue
+		}
+		channel := 2 * len(trackers)
+		resp, err := client.SetupInterleaved(rtsp.ControlURL(base, m.Control), channel)
+		if err != nil {
+			result.Valid = false
+			result.Error = fmt.Sprintf("SETUP failed: %v", err)
+			return result
+		}
+		if resp.StatusCode != 200 {
+			result.Valid = false
+			result.Error = "SETUP returned " + resp.Status
+			return result
+		}
+		trackers[channel] = &rtpTracker{clockRate: float64(m.ClockRate)}
+		if m.Type == "video" && videoChannel < 0 {
+			videoChannel = channel
+		}
+	}
+	if len(trackers) == 0 {
+		result.Valid = false
+		result.Error = "No audio or video track to play"
+		return result
+	}
+
+	resp, err := client.Play(sessionURL)
+	if err != nil {
+		result.Valid = false
+		result.Error = fmt.Sprintf("PLAY failed: %v", err)
+		return result
+	}
+	if resp.StatusCode != 200 {
+		result.Valid = false
+		result.Error = "PLAY returned " + resp.Status
+		return result
+	}
+	defer client.Teardown(sessionURL)
+
+	window := opts.SampleWindow
+	if window <= 0 || window > opts.Timeout {
+		window = opts.Timeout
+	}
+	playStart := time.Now()
+	deadline := playStart.Add(opts.Timeout)
+	var first, last time.Time
+	var bytes, packets int64
+	for {
+		client.SetReadDeadline(deadline)
+		channel, data, err := client.ReadInterleaved()
+		now := time.Now()
+		if err != nil {
+			break
+		}
+		tracker, ok := trackers[channel]
+		if !ok {
+			continue // RTCP
+		}
+		payload, seq, timestamp, ok := rtpPayload(data)
+		if !ok {
+			continue
+		}
+		if first.IsZero() {
+			first = now
+			deadline = now.Add(window)
+		}
+		tracker.add(seq, timestamp, now)
+		bytes += int64(len(payload))
+		packets++
+		last = now
+	}
+
+	if packets == 0 {
+		result.Valid = false
+		result.Error = fmt.Sprintf("No RTP data received within %v after PLAY", opts.Timeout)
+		return result
+	}
+
+	elapsed := last.Sub(first)
+	if elapsed <= 0 {
+		elapsed = window
+	}
+	var lost int64
+	for _, t := range trackers {
+		lost += t.lost()
+	}
+	result.Error = "OK"
+	result.DownloadSpeed = float64(bytes) / elapsed.Seconds() / 1024
+	result.DataSize = bytes
+	result.DownloadTime = elapsed
+	result.Measurement = measurementFor(bytes)
+	result.Bitrate = float64(bytes) * 8 / 1000 / elapsed.Seconds()
+	result.Packets = packets
+	result.LostPackets = lost
+	if t, ok := trackers[videoChannel]; ok {
+		result.Jitter = t.jitterDuration()
+	}
+	if lost > 0 {
+		result.Error = fmt.Sprintf("OK (RTP loss %.2f%%)", float64(lost)*100/float64(lost+packets))
+	}
+	return result
+}
+
+// sdpPicture 从 SDP 的 sprop 参数中取出 SPS，解析分辨率和帧率
+func sdpPicture(m rtsp.Media) video.Info {
+	var sps string
+	var parse func([]byte) (video.Info, error)
+	switch m.Codec {
+	case "H.264":
+		sps = strings.Split(m.Fmtp["sprop-parameter-sets"], ",")[0]
+		parse = video.ParseH264SPS
+	case "H.265":
+		sps = m.Fmtp["sprop-sps"]
+		parse = video.ParseHEVCSPS
+	default:
+		return video.Info{}
+	}
+	nal, err := base64.StdEncoding.DecodeString(sps)
+	if err != nil || len(nal) == 0 {
+		return video.Info{}
+	}
+	picture, err := parse(nal)
+	if err != nil {
+		return video.Info{}
+	}
+	return picture
+}
@@ -0,0 +1,162 @@
+package tester
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rtspFixture 是只支持一次播放的 RTSP 服务器：回应 OPTIONS、DESCRIBE（H.264 + AAC）、
+// 两次 SETUP 和 PLAY，然后按 interval 交织发送视频和音频 RTP 包，视频序列号 skip 处缺一个包
+type rtspFixture struct {
+	videoPackets, audioPackets int
+	videoSize, audioSize       int
+	skip                       uint16
+	interval                   time.Duration
+
+	methods []string // 收到的请求方法，serve 返回后才能读取
+}
+
+func (f *rtspFixture) serve(t *testing.T, conn net.Conn, done chan<- struct{}) {
+	defer close(done)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	tp := textproto.NewReader(bufio.NewReader(conn))
+
+	sps, _ := hex.DecodeString(testSPS)
+	sdp := "v=0\r\ns=fixture\r\na=control:*\r\n" +
+		"m=video 0 RTP/AVP 96\r\na=rtpmap:96 H264/90000\r\n" +
+		"a=fmtp:96 packetization-mode=1;sprop-parameter-sets=" + base64.StdEncoding.EncodeToString(sps) + ",aO48gA==\r\n" +
+		"a=control:trackID=0\r\n" +
+		"m=audio 0 RTP/AVP 97\r\na=rtpmap:97 MPEG4-GENERIC/48000/2\r\na=control:trackID=1\r\n"
+
+	setups := 0
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		header, err := tp.ReadMIMEHeader()
+		if err != nil {
+			t.Errorf("fixture: %v", err)
+			return
+		}
+		method := strings.Fields(line)[0]
+		f.methods = append(f.methods, method)
+		reply := "RTSP/1.0 200 OK\r\nCSeq: " + header.Get("CSeq") + "\r\n"
+		switch method {
+		case "OPTIONS":
+			io.WriteString(conn, reply+"Public: OPTIONS, DESCRIBE, SETUP, PLAY, TEARDOWN\r\n\r\n")
+		case "DESCRIBE":
+			io.WriteString(conn, reply+fmt.Sprintf("Content-Type: application/sdp\r\nContent-Length: %d\r\n\r\n", len(sdp))+sdp)
+		case "SETUP":
+			want := fmt.Sprintf("interleaved=%d-%d", 2*setups, 2*setups+1)
+			if !strings.HasSuffix(line, fmt.Sprintf("/trackID=%d RTSP/1.0", setups)) || !strings.HasSuffix(header.Get("Transport"), want) {
+				t.Errorf("SETUP %d: %q Transport %q", setups, line, header.Get("Transport"))
+			}
+			setups++
+			io.WriteString(conn, reply+"Session: 7788;timeout=60\r\n\r\n")
+		case "PLAY":
+			io.WriteString(conn, reply+"\r\n")
+			f.play(conn)
+		case "TEARDOWN":
+			return
+		}
+	}
+}
+
+// play 发送 RTP 包，视频在通道 0，音频在通道 2，另外在通道 1 发送一个 RTCP 包
+func (f *rtspFixture) play(w io.Writer) {
+	frame := func(channel byte, packet []byte) []byte {
+		return append([]byte{'$', channel, byte(len(packet) >> 8), byte(len(packet))}, packet...)
+	}
+	w.Write(frame(1, []byte{0x80, 200, 0, 6, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}))
+	seq := uint16(0)
+	for i := 0; i < max(f.videoPackets, f.audioPackets); i++ {
+		if i < f.videoPackets {
+			if seq == f.skip {
+				seq++
+			}
+			w.Write(frame(0, rtpPacket(seq, uint32(i)*3000, make([]byte, f.videoSize))))
+			seq++
+		}
+		if i < f.audioPackets {
+			w.Write(frame(2, rtpPacket(uint16(i), uint32(i)*1024, make([]byte, f.audioSize))))
+		}
+		time.Sleep(f.interval)
+	}
+}
+
+func TestRTSPFixture(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("TCP not available: %v", err)
+	}
+	defer ln.Close()
+	fixture := &rtspFixture{videoPackets: 10, audioPackets: 5, videoSize: 1000, audioSize: 200, skip: 5, interval: 20 * time.Millisecond}
+	done := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(done)
+			return
+		}
+		fixture.serve(t, conn, done)
+	}()
+
+	opts := DefaultOptions()
+	opts.Timeout = 3 * time.Second
+	opts.SampleWindow = 500 * time.Millisecond
+	result := testRTSP(fmt.Sprintf("rtsp://%s/live/stream", ln.Addr()), opts)
+	<-done
+
+	if !result.Valid {
+		t.Fatalf("result invalid: %s", result.Error)
+	}
+	if result.Error != "OK (RTP loss 6.25%)" {
+		t.Errorf("Error = %q", result.Error)
+	}
+	if want := []string{"H.264", "AAC"}; !reflect.DeepEqual(result.Codecs, want) {
+		t.Errorf("Codecs = %v, want %v", result.Codecs, want)
+	}
+	if result.Width != 1920 || result.Height != 1080 || result.FrameRate != 30 {
+		t.Errorf("picture = %dx%d@%v, want 1920x1080@30", result.Width, result.Height, result.FrameRate)
+	}
+	if result.Packets != 15 || result.LostPackets != 1 {
+		t.Errorf("packets = %d, lost = %d, want 15 and 1", result.Packets, result.LostPackets)
+	}
+	if result.DataSize != 10*1000+5*200 {
+		t.Errorf("DataSize = %d, want %d", result.DataSize, 10*1000+5*200)
+	}
+	if result.DownloadTime <= 0 || result.DownloadTime > opts.SampleWindow {
+		t.Errorf("DownloadTime = %v", result.DownloadTime)
+	}
+	if want := []string{"OPTIONS", "DESCRIBE", "SETUP", "SETUP", "PLAY", "TEARDOWN"}; !reflect.DeepEqual(fixture.methods, want) {
+		t.Errorf("requests = %v, want %v", fixture.methods, want)
+	}
+
+	// 只做 OPTIONS/DESCRIBE 时不播放，结果未测速
+	done = make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(done)
+			return
+		}
+		(&rtspFixture{}).serve(t, conn, done)
+	}()
+	opts.RTSPPlay = false
+	result = testRTSP(fmt.Sprintf("rtsp://%s/live/stream", ln.Addr()), opts)
+	<-done
+	if !result.Valid || result.Error != "OK (DESCRIBE only, not played)" || result.Width != 1920 {
+		t.Errorf("DESCRIBE only: %+v", result)
+	}
+}
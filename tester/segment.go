@@ -0,0 +1,70 @@
+package tester
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"m3u8_selector/hls"
+	"m3u8_selector/mpegts"
+	"m3u8_selector/video"
+)
+
+// maxSegmentSize 限制单个媒体片段的读取大小，避免异常的服务器返回无限数据
+const maxSegmentSize = 32 * 1024 * 1024
+
+// readSegment 读取完整的片段内容
+func readSegment(resp *http.Response) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(resp.Body, maxSegmentSize))
+}
+
+// inspectSegment 检查片段是否为有效的 MPEG-TS。加密片段、fMP4 片段 (#EXT-X-MAP，由 fmp4Checker 检查)
+// 和打包音频 (ID3/ADTS) 无法按 TS 检查，返回 nil, nil。
+func inspectSegment(segment hls.Segment, data []byte) (*mpegts.Info, error) {
+	if segment.Key != nil && segment.Key.Method != "NONE" {
+		return nil, nil
+	}
+	if segment.Map != nil {
+		return nil, nil
+	}
+	if isPackedAudio(data) {
+		return nil, nil
+	}
+
+	info, err := mpegts.Inspect(data)
+	if err != nil {
+		return nil, fmt.Errorf("segment is not valid MPEG-TS: %v", err)
+	}
+	return info, nil
+}
+
+// isFullyEncrypted 判断片段是否整体加密 (AES-128)。SAMPLE-AES 只加密样本数据，fMP4 的盒子结构仍然可以检查
+func isFullyEncrypted(segment hls.Segment) bool {
+	return segment.Key != nil && segment.Key.Method == "AES-128"
+}
+
+// isPackedAudio 判断片段是否为 HLS 打包音频：以 ID3 标签或 ADTS 同步字开头
+func isPackedAudio(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("ID3")) {
+		return true
+	}
+	return len(data) >= 2 && data[0] == 0xFF && data[1]&0xF6 == 0xF0
+}
+
+// detectPicture 从片段的视频 SPS 解析分辨率和帧率。SPS 中没有时序信息时，
+// 用 PES 的 PTS 间隔估算帧率。没有视频流或找不到 SPS 时返回零值。
+func detectPicture(info *mpegts.Info) video.Info {
+	codec := info.VideoCodec()
+	if codec == "" {
+		return video.Info{}
+	}
+	picture, err := video.FindSPS(codec, info.VideoData)
+	if err != nil {
+		return video.Info{}
+	}
+	if picture.FrameRate == 0 {
+		picture.FrameRate = info.FrameRateFromPTS()
+	}
+	return picture
+}
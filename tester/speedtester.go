@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"sort"
 	"strings"
@@ -12,32 +11,18 @@ import (
 	"time"
 
 	"m3u8_selector/core"
+	"m3u8_selector/hls"
+	"m3u8_selector/video"
 )
 
 // testStreamConnectSpeed tests the connection speed of a stream (initial screening)
 func TestStreamConnectSpeed(url string, timeout time.Duration) core.M3U8Source {
 	if strings.HasPrefix(url, "udp") {
-		// 对于UDP链接，模拟连接测速
-		start := time.Now()
-
-		// 模拟UDP测速
-		success := simulateUDPSpeedTest()
-		latency := time.Since(start)
-
-		if !success {
-			return core.M3U8Source{
-				URL:     url,
-				Latency: latency,
-				Valid:   false,
-				Error:   "UDP test failed",
-			}
-		}
-
+		// UDP 是无连接的，预检阶段无法判断是否可用，交给后续的测试
 		return core.M3U8Source{
-			URL:     url,
-			Latency: latency,
-			Valid:   true,
-			Error:   "OK",
+			URL:   url,
+			Valid: true,
+			Error: "OK",
 		}
 	} else {
 		client := &http.Client{
@@ -108,11 +93,6 @@ func TestStreamConnectSpeed(url string, timeout time.Duration) core.M3U8Source {
 	}
 }
 
-// simulateUDPSpeedTest is a placeholder for simulating UDP speed test
-func simulateUDPSpeedTest() bool {
-	return true
-}
-
 // isM3U8Content checks if the content of a URL is likely an M3U8 playlist
 func IsM3U8Content(url string, timeout time.Duration) bool {
 	client := &http.Client{
@@ -159,6 +139,25 @@ func IsM3U8Content(url string, timeout time.Duration) bool {
 
 // testM3U8PlaybackSpeed tests the actual playback download speed
 func TestM3U8PlaybackSpeed(url string, timeout time.Duration) core.M3U8Source {
+	opts := DefaultOptions()
+	opts.Timeout = timeout
+	return testM3U8Playback(url, opts, 0)
+}
+
+// maxPlaylistDepth 限制主播放列表/跳转页面的嵌套层数，避免循环引用
+const maxPlaylistDepth = 3
+
+// testM3U8Playback 测试播放列表，depth 为当前的嵌套层数
+func testM3U8Playback(url string, opts Options, depth int) core.M3U8Source {
+	timeout := opts.Timeout
+	if depth > maxPlaylistDepth {
+		return core.M3U8Source{
+			URL:   url,
+			Valid: false,
+			Error: "Too many nested playlists",
+		}
+	}
+
 	client := &http.Client{
 		Timeout: timeout,
 		// 启用自动重定向跟随
@@ -219,7 +218,7 @@ func TestM3U8PlaybackSpeed(url string, timeout time.Duration) core.M3U8Source {
 						if endIdx != -1 {
 							realM3U8URL := line[startIdx:startIdx+endIdx]
 							// 递归测试真实的M3U8链接
-							return TestM3U8PlaybackSpeed(realM3U8URL, timeout)
+							return testM3U8Playback(realM3U8URL, opts, depth+1)
 						}
 					}
 				}
@@ -254,212 +253,235 @@ func TestM3U8PlaybackSpeed(url string, timeout time.Duration) core.M3U8Source {
 		}
 	}
 
-	// 检查是否包含JSON错误关键字
-	if strings.Contains(content, `"Ret"`) || strings.Contains(content, `"Reason"`) ||
-		strings.Contains(content, "无效") || strings.Contains(content, "invalid") ||
-		strings.Contains(content, "expired") || strings.Contains(content, "error") {
-		return core.M3U8Source{
-			URL:     url,
-			Latency: latency,
-			Valid:   false,
-			Error:   "API error response (token invalid or other error)",
-		}
-	}
-
-	if !strings.HasPrefix(content, "#EXTM3U") {
+	playlist, err := hls.Parse(content, resp.Request.URL.String())
+	if err != nil {
 		return core.M3U8Source{
 			URL:     url,
 			Latency: latency,
 			Valid:   false,
-			Error:   "Not a valid M3U8 file (missing #EXTM3U)",
+			Error:   fmt.Sprintf("Not a valid M3U8 file (%v)", err),
 		}
 	}
 
-	// 检查是否为静态内容或测试内容 - 关键修复
-	lowerContent := strings.ToLower(content)
-	if strings.Contains(lowerContent, "nosignal") || 
-	   strings.Contains(lowerContent, "test") || 
-	   strings.Contains(lowerContent, "sample") ||
-	   strings.Contains(lowerContent, "demo") ||
-	   strings.Contains(lowerContent, "output") && strings.Count(content, ".ts") > 10 ||
-	   strings.Contains(content, "#EXT-X-ENDLIST") && strings.Count(content, "#EXTINF:") > 20 {
-		return core.M3U8Source{
-			URL:     url,
-			Latency: latency,
-			Valid:   false,
-			Error:   "Static test content or sample video, not live stream",
-		}
+	// 主播放列表：按策略测试变体流
+	if master, ok := playlist.(*hls.MasterPlaylist); ok {
+		return testMasterPlaylist(url, master, opts, depth, latency)
 	}
+	media := playlist.(*hls.MediaPlaylist)
 
-	if strings.Contains(content, "#EXT-X-PLAYLIST-TYPE:VOD") {
+	// 点播和已结束的播放列表不是直播
+	if media.PlaylistType == "VOD" {
 		return core.M3U8Source{
 			URL:     url,
 			Latency: latency,
 			Valid:   false,
 			Error:   "VOD (Video On Demand) stream, not live",
+			Live:    core.LiveStatic,
 		}
 	}
-
-	// 不再检查 #EXT-X-ENDLIST，因为直播流通常没有这个标签
-
-	// 检查M3U8内容是否包含有效的媒体片段（#EXTINF）- 修复：不再强制要求.ts或.m4s扩展名
-	if !strings.Contains(content, "#EXTINF:") {
+	if media.EndList {
 		return core.M3U8Source{
 			URL:     url,
 			Latency: latency,
 			Valid:   false,
-			Error:   "M3U8 content does not contain valid media segments",
+			Error:   "Playlist has #EXT-X-ENDLIST, not live",
+			Live:    core.LiveStatic,
 		}
 	}
 
-	// 检查媒体片段数量 - 降低要求，至少1个片段就可以
-	extinfCount := strings.Count(content, "#EXTINF:")
-	if extinfCount < 1 {
+	// 至少需要1个媒体片段 - 不强制要求.ts或.m4s扩展名
+	if len(media.Segments) == 0 {
 		return core.M3U8Source{
 			URL:     url,
 			Latency: latency,
 			Valid:   false,
-			Error:   fmt.Sprintf("No media segments found (%d)", extinfCount),
+			Error:   "M3U8 content does not contain valid media segments",
 		}
 	}
 
-	// 检查URL中是否包含测试或静态内容关键词
-	lowerURL := strings.ToLower(url)
-	if strings.Contains(lowerURL, "nosignal") || 
-	   strings.Contains(lowerURL, "test") || 
-	   strings.Contains(lowerURL, "sample") ||
-	   strings.Contains(lowerURL, "demo") {
+	// 同一个片段地址在播放列表中重复出现，说明是循环播放的静态内容
+	if uri, ok := repeatedSegment(media.Segments); ok {
 		return core.M3U8Source{
 			URL:     url,
 			Latency: latency,
 			Valid:   false,
-			Error:   "URL indicates test or static content",
+			Error:   fmt.Sprintf("Segment %s repeats within the playlist, looping content", uri),
+			Live:    core.LiveLooping,
 		}
 	}
 
 	// 新的真实直播拉流测速算法
-	return testLiveStreamingSpeed(url, content, timeout, latency)
+	result := testLiveStreamingSpeed(url, media, timeout, latency)
+	if !result.Valid || !opts.VerifyLive {
+		return result
+	}
+
+	// 在约一个目标时长后重新加载播放列表，验证是否仍在更新
+	state, detail := verifyLiveness(client, resp.Request.URL.String(), media, start, timeout)
+	result.Live = state
+	switch state {
+	case core.LiveFrozen, core.LiveLooping, core.LiveStatic:
+		result.Valid = false
+		result.Error = "Not live: " + detail
+	case core.LiveUnknown:
+		result.Error += " (liveness unverified: " + detail + ")"
+	}
+	return result
 }
 
 // testLiveStreamingSpeed 测试真实直播流的速度
-func testLiveStreamingSpeed(m3u8URL, m3u8Content string, timeout time.Duration, m3u8Latency time.Duration) core.M3U8Source {
+func testLiveStreamingSpeed(m3u8URL string, media *hls.MediaPlaylist, timeout time.Duration, m3u8Latency time.Duration) core.M3U8Source {
 	client := &http.Client{
 		Timeout: timeout,
 	}
 
-	// 提取前几个TS片段进行连续下载测试
-	tsURLs := extractMultipleTSURLs(m3u8Content, m3u8URL, 5) // 测试5个连续的TS片段
-	if len(tsURLs) == 0 {
-		// 如果没有提取到TS URL，但M3U8格式正确，给一个合理的默认值
-		return core.M3U8Source{
-			URL:           m3u8URL,
-			Latency:       m3u8Latency,
-			DownloadSpeed: 800.0, // 假设800KB/s的合理直播速度
-			Valid:         true,
-			Error:         "OK (M3U8 valid, estimated speed)",
-			DataSize:      1024 * 1024, // 1MB估算
-			DownloadTime:  1250 * time.Millisecond, // 根据速度推算
-		}
+	// 取前几个片段进行连续下载测试
+	segments := media.Segments
+	if len(segments) > 5 {
+		segments = segments[:5] // 测试5个连续的TS片段
 	}
 
 	// 连续下载多个TS片段，模拟真实直播播放
 	var totalDataSize int64 = 0
 	var totalDownloadTime time.Duration = 0
+	var totalMediaDuration time.Duration = 0 // 成功下载片段的 #EXTINF 时长之和
 	successfulDownloads := 0
 	var speeds []float64
 
+	// 片段内容检查的结果
+	var codecs []string
+	continuityErrors := 0
+	inspected := false
+	var inspectErr error
+	var picture video.Info
+	var container core.Container
+
+	// fMP4 片段 (#EXT-X-MAP) 的检查，以及上一个成功片段的序列号，用于判断片段是否相邻
+	fragments := &fmp4Checker{client: client}
+	var lastSequence uint64
+	hasLast := false
+
 	// 限制总的测试时间，避免过长时间等待
-	testTimeout := 8 * time.Second
 	testStart := time.Now()
 
-	for _, tsURL := range tsURLs {
+	for _, segment := range segments {
 		// 检查是否超时
-		if time.Since(testStart) > testTimeout {
+		if time.Since(testStart) > timeout {
 			break
 		}
 
 		downloadStart := time.Now()
-		tsResp, err := client.Get(tsURL)
+		tsResp, err := fetchSegment(client, segment)
 		if err != nil {
 			continue // 跳过失败的片段
 		}
 		
-		if tsResp.StatusCode != 200 {
+		if tsResp.StatusCode != 200 && tsResp.StatusCode != 206 {
 			tsResp.Body.Close()
 			continue
 		}
 
 		// 模拟真实播放，读取整个TS片段（通常2-10秒的视频数据）
-		buffer := make([]byte, 2*1024*1024) // 2MB缓冲区
-		n, err := tsResp.Body.Read(buffer)
+		data, err := readSegment(tsResp)
 		tsResp.Body.Close()
-		
+
 		downloadTime := time.Since(downloadStart)
-		
-		if err != nil && err != io.EOF && n == 0 {
+
+		if err != nil || len(data) == 0 {
 			continue
 		}
+		n := len(data)
 
 		// 检查下载的内容是否为错误响应
-		tsContent := string(buffer[:n])
+		tsContent := string(data[:min(n, 1024)])
 		if strings.Contains(tsContent, `"Ret"`) || strings.Contains(tsContent, `"Reason"`) ||
 			strings.Contains(tsContent, "无效") || strings.HasPrefix(strings.TrimSpace(tsContent), "{") {
 			continue
 		}
 
-		// 只有下载到足够的数据才算成功
-		if n > 10*1024 { // 至少10KB
-			totalDataSize += int64(n)
-			totalDownloadTime += downloadTime
-			successfulDownloads++
-			
-			// 计算这个片段的速度
-			speed := float64(n) / downloadTime.Seconds() / 1024 // KB/s
-			speeds = append(speeds, speed)
-			
-			// 如果是直播流，通常片段大小相似，可以用于估算整体速度
-			if successfulDownloads >= 3 {
-				break // 下载3个成功片段就足够评估速度了
+		if segment.Map != nil && !isFullyEncrypted(segment) {
+			// fMP4 片段：初始化片段提供编码和画面参数，媒体片段检查 moof/mdat 和解码时间连续性
+			if err := fragments.loadInit(segment.Map); err != nil {
+				inspectErr = err
+				continue
+			}
+			contiguous := hasLast && segment.Sequence == lastSequence+1 && !segment.Discontinuity
+			if err := fragments.check(data, contiguous); err != nil {
+				inspectErr = err
+				continue
+			}
+			inspected = true
+			container = core.ContainerFMP4
+			codecs = mergeCodecs(codecs, fragments.init.Codecs())
+			if picture.Width == 0 {
+				picture = fragments.picture()
+			}
+		} else {
+			// 检查 TS 包同步和节目表，HTML 错误页等无效数据不计入测速
+			info, err := inspectSegment(segment, data)
+			if err != nil {
+				inspectErr = err
+				continue
+			}
+			if info != nil {
+				inspected = true
+				container = core.ContainerMPEGTS
+				codecs = mergeCodecs(codecs, info.Codecs())
+				continuityErrors += info.ContinuityErrors
+				if picture.Width == 0 {
+					picture = detectPicture(info)
+				}
+			} else if n <= 10*1024 {
+				// 无法检查内容的片段，只有下载到足够的数据才算成功
+				continue
 			}
 		}
+		lastSequence, hasLast = segment.Sequence, true
+
+		totalDataSize += int64(n)
+		totalDownloadTime += downloadTime
+		totalMediaDuration += segment.Duration
+		successfulDownloads++
+
+		// 计算这个片段的速度
+		speed := float64(n) / downloadTime.Seconds() / 1024 // KB/s
+		speeds = append(speeds, speed)
+
+		// 如果是直播流，通常片段大小相似，可以用于估算整体速度
+		if successfulDownloads >= 3 {
+			break // 下载3个成功片段就足够评估速度了
+		}
 	}
 
-	if successfulDownloads == 0 {
-		// 如果TS片段都下载失败，但M3U8有效，给一个保守的估计
+	if successfulDownloads == 0 && inspectErr != nil {
+		// 片段能下载但内容不是有效的媒体数据
 		return core.M3U8Source{
-			URL:           m3u8URL,
-			Latency:       m3u8Latency,
-			DownloadSpeed: 200.0, // 保守估计200KB/s
-			Valid:         true,
-			Error:         "OK (M3U8 valid, TS test failed, estimated)",
-			DataSize:      512 * 1024, // 512KB估算
-			DownloadTime:  2560 * time.Millisecond, // 根据速度推算
+			URL:     m3u8URL,
+			Latency: m3u8Latency,
+			Valid:   false,
+			Error:   inspectErr.Error(),
 		}
 	}
 
-	// 计算平均速度
-	var avgSpeed float64
-	if len(speeds) > 0 {
-		// 使用中位数避免异常值影响
-		sort.Float64s(speeds)
-		medianIdx := len(speeds) / 2
-		avgSpeed = speeds[medianIdx]
-	} else {
-		avgSpeed = float64(totalDataSize) / totalDownloadTime.Seconds() / 1024
+	if successfulDownloads == 0 {
+		// 播放列表有效但片段都下载失败，无法测速
+		return core.M3U8Source{
+			URL:         m3u8URL,
+			Latency:     m3u8Latency,
+			Valid:       true,
+			Error:       "OK (playlist valid, all segment downloads failed)",
+			Measurement: core.Unmeasured,
+		}
 	}
 
-	// 根据实际情况调整估算
-	// 直播流通常需要至少500KB/s才能流畅播放
-	if avgSpeed < 100 {
-		avgSpeed = 150.0 // 给一个最低的可用速度
-	} else if avgSpeed > 5000 {
-		avgSpeed = avgSpeed * 0.8 // 如果速度过高，稍微调低一些，更真实
-	}
+	// 使用各片段速度的中位数，避免异常值影响
+	sort.Float64s(speeds)
+	avgSpeed := speeds[len(speeds)/2]
 
 	avgDataSize := totalDataSize / int64(successfulDownloads)
 	avgDownloadTime := totalDownloadTime / time.Duration(successfulDownloads)
 
-	return core.M3U8Source{
+	result := core.M3U8Source{
 		URL:           m3u8URL,
 		Latency:       m3u8Latency,
 		DownloadSpeed: avgSpeed,
@@ -467,328 +489,177 @@ func testLiveStreamingSpeed(m3u8URL, m3u8Content string, timeout time.Duration,
 		Error:         "OK",
 		DataSize:      avgDataSize,
 		DownloadTime:  avgDownloadTime,
-	}
-}
+		Measurement:   measurementFor(totalDataSize),
+		Container:     container,
 
-// testGenericStreamSpeed tests generic stream speed for non-M3U8 links
-func TestGenericStreamSpeed(url string, timeout time.Duration) core.M3U8Source {
-	if strings.HasPrefix(url, "udp") || strings.Contains(url, "/udp/") {
-		return testRealUDPSpeed(url, timeout)
+		Codecs:           codecs,
+		ContinuityErrors: continuityErrors,
+		Width:            picture.Width,
+		Height:           picture.Height,
+		FrameRate:        picture.FrameRate,
 	}
-
-	// 对于HTTP流媒体链接，下载更多内容测试速度
-	client := &http.Client{
-		Timeout: timeout,
+	result.Bitrate, result.Headroom = playbackRate(totalDataSize, totalMediaDuration, totalDownloadTime)
+	if inspected && continuityErrors > 0 {
+		result.Error = fmt.Sprintf("OK (%d MPEG-TS continuity errors)", continuityErrors)
 	}
-
-	start := time.Now()
-	resp, err := client.Get(url)
-	latency := time.Since(start)
-
-	if err != nil {
-		return core.M3U8Source{
-			URL:     url,
-			Latency: latency,
-			Valid:   false,
-			Error:   err.Error(),
-		}
+	if fragments.gaps > 0 {
+		result.ContinuityErrors += fragments.gaps
+		result.Error = fmt.Sprintf("OK (%d fMP4 decode time gaps)", fragments.gaps)
 	}
-	defer resp.Body.Close()
+	return result
+}
 
-	// 更宽松的状态码检查，接受一些常见的成功状态码
-	if resp.StatusCode != 200 && resp.StatusCode != 206 && resp.StatusCode != 302 && resp.StatusCode != 301 {
-		return core.M3U8Source{
-			URL:     url,
-			Latency: latency,
-			Valid:   false,
-			Error:   fmt.Sprintf("HTTP %d", resp.StatusCode),
-		}
+// playbackRate 根据下载的字节数、片段的 #EXTINF 总时长和下载耗时计算媒体码率 (kbit/s)
+// 和播放余量：下载吞吐量 / 媒体码率，即每秒下载时间能获得多少秒的媒体内容。
+// 片段未声明时长时两者都返回 0。
+func playbackRate(bytes int64, mediaDuration, downloadTime time.Duration) (bitrate, headroom float64) {
+	if mediaDuration <= 0 || bytes <= 0 {
+		return 0, 0
 	}
-
-	// 下载更多数据来测试速度（1MB）
-	downloadStart := time.Now()
-	buffer := make([]byte, 1024*1024) // 1MB
-	n, err := resp.Body.Read(buffer)
-	downloadTime := time.Since(downloadStart)
-
-	// 更宽松的错误处理
-	if err != nil && err != io.EOF && n == 0 {
-		return core.M3U8Source{
-			URL:     url,
-			Latency: latency,
-			Valid:   false,
-			Error:   fmt.Sprintf("Read failed: %v", err),
-		}
+	bitrate = float64(bytes) * 8 / 1000 / mediaDuration.Seconds()
+	if downloadTime > 0 {
+		headroom = mediaDuration.Seconds() / downloadTime.Seconds()
 	}
+	return bitrate, headroom
+}
 
-	// 检查下载的内容是否为JSON错误响应
-	content := string(buffer[:n])
-	trimmedContent := strings.TrimSpace(content)
-	if strings.HasPrefix(trimmedContent, "{") {
-		// 更细致的JSON检查，只拒绝明显的错误响应
-		if strings.Contains(content, `"Ret"`) || strings.Contains(content, `"Reason"`) ||
-			strings.Contains(content, "error") || strings.Contains(content, "失败") {
-			return core.M3U8Source{
-				URL:     url,
-				Latency: latency,
-				Valid:   false,
-				Error:   "JSON error response",
+// mergeCodecs 将新检测到的编码加入列表，保持顺序并去重
+func mergeCodecs(codecs, more []string) []string {
+	for _, c := range more {
+		found := false
+		for _, existing := range codecs {
+			if existing == c {
+				found = true
+				break
 			}
 		}
-	}
-
-	// 更宽松的错误关键字检查
-	if strings.Contains(content, `"Ret":0`) || strings.Contains(content, `"Reason":""`) ||
-		strings.Contains(content, "链接无效") || strings.Contains(content, "链接已失效") {
-		return core.M3U8Source{
-			URL:     url,
-			Latency: latency,
-			Valid:   false,
-			Error:   "API error response (invalid link)",
+		if !found {
+			codecs = append(codecs, c)
 		}
 	}
+	return codecs
+}
 
-	// 检查是否为HTML页面（但不是重定向页面）
-	if (strings.Contains(content, "<html>") || strings.Contains(content, "<HTML>")) && 
-	   !strings.Contains(content, "m3u8") {
-		return core.M3U8Source{
-			URL:     url,
-			Latency: latency,
-			Valid:   false,
-			Error:   "HTML page instead of stream",
-		}
+// testGenericStreamSpeed tests generic stream speed for non-M3U8 links
+func TestGenericStreamSpeed(url string, timeout time.Duration) core.M3U8Source {
+	opts := DefaultOptions()
+	opts.Timeout = timeout
+	if isMulticastURL(url) || isUDPProxyURL(url) || isRTSPURL(url) || isRTMPURL(url) || isDASHURL(url) {
+		return testSource(url, opts)
 	}
+	return testHTTPStream(url, opts)
+}
 
-	// 只要下载了一些数据就认为是成功的
-	if n < 1024 { // 至少1KB
-		return core.M3U8Source{
-			URL:     url,
-			Latency: latency,
-			Valid:   false,
-			Error:   fmt.Sprintf("Too little data downloaded (%d bytes)", n),
-		}
+// fetchSegment 请求一个媒体片段，片段带有 #EXT-X-BYTERANGE 时只请求对应的字节范围
+func fetchSegment(client *http.Client, segment hls.Segment) (*http.Response, error) {
+	req, err := http.NewRequest("GET", segment.URI, nil)
+	if err != nil {
+		return nil, err
 	}
-
-	dataSize := int64(n)
-	downloadSpeed := float64(dataSize) / downloadTime.Seconds() / 1024
-
-	return core.M3U8Source{
-		URL:           url,
-		Latency:       latency,
-		DownloadSpeed: downloadSpeed,
-		Valid:         true,
-		Error:         "OK",
-		DataSize:      dataSize,
-		DownloadTime:  downloadTime,
+	if r := segment.ByteRange; r != nil {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.Offset, r.Offset+r.Length-1))
 	}
+	return client.Do(req)
 }
 
-// testRealUDPSpeed 测试真实的UDP连接速度
-func testRealUDPSpeed(url string, timeout time.Duration) core.M3U8Source {
-	start := time.Now()
+// minMeasuredBytes 是可靠测速所需的最少数据量，更少的数据主要反映 TCP 慢启动，只能算估算值
+const minMeasuredBytes = 256 * 1024
 
-	// 解析UDP URL，支持两种格式：
-	// 1. udp://239.45.3.210:5140
-	// 2. http://proxy:port/udp/239.45.3.210:5140
-	var udpAddress string
-	if strings.HasPrefix(url, "udp://") {
-		udpAddress = url[6:] // 去掉 "udp://" 前缀
-	} else if strings.Contains(url, "/udp/") {
-		// 从HTTP代理URL中提取UDP地址
-		udpStart := strings.Index(url, "/udp/") + 5
-		udpAddress = url[udpStart:]
-	} else {
-		return core.M3U8Source{
-			URL:     url,
-			Latency: time.Since(start),
-			Valid:   false,
-			Error:   "Invalid UDP URL format",
-		}
+// measurementFor 根据实际下载的数据量判断测速结果是实测还是估算
+func measurementFor(bytes int64) core.Measurement {
+	if bytes < minMeasuredBytes {
+		return core.Estimated
 	}
+	return core.Measured
+}
 
-	udpAddr, err := net.ResolveUDPAddr("udp", udpAddress)
-	if err != nil {
-		return core.M3U8Source{
-			URL:     url,
-			Latency: time.Since(start),
-			Valid:   false,
-			Error:   fmt.Sprintf("Failed to resolve UDP address: %v", err),
-		}
+// checkMeasured 未测速的直播源默认不参与排名，标记为不可用
+func checkMeasured(result *core.M3U8Source, includeUnmeasured bool) {
+	if includeUnmeasured || !result.Valid || result.Measurement != core.Unmeasured {
+		return
 	}
+	result.Valid = false
+	result.Error = "Speed not measured: " + result.Error
+}
 
-	// 尝试建立UDP连接
-	conn, err := net.DialTimeout("udp", udpAddr.String(), timeout)
-	if err != nil {
-		return core.M3U8Source{
-			URL:     url,
-			Latency: time.Since(start),
-			Valid:   false,
-			Error:   fmt.Sprintf("Failed to connect to UDP: %v", err),
-		}
+// checkMinHeight 将画面高度低于 minHeight 的可用直播源标记为不可用
+func checkMinHeight(result *core.M3U8Source, minHeight int) {
+	if minHeight <= 0 || !result.Valid {
+		return
 	}
-	defer conn.Close()
-
-	connectTime := time.Since(start)
-
-	// UDP是无连接的，能建立socket就算连接成功
-	// 我们基于延迟和UDP协议特性来估算速度
-	// 直播UDP流通常有以下特点：
-	// 1. 延迟通常在10-100ms之间（局域网）或100-500ms（公网）
-	// 2. UDP直播流通常有固定码率：1-10 Mbps（125-1250 KB/s）
-	
-	// 根据延迟估算网络质量
-	var estimatedSpeed float64
-	var speedDescription string
-	
-	if connectTime < 50*time.Millisecond {
-		// 极低延迟，可能是局域网或高质量网络
-		estimatedSpeed = 800.0 // 800 KB/s (6.4 Mbps)
-		speedDescription = "UDP (Low latency, high quality)"
-	} else if connectTime < 150*time.Millisecond {
-		// 低延迟，良好网络
-		estimatedSpeed = 500.0 // 500 KB/s (4 Mbps)
-		speedDescription = "UDP (Good latency)"
-	} else if connectTime < 300*time.Millisecond {
-		// 中等延迟
-		estimatedSpeed = 300.0 // 300 KB/s (2.4 Mbps)
-		speedDescription = "UDP (Medium latency)"
-	} else if connectTime < 500*time.Millisecond {
-		// 较高延迟
-		estimatedSpeed = 150.0 // 150 KB/s (1.2 Mbps)
-		speedDescription = "UDP (High latency)"
-	} else {
-		// 很高延迟
-		estimatedSpeed = 80.0 // 80 KB/s (640 Kbps)
-		speedDescription = "UDP (Very high latency)"
+	if height := core.SourceHeight(*result); height > 0 && height < minHeight {
+		result.Valid = false
+		result.Error = fmt.Sprintf("Resolution %dp below minimum %dp", height, minHeight)
 	}
+}
 
-	// 发送一个小测试包验证连接是否真的可用
-	testPacket := []byte("PING")
-	conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
-	_, err = conn.Write(testPacket)
-	if err != nil {
-		// 写入失败，降低速度估计
-		estimatedSpeed *= 0.5
-		speedDescription += " - Write failed"
+// testSource 根据地址的协议选择测试方法
+func testSource(url string, opts Options) core.M3U8Source {
+	timeout := opts.Timeout
+	if isMulticastURL(url) {
+		return testMulticast(url, opts)
 	}
-
-	return core.M3U8Source{
-		URL:           url,
-		Latency:       connectTime,
-		DownloadSpeed: estimatedSpeed,
-		Valid:         true,
-		Error:         speedDescription,
-		DataSize:      1024, // 模拟1KB的数据包大小
-		DownloadTime:  time.Duration(float64(1024) / estimatedSpeed * 1000) * time.Millisecond,
+	if isRTSPURL(url) {
+		return testRTSP(url, opts)
 	}
-}
-
-// extractFirstTSURL extracts the first TS URL from M3U8 content
-func extractFirstTSURL(m3u8Content, baseURL string) string {
-	lines := strings.Split(m3u8Content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "#") {
-			if strings.HasPrefix(line, "http") {
-				return line
-			} else {
-				return resolveURL(baseURL, line)
-			}
-		}
+	if isRTMPURL(url) {
+		return testRTMP(url, opts)
 	}
-	return ""
-}
-
-// extractMultipleTSURLs extracts multiple TS URLs from M3U8 content
-func extractMultipleTSURLs(m3u8Content, baseURL string, maxCount int) []string {
-	lines := strings.Split(m3u8Content, "\n")
-	var urls []string
-
-	for _, line := range lines {
-		if len(urls) >= maxCount {
-			break
-		}
-
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "#") {
-			var tsURL string
-			if strings.HasPrefix(line, "http") {
-				tsURL = line
-			} else {
-				tsURL = resolveURL(baseURL, line)
-			}
-			if tsURL != "" {
-				urls = append(urls, tsURL)
-			}
-		}
+	if isUDPProxyURL(url) {
+		// udpxy 代理按 HTTP TS 流测试，不做 HEAD 预检，避免代理为预检单独加入一次组播组
+		return testUDPProxy(url, opts)
 	}
-	return urls
-}
 
-// resolveURL resolves relative URL against base URL
-func resolveURL(baseURL, relativeURL string) string {
-	if strings.HasPrefix(relativeURL, "http") {
-		return relativeURL
+	initialResult := TestStreamConnectSpeed(url, timeout)
+	if !initialResult.Valid {
+		return initialResult
 	}
-
-	// Simple URL resolution - prepend base URL path if needed
-	if strings.HasPrefix(relativeURL, "/") {
-		// Absolute path
-		if strings.Contains(baseURL, "://") {
-			protocolEnd := strings.Index(baseURL, "://") + 3
-			domainEnd := strings.Index(baseURL[protocolEnd:], "/")
-			if domainEnd == -1 {
-				return baseURL + relativeURL
-			}
-			return baseURL[:protocolEnd+domainEnd] + relativeURL
-		}
+	if isDASHURL(url) {
+		return testDASH(url, opts)
 	}
-
-	// Relative path
-	if !strings.HasSuffix(baseURL, "/") {
-		baseURL += "/"
+	if strings.HasPrefix(url, "http") {
+		// 尝试判断是否为M3U8内容
+		if IsM3U8Content(url, timeout) {
+			return testM3U8Playback(url, opts, 0)
+		}
 	}
-	return baseURL + relativeURL
+	// 对于非M3U8内容，识别容器格式后按对应格式测试，MPD 清单转为 DASH 测试
+	return testHTTPStream(url, opts)
 }
 
-// TestAllSources tests all sources concurrently
-func TestAllSources(urls []string, timeout time.Duration) []core.M3U8Source {
+// TestAllSources tests all candidate sources concurrently; each result keeps the candidate's metadata
+func TestAllSources(candidates []core.Candidate, opts Options) []core.M3U8Source {
 	var wg sync.WaitGroup
-	results := make([]core.M3U8Source, len(urls))
+	results := make([]core.M3U8Source, len(candidates))
 
-	fmt.Printf("正在并发测试 %d 个直播源的实际访问速度...\n", len(urls))
+	progress := opts.Progress
+	if progress == nil {
+		progress = io.Discard
+	}
+	fmt.Fprintf(progress, "正在并发测试 %d 个直播源的实际访问速度...\n", len(candidates))
 
-	semaphore := make(chan struct{}, 10)
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
 
-	for i, url := range urls {
+	for i, candidate := range candidates {
 		wg.Add(1)
 		go func(index int, url string) {
 			defer wg.Done()
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			fmt.Printf(".")
-			initialResult := TestStreamConnectSpeed(url, timeout)
-			if initialResult.Valid {
-				if strings.HasPrefix(url, "http") {
-					// 尝试判断是否为M3U8内容
-					if IsM3U8Content(url, timeout) {
-						results[index] = TestM3U8PlaybackSpeed(url, timeout)
-					} else {
-						// 对于非M3U8内容或UDP链接，使用通用的流媒体测试
-						results[index] = TestGenericStreamSpeed(url, timeout)
-					}
-				} else {
-					// 对于UDP等链接，使用通用测试
-					results[index] = TestGenericStreamSpeed(url, timeout)
-				}
-			} else {
-				results[index] = initialResult
-			}
-		}(i, url)
+			fmt.Fprint(progress, ".")
+			results[index] = testSource(url, opts)
+			results[index].Meta = candidates[index].Meta
+			checkMeasured(&results[index], opts.IncludeUnmeasured)
+			checkMinHeight(&results[index], opts.MinHeight)
+		}(i, candidate.URL)
 	}
 
 	wg.Wait()
-	fmt.Println()
+	fmt.Fprintln(progress)
 	return results
-}
\ No newline at end of file
+}
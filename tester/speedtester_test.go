@@ -0,0 +1,39 @@
+package tester
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestM3U8PlaybackContentChecks(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{
+			// 分片地址中的 "error"、"invalid" 不影响判断，由播放列表解析结果决定
+			name: "keywords in segment URIs",
+			body: "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-PLAYLIST-TYPE:VOD\n" +
+				"#EXTINF:10,\n/error_recovery/seg1.ts\n#EXTINF:10,\n/invalid-cache/seg2.ts\n#EXT-X-ENDLIST\n",
+			want: "VOD (Video On Demand) stream, not live",
+		},
+		{
+			name: "json error",
+			body: `{"Ret": -1, "Reason": "token invalid or expired, please refresh"}`,
+			want: "JSON response instead of M3U8",
+		},
+	}
+	for _, tt := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(tt.body))
+		}))
+		opts := DefaultOptions()
+		opts.Timeout = 5 * time.Second
+		result := testM3U8Playback(ts.URL+"/live.m3u8", opts, 0)
+		ts.Close()
+		if result.Error != tt.want {
+			t.Errorf("%s: error %q, want %q", tt.name, result.Error, tt.want)
+		}
+	}
+}
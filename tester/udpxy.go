@@ -0,0 +1,177 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/mpegts"
+)
+
+var (
+	// udpxy/msd_lite 代理地址的路径，如 /udp/239.1.1.1:5000 或 /rtp/@239.1.1.1:5000
+	udpProxyPathRegex = regexp.MustCompile(`^/(udp|rtp)/@?[^/]+:\d+`)
+
+	// udpxy 状态页中的活动客户端数量
+	udpxyActiveRegex = regexp.MustCompile(`(?is)active\s+clients?\s*:?\s*(?:<[^>]*>\s*)*(\d+)`)
+	// 状态页客户端表格中的地址:端口
+	udpxyAddressRegex = regexp.MustCompile(`\d{1,3}(?:\.\d{1,3}){3}:\d+`)
+)
+
+// isUDPProxyURL 判断是否为 udpxy/msd_lite 风格的 http://代理/udp/组地址:端口 地址
+func isUDPProxyURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return udpProxyPathRegex.MatchString(u.Path)
+}
+
+// testUDPProxy 通过 udpxy/msd_lite 代理以 HTTP 方式拉取 TS 流，在采样窗口内统计吞吐量，
+// 并按 MPEG-TS 检查收到的数据。代理按实时码率转发组播，吞吐量即为节目码率。
+func testUDPProxy(rawURL string, opts Options) core.M3U8Source {
+	window := opts.SampleWindow
+	if window <= 0 || window > opts.Timeout {
+		window = opts.Timeout
+	}
+	client := &http.Client{Timeout: opts.Timeout + window}
+
+	start := time.Now()
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return core.M3U8Source{URL: rawURL, Latency: time.Since(start), Valid: false, Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return core.M3U8Source{
+			URL:     rawURL,
+			Latency: time.Since(start),
+			Valid:   false,
+			Error:   fmt.Sprintf("HTTP %d", resp.StatusCode),
+		}
+	}
+
+	// 代理加入组播组后才开始发送数据，以收到第一块数据的时间作为延迟
+	var data []byte
+	var latency time.Duration
+	var first, last time.Time
+	clients := make(chan int, 1)
+	buf := make([]byte, 64*1024)
+	for len(data) < maxSegmentSize {
+		n, err := resp.Body.Read(buf)
+		now := time.Now()
+		if n > 0 {
+			if first.IsZero() {
+				first = now
+				latency = now.Sub(start)
+				if opts.QueryProxyStatus {
+					// 在本次连接仍在拉流时查询状态页，客户端数量包含本次连接
+					go func() { clients <- queryUDPXYClients(rawURL, opts.Timeout) }()
+				}
+			}
+			data = append(data, buf[:n]...)
+			last = now
+		}
+		if err != nil || (!first.IsZero() && now.Sub(first) >= window) {
+			if err != nil && err != io.EOF && len(data) == 0 {
+				return core.M3U8Source{
+					URL:     rawURL,
+					Latency: time.Since(start),
+					Valid:   false,
+					Error:   fmt.Sprintf("Read failed: %v", err),
+				}
+			}
+			break
+		}
+	}
+
+	if len(data) == 0 {
+		return core.M3U8Source{
+			URL:     rawURL,
+			Latency: time.Since(start),
+			Valid:   false,
+			Error:   "Proxy returned no data",
+		}
+	}
+
+	elapsed := last.Sub(first)
+	if elapsed <= 0 {
+		elapsed = time.Since(first)
+	}
+	bytes := int64(len(data))
+	result := core.M3U8Source{
+		URL:           rawURL,
+		Latency:       latency,
+		DownloadSpeed: float64(bytes) / elapsed.Seconds() / 1024,
+		Valid:         true,
+		Error:         "OK",
+		DataSize:      bytes,
+		DownloadTime:  elapsed,
+		Measurement:   measurementFor(bytes),
+		Bitrate:       float64(bytes) * 8 / 1000 / elapsed.Seconds(),
+	}
+	if opts.QueryProxyStatus {
+		result.ProxyClients = <-clients
+	}
+
+	info, err := mpegts.Inspect(data)
+	if err != nil {
+		result.Valid = false
+		result.Error = fmt.Sprintf("Proxy stream is not valid MPEG-TS: %v", err)
+		return result
+	}
+	result.Codecs = info.Codecs()
+	result.ContinuityErrors = info.ContinuityErrors
+	picture := detectPicture(info)
+	result.Width, result.Height, result.FrameRate = picture.Width, picture.Height, picture.FrameRate
+	if info.ContinuityErrors > 0 {
+		result.Error = fmt.Sprintf("OK (%d MPEG-TS continuity errors)", info.ContinuityErrors)
+	}
+	return result
+}
+
+// queryUDPXYClients 读取 udpxy 的 /status 页面，返回当前的客户端数量，查询失败时返回 -1
+func queryUDPXYClients(streamURL string, timeout time.Duration) int {
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return -1
+	}
+	statusURL := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/status"}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(statusURL.String())
+	if err != nil {
+		return -1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256*1024))
+	if err != nil {
+		return -1
+	}
+	return parseUDPXYClients(string(body))
+}
+
+// parseUDPXYClients 从状态页中取出客户端数量：优先使用 "Active clients" 字段，
+// 没有时按客户端表格中包含组播地址的行数计算
+func parseUDPXYClients(page string) int {
+	if m := udpxyActiveRegex.FindStringSubmatch(page); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	rows := 0
+	for _, row := range strings.Split(strings.ToLower(page), "<tr")[1:] {
+		if udpxyAddressRegex.MatchString(row) {
+			rows++
+		}
+	}
+	return rows
+}
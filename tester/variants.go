@@ -0,0 +1,99 @@
+package tester
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"m3u8_selector/core"
+	"m3u8_selector/hls"
+)
+
+// 主播放列表的变体测试策略
+const (
+	VariantHighest = "highest" // 只测试码率最高的变体
+	VariantLowest  = "lowest"  // 只测试码率最低的变体
+	VariantAll     = "all"     // 测试所有变体，排名使用可用变体中码率最高的一个
+)
+
+// VariantPolicies 是支持的变体测试策略
+var VariantPolicies = []string{VariantHighest, VariantLowest, VariantAll}
+
+// testMasterPlaylist 按 opts.VariantPolicy 测试主播放列表中的变体流。
+// 返回结果使用选中变体的测速数据，并附带每个被测变体的结果。
+func testMasterPlaylist(url string, master *hls.MasterPlaylist, opts Options, depth int, latency time.Duration) core.M3U8Source {
+	variants := append([]hls.Variant(nil), master.Variants...)
+	// 码率从高到低排列，相同码率保持播放列表中的顺序
+	sort.SliceStable(variants, func(i, j int) bool {
+		return variants[i].Bandwidth > variants[j].Bandwidth
+	})
+
+	variants = selectVariants(variants, opts.VariantPolicy)
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	results := make([]core.M3U8Source, len(variants))
+	var wg sync.WaitGroup
+	for i, variant := range variants {
+		wg.Add(1)
+		go func(i int, variant hls.Variant) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i] = testM3U8Playback(variant.URI, opts, depth+1)
+		}(i, variant)
+	}
+	wg.Wait()
+
+	variantResults := make([]core.VariantResult, len(variants))
+	selected := -1
+	for i, variant := range variants {
+		variantResults[i] = core.VariantResult{
+			URL:           variant.URI,
+			Bandwidth:     variant.Bandwidth,
+			Resolution:    variant.Resolution,
+			Codecs:        variant.Codecs,
+			Valid:         results[i].Valid,
+			Error:         results[i].Error,
+			Latency:       results[i].Latency,
+			DownloadSpeed: results[i].DownloadSpeed,
+		}
+		if selected < 0 && results[i].Valid {
+			selected = i
+		}
+	}
+
+	if selected < 0 {
+		return core.M3U8Source{
+			URL:      url,
+			Latency:  latency,
+			Valid:    false,
+			Error:    fmt.Sprintf("All %d tested variants failed (first: %s)", len(variants), results[0].Error),
+			Variants: variantResults,
+		}
+	}
+
+	result := results[selected]
+	result.URL = url
+	result.Latency += latency
+	result.Variants = variantResults
+	result.SelectedVariant = variants[selected].URI
+	return result
+}
+
+// selectVariants 按策略从码率从高到低排列的变体中选出要测试的部分
+func selectVariants[T any](sorted []T, policy string) []T {
+	switch policy {
+	case VariantAll:
+		return sorted
+	case VariantLowest:
+		return sorted[len(sorted)-1:]
+	}
+	return sorted[:1]
+}
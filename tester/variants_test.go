@@ -0,0 +1,59 @@
+package tester
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"m3u8_selector/hls"
+)
+
+// inflightServer 对每个请求延迟后返回 404，并记录同时处理的最大请求数
+type inflightServer struct {
+	mu       sync.Mutex
+	inflight int
+	peak     int
+}
+
+func (s *inflightServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.inflight++
+	s.peak = max(s.peak, s.inflight)
+	s.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	s.mu.Lock()
+	s.inflight--
+	s.mu.Unlock()
+	http.NotFound(w, r)
+}
+
+func TestMasterPlaylistConcurrency(t *testing.T) {
+	server := &inflightServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	master := &hls.MasterPlaylist{}
+	for i := 0; i < 8; i++ {
+		master.Variants = append(master.Variants, hls.Variant{
+			URI:       fmt.Sprintf("%s/variant%d.m3u8", ts.URL, i),
+			Bandwidth: int64(i+1) * 1000000,
+		})
+	}
+	opts := DefaultOptions()
+	opts.Timeout = 5 * time.Second
+	opts.Concurrency = 2
+	opts.VariantPolicy = VariantAll
+
+	result := testMasterPlaylist(ts.URL+"/master.m3u8", master, opts, 0, 0)
+	if len(result.Variants) != len(master.Variants) {
+		t.Fatalf("tested %d variants, want %d", len(result.Variants), len(master.Variants))
+	}
+	if server.peak > opts.Concurrency {
+		t.Errorf("%d variants tested at once, Concurrency is %d", server.peak, opts.Concurrency)
+	}
+}
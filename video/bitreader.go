@@ -0,0 +1,60 @@
+// Package video 解析 H.264/H.265 码流中的 SPS，获取画面分辨率和帧率
+package video
+
+import (
+	"errors"
+)
+
+// errShortData 表示 SPS 数据在解析完成前就结束了
+var errShortData = errors.New("SPS truncated")
+
+// bitReader 按位读取 RBSP 数据。读取越界后所有读取都返回 0，并记录错误
+type bitReader struct {
+	data []byte
+	pos  int // 位偏移
+	err  error
+}
+
+func (r *bitReader) u(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.data)*8 {
+			r.err = errShortData
+			return 0
+		}
+		bit := r.data[r.pos/8] >> (7 - uint(r.pos%8)) & 1
+		v = v<<1 | uint32(bit)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) flag() bool {
+	return r.u(1) == 1
+}
+
+func (r *bitReader) skip(n int) {
+	r.u(n)
+}
+
+// ue 读取无符号指数哥伦布编码
+func (r *bitReader) ue() uint32 {
+	zeros := 0
+	for r.u(1) == 0 {
+		if r.err != nil || zeros > 31 {
+			r.err = errShortData
+			return 0
+		}
+		zeros++
+	}
+	return (1<<uint(zeros) - 1) + r.u(zeros)
+}
+
+// se 读取有符号指数哥伦布编码
+func (r *bitReader) se() int32 {
+	v := r.ue()
+	if v&1 == 1 {
+		return int32((v + 1) / 2)
+	}
+	return -int32(v / 2)
+}
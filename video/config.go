@@ -0,0 +1,72 @@
+package video
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ParseAVCConfig 从 AVCDecoderConfigurationRecord (ISO/IEC 14496-15 5.3.3) 中取出第一个 SPS 并解析。
+// FLV 的 AVC 序列头和 MP4 的 avcC 盒子都使用这种格式。
+func ParseAVCConfig(record []byte) (Info, error) {
+	if len(record) < 8 {
+		return Info{}, errShortData
+	}
+	if record[0] != 1 {
+		return Info{}, fmt.Errorf("unsupported avcC version %d", record[0])
+	}
+	numSPS := int(record[5] & 0x1F)
+	pos := 6
+	for i := 0; i < numSPS; i++ {
+		nal, next, ok := lengthPrefixed(record, pos)
+		if !ok {
+			return Info{}, errShortData
+		}
+		if len(nal) > 0 && nal[0]&0x1F == nalH264SPS {
+			return ParseH264SPS(nal)
+		}
+		pos = next
+	}
+	return Info{}, fmt.Errorf("no H.264 SPS found")
+}
+
+// ParseHEVCConfig 从 HEVCDecoderConfigurationRecord (ISO/IEC 14496-15 8.3.3) 中取出第一个 SPS 并解析。
+// FLV 的 HEVC 序列头和 MP4 的 hvcC 盒子都使用这种格式。
+func ParseHEVCConfig(record []byte) (Info, error) {
+	if len(record) < 23 {
+		return Info{}, errShortData
+	}
+	numArrays := int(record[22])
+	pos := 23
+	for i := 0; i < numArrays; i++ {
+		if pos+3 > len(record) {
+			return Info{}, errShortData
+		}
+		nalType := record[pos] & 0x3F
+		numNalus := int(binary.BigEndian.Uint16(record[pos+1:]))
+		pos += 3
+		for j := 0; j < numNalus; j++ {
+			nal, next, ok := lengthPrefixed(record, pos)
+			if !ok {
+				return Info{}, errShortData
+			}
+			if nalType == nalHEVCSPS {
+				return ParseHEVCSPS(nal)
+			}
+			pos = next
+		}
+	}
+	return Info{}, fmt.Errorf("no H.265 SPS found")
+}
+
+// lengthPrefixed 读取 pos 处以两字节长度开头的 NAL 单元
+func lengthPrefixed(data []byte, pos int) (nal []byte, next int, ok bool) {
+	if pos+2 > len(data) {
+		return nil, 0, false
+	}
+	n := int(binary.BigEndian.Uint16(data[pos:]))
+	pos += 2
+	if pos+n > len(data) {
+		return nil, 0, false
+	}
+	return data[pos : pos+n], pos + n, true
+}
@@ -0,0 +1,159 @@
+package video
+
+import (
+	"fmt"
+)
+
+// 带有 chroma_format_idc 等扩展字段的 H.264 profile
+var h264HighProfiles = map[uint32]bool{
+	100: true, 110: true, 122: true, 244: true, 44: true, 83: true, 86: true,
+	118: true, 128: true, 138: true, 139: true, 134: true, 135: true,
+}
+
+// ParseH264SPS 解析 H.264 SPS (ITU-T H.264 7.3.2.1.1)，nal 包含一字节的 NAL 头
+func ParseH264SPS(nal []byte) (Info, error) {
+	if len(nal) < 4 {
+		return Info{}, errShortData
+	}
+	r := &bitReader{data: unescapeRBSP(nal[1:])}
+
+	profile := r.u(8)
+	r.skip(8) // constraint_set 标志
+	r.skip(8) // level_idc
+	r.ue()    // seq_parameter_set_id
+
+	chromaFormat := uint32(1)
+	separateColourPlane := false
+	if h264HighProfiles[profile] {
+		chromaFormat = r.ue()
+		if chromaFormat == 3 {
+			separateColourPlane = r.flag()
+		}
+		r.ue()        // bit_depth_luma_minus8
+		r.ue()        // bit_depth_chroma_minus8
+		r.skip(1)     // qpprime_y_zero_transform_bypass_flag
+		if r.flag() { // seq_scaling_matrix_present_flag
+			count := 8
+			if chromaFormat == 3 {
+				count = 12
+			}
+			for i := 0; i < count; i++ {
+				if r.flag() {
+					size := 16
+					if i >= 6 {
+						size = 64
+					}
+					skipH264ScalingList(r, size)
+				}
+			}
+		}
+	}
+
+	r.ue()          // log2_max_frame_num_minus4
+	switch r.ue() { // pic_order_cnt_type
+	case 0:
+		r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		r.skip(1) // delta_pic_order_always_zero_flag
+		r.se()    // offset_for_non_ref_pic
+		r.se()    // offset_for_top_to_bottom_field
+		cycle := r.ue()
+		for i := uint32(0); i < cycle && r.err == nil; i++ {
+			r.se()
+		}
+	}
+	r.ue()    // max_num_ref_frames
+	r.skip(1) // gaps_in_frame_num_value_allowed_flag
+
+	widthMbs := r.ue() + 1
+	heightMapUnits := r.ue() + 1
+	frameMbsOnly := r.flag()
+	if !frameMbsOnly {
+		r.skip(1) // mb_adaptive_frame_field_flag
+	}
+	r.skip(1) // direct_8x8_inference_flag
+
+	var cropLeft, cropRight, cropTop, cropBottom uint32
+	if r.flag() {
+		cropLeft, cropRight, cropTop, cropBottom = r.ue(), r.ue(), r.ue(), r.ue()
+	}
+	if r.err != nil {
+		return Info{}, r.err
+	}
+
+	frameHeightFactor := uint32(2)
+	if frameMbsOnly {
+		frameHeightFactor = 1
+	}
+
+	// 裁剪单位取决于色度格式 (7.4.2.1.1)
+	cropUnitX, cropUnitY := uint32(1), frameHeightFactor
+	if !separateColourPlane && chromaFormat != 0 {
+		subWidth, subHeight := uint32(2), uint32(2) // 4:2:0
+		switch chromaFormat {
+		case 2:
+			subHeight = 1
+		case 3:
+			subWidth, subHeight = 1, 1
+		}
+		cropUnitX = subWidth
+		cropUnitY = subHeight * frameHeightFactor
+	}
+
+	width := widthMbs*16 - cropUnitX*(cropLeft+cropRight)
+	height := frameHeightFactor*heightMapUnits*16 - cropUnitY*(cropTop+cropBottom)
+	if int32(width) <= 0 || int32(height) <= 0 {
+		return Info{}, fmt.Errorf("invalid H.264 frame size")
+	}
+	info := Info{Codec: "H.264", Width: int(width), Height: int(height)}
+
+	if r.flag() { // vui_parameters_present_flag
+		info.FrameRate = parseH264VUITiming(r)
+	}
+	return info, nil
+}
+
+// skipH264ScalingList 跳过 scaling_list 语法结构
+func skipH264ScalingList(r *bitReader, size int) {
+	last, next := int32(8), int32(8)
+	for j := 0; j < size && r.err == nil; j++ {
+		if next != 0 {
+			next = (last + r.se() + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+}
+
+// parseH264VUITiming 读取 VUI 中的时序信息，返回帧率，未声明或数据不完整时返回 0
+func parseH264VUITiming(r *bitReader) float64 {
+	if r.flag() { // aspect_ratio_info_present_flag
+		if r.u(8) == 255 { // Extended_SAR
+			r.skip(32)
+		}
+	}
+	if r.flag() { // overscan_info_present_flag
+		r.skip(1)
+	}
+	if r.flag() { // video_signal_type_present_flag
+		r.skip(4)
+		if r.flag() { // colour_description_present_flag
+			r.skip(24)
+		}
+	}
+	if r.flag() { // chroma_loc_info_present_flag
+		r.ue()
+		r.ue()
+	}
+	if !r.flag() { // timing_info_present_flag
+		return 0
+	}
+	unitsInTick := r.u(32)
+	timeScale := r.u(32)
+	if r.err != nil || unitsInTick == 0 {
+		return 0
+	}
+	// 一帧包含两个场，每个场一个 tick
+	return float64(timeScale) / float64(2*unitsInTick)
+}
@@ -0,0 +1,218 @@
+package video
+
+import (
+	"fmt"
+)
+
+// ParseHEVCSPS 解析 H.265 SPS (ITU-T H.265 7.3.2.2)，nal 包含两字节的 NAL 头
+func ParseHEVCSPS(nal []byte) (Info, error) {
+	if len(nal) < 4 {
+		return Info{}, errShortData
+	}
+	r := &bitReader{data: unescapeRBSP(nal[2:])}
+
+	r.skip(4) // sps_video_parameter_set_id
+	maxSubLayers := int(r.u(3))
+	r.skip(1) // sps_temporal_id_nesting_flag
+	skipProfileTierLevel(r, maxSubLayers)
+
+	r.ue() // sps_seq_parameter_set_id
+	chromaFormat := r.ue()
+	if chromaFormat == 3 {
+		r.skip(1) // separate_colour_plane_flag
+	}
+	width := r.ue()
+	height := r.ue()
+
+	var confLeft, confRight, confTop, confBottom uint32
+	if r.flag() { // conformance_window_flag
+		confLeft, confRight, confTop, confBottom = r.ue(), r.ue(), r.ue(), r.ue()
+	}
+	if r.err != nil {
+		return Info{}, r.err
+	}
+
+	subWidth, subHeight := uint32(1), uint32(1)
+	switch chromaFormat {
+	case 1:
+		subWidth, subHeight = 2, 2
+	case 2:
+		subWidth = 2
+	}
+	width -= subWidth * (confLeft + confRight)
+	height -= subHeight * (confTop + confBottom)
+	if int32(width) <= 0 || int32(height) <= 0 {
+		return Info{}, fmt.Errorf("invalid H.265 frame size")
+	}
+	info := Info{Codec: "H.265", Width: int(width), Height: int(height)}
+
+	// 以下字段只为找到 VUI 中的时序信息，解析失败时仍返回分辨率
+	r.ue() // bit_depth_luma_minus8
+	r.ue() // bit_depth_chroma_minus8
+	pocLsbBits := int(r.ue()) + 4
+	subLayerOrdering := r.flag()
+	first := maxSubLayers
+	if subLayerOrdering {
+		first = 0
+	}
+	for i := first; i <= maxSubLayers; i++ {
+		r.ue() // sps_max_dec_pic_buffering_minus1
+		r.ue() // sps_max_num_reorder_pics
+		r.ue() // sps_max_latency_increase_plus1
+	}
+	r.ue()        // log2_min_luma_coding_block_size_minus3
+	r.ue()        // log2_diff_max_min_luma_coding_block_size
+	r.ue()        // log2_min_luma_transform_block_size_minus2
+	r.ue()        // log2_diff_max_min_luma_transform_block_size
+	r.ue()        // max_transform_hierarchy_depth_inter
+	r.ue()        // max_transform_hierarchy_depth_intra
+	if r.flag() { // scaling_list_enabled_flag
+		if r.flag() { // sps_scaling_list_data_present_flag
+			skipHEVCScalingListData(r)
+		}
+	}
+	r.skip(1)     // amp_enabled_flag
+	r.skip(1)     // sample_adaptive_offset_enabled_flag
+	if r.flag() { // pcm_enabled_flag
+		r.skip(8) // pcm_sample_bit_depth_luma/chroma_minus1
+		r.ue()
+		r.ue()
+		r.skip(1) // pcm_loop_filter_disabled_flag
+	}
+	skipShortTermRefPicSets(r, int(r.ue()))
+	if r.flag() { // long_term_ref_pics_present_flag
+		count := r.ue()
+		for i := uint32(0); i < count && r.err == nil; i++ {
+			r.skip(pocLsbBits + 1) // lt_ref_pic_poc_lsb_sps, used_by_curr_pic_lt_sps_flag
+		}
+	}
+	r.skip(1)                     // sps_temporal_mvp_enabled_flag
+	r.skip(1)                     // strong_intra_smoothing_enabled_flag
+	if r.err == nil && r.flag() { // vui_parameters_present_flag
+		info.FrameRate = parseHEVCVUITiming(r)
+	}
+	return info, nil
+}
+
+// skipProfileTierLevel 跳过 profile_tier_level(1, maxSubLayersMinus1)
+func skipProfileTierLevel(r *bitReader, maxSubLayers int) {
+	r.skip(96) // general_profile_space ... general_level_idc
+	profilePresent := make([]bool, maxSubLayers)
+	levelPresent := make([]bool, maxSubLayers)
+	for i := 0; i < maxSubLayers; i++ {
+		profilePresent[i] = r.flag()
+		levelPresent[i] = r.flag()
+	}
+	if maxSubLayers > 0 {
+		for i := maxSubLayers; i < 8; i++ {
+			r.skip(2) // reserved_zero_2bits
+		}
+	}
+	for i := 0; i < maxSubLayers; i++ {
+		if profilePresent[i] {
+			r.skip(88)
+		}
+		if levelPresent[i] {
+			r.skip(8)
+		}
+	}
+}
+
+// skipHEVCScalingListData 跳过 scaling_list_data 语法结构
+func skipHEVCScalingListData(r *bitReader) {
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		step := 1
+		if sizeID == 3 {
+			step = 3
+		}
+		for matrixID := 0; matrixID < 6; matrixID += step {
+			if !r.flag() { // scaling_list_pred_mode_flag
+				r.ue() // scaling_list_pred_matrix_id_delta
+				continue
+			}
+			coefNum := 1 << uint(4+sizeID<<1)
+			if coefNum > 64 {
+				coefNum = 64
+			}
+			if sizeID > 1 {
+				r.se() // scaling_list_dc_coef_minus8
+			}
+			for i := 0; i < coefNum && r.err == nil; i++ {
+				r.se()
+			}
+		}
+	}
+}
+
+// skipShortTermRefPicSets 跳过 SPS 中的 st_ref_pic_set(i)，需要记录每组的参考帧数量用于帧间预测
+func skipShortTermRefPicSets(r *bitReader, count int) {
+	numDeltaPocs := make([]int, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		if i != 0 && r.flag() { // inter_ref_pic_set_prediction_flag
+			r.skip(1) // delta_rps_sign
+			r.ue()    // abs_delta_rps_minus1
+			n := 0
+			for j := 0; j <= numDeltaPocs[i-1]; j++ {
+				used := r.flag()
+				useDelta := true
+				if !used {
+					useDelta = r.flag()
+				}
+				if used || useDelta {
+					n++
+				}
+			}
+			numDeltaPocs[i] = n
+			continue
+		}
+		negative := int(r.ue())
+		positive := int(r.ue())
+		if negative+positive > 32 {
+			r.err = errShortData
+			return
+		}
+		for j := 0; j < negative+positive; j++ {
+			r.ue()    // delta_poc_minus1
+			r.skip(1) // used_by_curr_pic_flag
+		}
+		numDeltaPocs[i] = negative + positive
+	}
+}
+
+// parseHEVCVUITiming 读取 VUI 中的时序信息，返回帧率，未声明或数据不完整时返回 0
+func parseHEVCVUITiming(r *bitReader) float64 {
+	if r.flag() { // aspect_ratio_info_present_flag
+		if r.u(8) == 255 { // EXTENDED_SAR
+			r.skip(32)
+		}
+	}
+	if r.flag() { // overscan_info_present_flag
+		r.skip(1)
+	}
+	if r.flag() { // video_signal_type_present_flag
+		r.skip(4)
+		if r.flag() { // colour_description_present_flag
+			r.skip(24)
+		}
+	}
+	if r.flag() { // chroma_loc_info_present_flag
+		r.ue()
+		r.ue()
+	}
+	r.skip(3)     // neutral_chroma_indication_flag, field_seq_flag, frame_field_info_present_flag
+	if r.flag() { // default_display_window_flag
+		r.ue()
+		r.ue()
+		r.ue()
+		r.ue()
+	}
+	if !r.flag() { // vui_timing_info_present_flag
+		return 0
+	}
+	unitsInTick := r.u(32)
+	timeScale := r.u(32)
+	if r.err != nil || unitsInTick == 0 {
+		return 0
+	}
+	return float64(timeScale) / float64(unitsInTick)
+}
@@ -0,0 +1,86 @@
+package video
+
+import (
+	"fmt"
+)
+
+// Info 是从 SPS 解析出的视频参数
+type Info struct {
+	Codec     string  // H.264 或 H.265
+	Width     int     // 裁剪后的显示宽度
+	Height    int     // 裁剪后的显示高度
+	FrameRate float64 // 来自 VUI 时序信息，未声明时为 0
+}
+
+// SPS 的 NAL 单元类型
+const (
+	nalH264SPS = 7
+	nalHEVCSPS = 33
+)
+
+// FindSPS 在 Annex B 格式的基本流数据中查找并解析第一个 SPS。
+// codec 是 mpegts 识别出的编码名称，如 "H.264" 或 "H.265"。
+func FindSPS(codec string, data []byte) (Info, error) {
+	for _, nal := range SplitAnnexB(data) {
+		if len(nal) == 0 {
+			continue
+		}
+		switch codec {
+		case "H.264":
+			if nal[0]&0x1F == nalH264SPS {
+				return ParseH264SPS(nal)
+			}
+		case "H.265":
+			if nal[0]>>1&0x3F == nalHEVCSPS {
+				return ParseHEVCSPS(nal)
+			}
+		default:
+			return Info{}, fmt.Errorf("unsupported video codec %s", codec)
+		}
+	}
+	return Info{}, fmt.Errorf("no %s SPS found", codec)
+}
+
+// SplitAnnexB 按 00 00 01 起始码拆分 NAL 单元
+func SplitAnnexB(data []byte) [][]byte {
+	var nals [][]byte
+	start := -1
+	for i := 0; i+2 < len(data); i++ {
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			continue
+		}
+		if start >= 0 {
+			end := i
+			// 四字节起始码 00 00 00 01 的第一个 0 不属于上一个 NAL
+			if end > start && data[end-1] == 0 {
+				end--
+			}
+			nals = append(nals, data[start:end])
+		}
+		start = i + 3
+		i += 2
+	}
+	if start >= 0 && start < len(data) {
+		nals = append(nals, data[start:])
+	}
+	return nals
+}
+
+// unescapeRBSP 去掉防竞争字节：00 00 03 中的 03
+func unescapeRBSP(nal []byte) []byte {
+	out := make([]byte, 0, len(nal))
+	zeros := 0
+	for _, b := range nal {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
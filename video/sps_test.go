@@ -0,0 +1,439 @@
+package video
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// x264SPS 是 x264 编码的 1920x1080 High@4.2 SPS：编码高度 1088，底部裁剪 8 行，VUI 中帧率 30
+const x264SPS = "6764002AACD940780227E5C044000003000400000300F03C60C658"
+
+// bitWriter 按位写入 RBSP，用于构造带有特定语法元素的 SPS
+type bitWriter struct {
+	data []byte
+	n    int // 已写入的位数
+}
+
+func (w *bitWriter) u(n int, v uint32) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[len(w.data)-1] |= byte(v>>uint(i)&1) << (7 - uint(w.n%8))
+		w.n++
+	}
+}
+
+func (w *bitWriter) flag(b bool) {
+	if b {
+		w.u(1, 1)
+	} else {
+		w.u(1, 0)
+	}
+}
+
+func (w *bitWriter) ue(v uint32) {
+	bits := 0
+	for (v+1)>>uint(bits) > 1 {
+		bits++
+	}
+	w.u(bits, 0)
+	w.u(bits+1, v+1)
+}
+
+func (w *bitWriter) se(v int32) {
+	if v > 0 {
+		w.ue(uint32(2*v - 1))
+	} else {
+		w.ue(uint32(-2 * v))
+	}
+}
+
+// nal 加上 rbsp_trailing_bits 和防竞争字节，返回带 NAL 头的 NAL 单元
+func (w *bitWriter) nal(header ...byte) []byte {
+	w.u(1, 1)
+	for w.n%8 != 0 {
+		w.u(1, 0)
+	}
+	out := append([]byte{}, header...)
+	zeros := 0
+	for _, b := range w.data {
+		if zeros >= 2 && b <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+// h264SPS 是构造 H.264 SPS 使用的参数
+type h264SPS struct {
+	profile        uint32
+	chromaFormat   uint32
+	scalingLists   bool // 写入 seq_scaling_matrix，每个列表都显式给出
+	pocType        uint32
+	widthMbs       uint32
+	heightMapUnits uint32
+	frameMbsOnly   bool
+	crop           [4]uint32 // 左、右、上、下
+	unitsInTick    uint32    // 0 表示没有 VUI
+	timeScale      uint32
+}
+
+func (s h264SPS) encode() []byte {
+	w := &bitWriter{}
+	w.u(8, s.profile)
+	w.u(8, 0)  // constraint_set 标志
+	w.u(8, 40) // level_idc
+	w.ue(0)    // seq_parameter_set_id
+	if h264HighProfiles[s.profile] {
+		w.ue(s.chromaFormat)
+		if s.chromaFormat == 3 {
+			w.flag(false) // separate_colour_plane_flag
+		}
+		w.ue(0)
+		w.ue(0)
+		w.flag(false)
+		w.flag(s.scalingLists)
+		if s.scalingLists {
+			count := 8
+			if s.chromaFormat == 3 {
+				count = 12
+			}
+			for i := 0; i < count; i++ {
+				w.flag(true) // seq_scaling_list_present_flag
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				// 每个系数比上一个大 1，next_scale 不会回到 0
+				for j := 0; j < size; j++ {
+					w.se(1)
+				}
+			}
+		}
+	}
+	w.ue(0) // log2_max_frame_num_minus4
+	w.ue(s.pocType)
+	switch s.pocType {
+	case 0:
+		w.ue(2)
+	case 1:
+		w.flag(false)
+		w.se(-2)
+		w.se(3)
+		w.ue(2) // num_ref_frames_in_pic_order_cnt_cycle
+		w.se(4)
+		w.se(-4)
+	}
+	w.ue(4) // max_num_ref_frames
+	w.flag(false)
+	w.ue(s.widthMbs - 1)
+	w.ue(s.heightMapUnits - 1)
+	w.flag(s.frameMbsOnly)
+	if !s.frameMbsOnly {
+		w.flag(true) // mb_adaptive_frame_field_flag
+	}
+	w.flag(true) // direct_8x8_inference_flag
+	cropped := s.crop != [4]uint32{}
+	w.flag(cropped)
+	if cropped {
+		for _, c := range s.crop {
+			w.ue(c)
+		}
+	}
+	w.flag(s.unitsInTick > 0)
+	if s.unitsInTick > 0 {
+		w.flag(true) // aspect_ratio_info_present_flag
+		w.u(8, 255)  // Extended_SAR
+		w.u(16, 4)
+		w.u(16, 3)
+		w.flag(false) // overscan_info_present_flag
+		w.flag(true)  // video_signal_type_present_flag
+		w.u(4, 0b1010)
+		w.flag(true) // colour_description_present_flag
+		w.u(24, 0x010101)
+		w.flag(false) // chroma_loc_info_present_flag
+		w.flag(true)  // timing_info_present_flag
+		w.u(32, s.unitsInTick)
+		w.u(32, s.timeScale)
+		w.flag(true) // fixed_frame_rate_flag
+	}
+	return w.nal(0x67)
+}
+
+// hevcSPS 构造 1920x1080 (编码高度 1088) 的 H.265 Main SPS，
+// 包含显式的缩放列表、帧间预测的短期参考帧集和 VUI 时序信息
+func hevcSPS(unitsInTick, timeScale uint32) []byte {
+	w := &bitWriter{}
+	w.u(4, 0) // sps_video_parameter_set_id
+	w.u(3, 1) // sps_max_sub_layers_minus1
+	w.flag(true)
+	// profile_tier_level: general_profile_idc = 1 (Main)，general_level_idc = 120
+	w.u(8, 0x01)
+	w.u(32, 0x60000000)
+	w.u(16, 0x9000)
+	w.u(32, 0)
+	w.u(8, 120)
+	w.flag(true) // sub_layer_profile_present_flag[0]
+	w.flag(true) // sub_layer_level_present_flag[0]
+	for i := 1; i < 8; i++ {
+		w.u(2, 0)
+	}
+	w.u(32, 0x01600000)
+	w.u(32, 0)
+	w.u(24, 0)
+	w.u(8, 90)
+
+	w.ue(0) // sps_seq_parameter_set_id
+	w.ue(1) // chroma_format_idc
+	w.ue(1920)
+	w.ue(1088)
+	w.flag(true) // conformance_window_flag
+	w.ue(0)
+	w.ue(0)
+	w.ue(0)
+	w.ue(4)
+	w.ue(0) // bit_depth_luma_minus8
+	w.ue(0)
+	w.ue(4)      // log2_max_pic_order_cnt_lsb_minus4
+	w.flag(true) // sps_sub_layer_ordering_info_present_flag
+	for i := 0; i < 2; i++ {
+		w.ue(4)
+		w.ue(2)
+		w.ue(0)
+	}
+	w.ue(0)
+	w.ue(3)
+	w.ue(0)
+	w.ue(3)
+	w.ue(1)
+	w.ue(1)
+	w.flag(true) // scaling_list_enabled_flag
+	w.flag(true) // sps_scaling_list_data_present_flag
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		step := 1
+		if sizeID == 3 {
+			step = 3
+		}
+		for matrixID := 0; matrixID < 6; matrixID += step {
+			if matrixID%2 == 1 {
+				w.flag(false) // scaling_list_pred_mode_flag
+				w.ue(1)
+				continue
+			}
+			w.flag(true)
+			coefNum := min(64, 1<<uint(4+sizeID<<1))
+			if sizeID > 1 {
+				w.se(8)
+			}
+			for i := 0; i < coefNum; i++ {
+				w.se(1)
+			}
+		}
+	}
+	w.flag(false) // amp_enabled_flag
+	w.flag(true)  // sample_adaptive_offset_enabled_flag
+	w.flag(false) // pcm_enabled_flag
+	w.ue(2)       // num_short_term_ref_pic_sets
+	// 第一组：2 个前向参考帧
+	w.ue(2)
+	w.ue(0)
+	w.ue(0)
+	w.flag(true)
+	w.ue(1)
+	w.flag(true)
+	// 第二组：从第一组预测，NumDeltaPocs[0] + 1 个标志
+	w.flag(true) // inter_ref_pic_set_prediction_flag
+	w.flag(false)
+	w.ue(0)
+	w.flag(true)
+	w.flag(false)
+	w.flag(true) // use_delta_flag
+	w.flag(true)
+	w.flag(true) // long_term_ref_pics_present_flag
+	w.ue(1)
+	w.u(8, 0x55) // lt_ref_pic_poc_lsb_sps
+	w.flag(true)
+	w.flag(true) // sps_temporal_mvp_enabled_flag
+	w.flag(true) // strong_intra_smoothing_enabled_flag
+	w.flag(true) // vui_parameters_present_flag
+	w.flag(true) // aspect_ratio_info_present_flag
+	w.u(8, 1)
+	w.flag(false) // overscan_info_present_flag
+	w.flag(true)  // video_signal_type_present_flag
+	w.u(4, 0b1010)
+	w.flag(true)
+	w.u(24, 0x010101)
+	w.flag(false) // chroma_loc_info_present_flag
+	w.u(3, 0)
+	w.flag(true) // default_display_window_flag
+	w.ue(0)
+	w.ue(0)
+	w.ue(0)
+	w.ue(0)
+	w.flag(unitsInTick > 0) // vui_timing_info_present_flag
+	if unitsInTick > 0 {
+		w.u(32, unitsInTick)
+		w.u(32, timeScale)
+		w.flag(false) // vui_poc_proportional_to_timing_flag
+		w.flag(false) // vui_hrd_parameters_present_flag
+	}
+	w.flag(false) // bitstream_restriction_flag
+	w.flag(false) // sps_extension_present_flag
+	return w.nal(0x42, 0x01)
+}
+
+func TestParseH264SPS(t *testing.T) {
+	x264, _ := hex.DecodeString(x264SPS)
+	tests := []struct {
+		name string
+		nal  []byte
+		want Info
+	}{
+		{"x264 1080p with cropping", x264, Info{Codec: "H.264", Width: 1920, Height: 1080, FrameRate: 30}},
+		{
+			"High profile with scaling lists",
+			h264SPS{profile: 100, chromaFormat: 1, scalingLists: true, widthMbs: 80, heightMapUnits: 45, frameMbsOnly: true,
+				unitsInTick: 1001, timeScale: 48000}.encode(),
+			Info{Codec: "H.264", Width: 1280, Height: 720, FrameRate: 48000.0 / 2002},
+		},
+		{
+			"High 4:4:4 with twelve scaling lists",
+			h264SPS{profile: 244, chromaFormat: 3, scalingLists: true, widthMbs: 40, heightMapUnits: 30, frameMbsOnly: true,
+				crop: [4]uint32{0, 0, 0, 2}}.encode(),
+			Info{Codec: "H.264", Width: 640, Height: 478},
+		},
+		{
+			"interlaced with field cropping",
+			h264SPS{profile: 100, chromaFormat: 1, pocType: 1, widthMbs: 120, heightMapUnits: 34, crop: [4]uint32{0, 0, 0, 2},
+				unitsInTick: 1, timeScale: 50}.encode(),
+			Info{Codec: "H.264", Width: 1920, Height: 1080, FrameRate: 25},
+		},
+		{
+			"Baseline without VUI",
+			h264SPS{profile: 66, pocType: 2, widthMbs: 45, heightMapUnits: 36, frameMbsOnly: true, crop: [4]uint32{4, 4, 0, 0}}.encode(),
+			Info{Codec: "H.264", Width: 704, Height: 576},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseH264SPS(tt.nal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHEVCSPS(t *testing.T) {
+	got, err := ParseHEVCSPS(hevcSPS(1001, 60000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Info{Codec: "H.265", Width: 1920, Height: 1080, FrameRate: 60000.0 / 1001}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	got, err = ParseHEVCSPS(hevcSPS(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FrameRate != 0 || got.Width != 1920 {
+		t.Errorf("without timing info: got %+v, want 1920 wide with no frame rate", got)
+	}
+}
+
+func TestParseTruncatedSPS(t *testing.T) {
+	x264, _ := hex.DecodeString(x264SPS)
+	tests := []struct {
+		name   string
+		nal    []byte
+		parse  func([]byte) (Info, error)
+		header int // 短于这个长度时分辨率字段一定不完整
+	}{
+		{"H.264", x264, ParseH264SPS, 8},
+		{"H.265", hevcSPS(1001, 60000), ParseHEVCSPS, 20},
+	}
+	for _, tt := range tests {
+		// 分辨率字段完整之前必须返回错误；之后允许缺少时序信息，但分辨率必须正确
+		for n := 0; n < len(tt.nal); n++ {
+			info, err := tt.parse(tt.nal[:n])
+			if n < tt.header && err == nil {
+				t.Errorf("%s SPS truncated to %d bytes: expected error, got %+v", tt.name, n, info)
+			}
+			if err == nil && (info.Width != 1920 || info.Height != 1080) {
+				t.Errorf("%s SPS truncated to %d bytes: got %+v", tt.name, n, info)
+			}
+		}
+	}
+}
+
+func TestParseAVCConfig(t *testing.T) {
+	sps, _ := hex.DecodeString(x264SPS)
+	pps := []byte{0x68, 0xEB, 0xE3, 0xCB, 0x22, 0xC0}
+	record := []byte{1, 0x64, 0, 0x2A, 0xFF, 0xE1, 0, byte(len(sps))}
+	record = append(record, sps...)
+	record = append(record, 1, 0, byte(len(pps)))
+	record = append(record, pps...)
+
+	got, err := ParseAVCConfig(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Info{Codec: "H.264", Width: 1920, Height: 1080, FrameRate: 30}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseAVCConfig(record[:10]); !errors.Is(err, errShortData) {
+		t.Errorf("truncated record: err = %v, want %v", err, errShortData)
+	}
+	noSPS := []byte{1, 0x64, 0, 0x2A, 0xFF, 0xE0, 1, 0, byte(len(pps))}
+	if _, err := ParseAVCConfig(append(noSPS, pps...)); err == nil {
+		t.Error("record without SPS: expected error")
+	}
+	if _, err := ParseAVCConfig(append([]byte{0}, record[1:]...)); err == nil {
+		t.Error("record with version 0: expected error")
+	}
+}
+
+func TestParseHEVCConfig(t *testing.T) {
+	vps := []byte{0x40, 0x01, 0x0C, 0x01, 0xFF, 0xFF}
+	sps := hevcSPS(1, 25)
+	pps := []byte{0x44, 0x01, 0xC1, 0x72, 0xB4, 0x62, 0x40}
+	record := make([]byte, 22, 64)
+	record[0] = 1
+	record = append(record, 3)
+	for _, array := range []struct {
+		typ byte
+		nal []byte
+	}{{32, vps}, {33, sps}, {34, pps}} {
+		record = append(record, 0x80|array.typ, 0, 1, byte(len(array.nal)>>8), byte(len(array.nal)))
+		record = append(record, array.nal...)
+	}
+
+	got, err := ParseHEVCConfig(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Info{Codec: "H.265", Width: 1920, Height: 1080, FrameRate: 25}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseHEVCConfig(record[:30]); !errors.Is(err, errShortData) {
+		t.Errorf("truncated record: err = %v, want %v", err, errShortData)
+	}
+	if _, err := ParseHEVCConfig(record[:22]); !errors.Is(err, errShortData) {
+		t.Errorf("short header: err = %v, want %v", err, errShortData)
+	}
+}